@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
 	codec "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/codec_generated"
 )
 
@@ -42,6 +43,16 @@ func BenchmarkWellsRpc_Encode(b *testing.B) {
 	}
 }
 
+func BenchmarkWellsRpc_EncodeAppend(b *testing.B) {
+	s := generateDummyData()
+	buf := make([]byte, 0, s.SizeWells())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = s.MarshalWellsAppend(buf[:0])
+	}
+}
+
 func BenchmarkWellsRpc_Decode(b *testing.B) {
 	s := generateDummyData()
 	data := s.MarshalWells()
@@ -52,6 +63,35 @@ func BenchmarkWellsRpc_Decode(b *testing.B) {
 	}
 }
 
+// taggedReading is SensorReading as a plain struct, encoded by reflection.
+type taggedReading struct {
+	Timestamp   int64   `wells:"1"`
+	Temperature float32 `wells:"2"`
+	Humidity    float32 `wells:"3"`
+	Payload     []byte  `wells:"4"`
+}
+
+func generateTaggedData() *taggedReading {
+	s := generateDummyData()
+	return &taggedReading{Timestamp: s.Timestamp, Temperature: s.Temperature, Humidity: s.Humidity, Payload: s.Payload}
+}
+
+func BenchmarkWellsRpc_EncodeReflect(b *testing.B) {
+	s := generateTaggedData()
+	for i := 0; i < b.N; i++ {
+		_, _ = wellsrpc.MarshalStruct(s)
+	}
+}
+
+func BenchmarkWellsRpc_DecodeReflect(b *testing.B) {
+	data, _ := wellsrpc.MarshalStruct(generateTaggedData())
+	out := &taggedReading{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = wellsrpc.UnmarshalStruct(out, data)
+	}
+}
+
 func BenchmarkJSON_Encode(b *testing.B) {
 	s := generateDummyData()
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+func runBreaking(args []string) int {
+	fs := flag.NewFlagSet("breaking", flag.ContinueOnError)
+	oldPath := fs.String("old", "", "Path to the old .wb.idl file or directory")
+	newPath := fs.String("new", "", "Path to the new .wb.idl file or directory")
+	format := fs.String("format", "text", "Output format: text or json")
+	strict := fs.Bool("strict", false, "Exit with status 1 on warnings as well as errors")
+	var importPaths stringList
+	fs.Var(&importPaths, "I", "Directory to search for imported IDL files (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `
+Usage:
+  welli-codegen breaking -old <path> -new <path> [-format text|json] [-strict] [-I <dir>]...
+
+Compares two versions of a schema and reports changes that break peers
+still running the old version. Messages and enums are matched by their
+package-qualified name, fields by number and services by name, since the
+"Service.Method" string is what goes on the wire.
+
+Errors:
+  field-number-reused     a field number now holds a different field
+  field-number-changed    a field kept its name but got a new number
+  reserved-number-reused  a field uses a number the old schema reserved
+  field-type-changed      a field changed to a type with another encoding
+  enum-value-changed      an enum value kept its name but got a new number
+  service-removed         a service was removed or renamed
+  rpc-removed             an rpc was removed or renamed
+  rpc-type-changed        an rpc changed its request or response type
+  rpc-streaming-changed   an rpc changed between unary and streaming
+
+Warnings:
+  field-renamed           a field number kept its type under a new name
+  field-type-changed      a field changed type but keeps its encoding
+  field-removed           a field was removed without reserving its number
+  enum-value-removed      an enum value was removed
+  field-oneof-changed     a field moved into, out of or between oneofs
+
+The exit status is 1 when errors are found (or warnings, with -strict) and
+2 on usage errors or when either schema fails to load.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *oldPath == "" || *newPath == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "❌ Error: unknown format %q\n", *format)
+		return 2
+	}
+
+	oldFiles, err := loadSchema(*oldPath, importPaths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Error loading old schema:", err)
+		return 2
+	}
+	newFiles, err := loadSchema(*newPath, importPaths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Error loading new schema:", err)
+		return 2
+	}
+
+	c := &comparer{}
+	c.compare(oldFiles, newFiles)
+	c.sort()
+	c.write(os.Stdout, *format)
+	return c.status(*strict)
+}
+
+// loadSchema loads the IDL files at path and returns them followed by
+// everything they import.
+func loadSchema(path string, importPaths []string) ([]*idl.File, error) {
+	files, isDir, err := findIDLFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if isDir {
+		importPaths = append(importPaths[:len(importPaths):len(importPaths)], path)
+	}
+	loaded, err := idl.Load(importPaths, files...)
+	if err != nil {
+		return nil, err
+	}
+	return withImports(loaded), nil
+}
+
+// schema indexes the declarations of a set of files.
+type schema struct {
+	messages map[string]*idl.Message
+	enums    map[string]*idl.Enum
+	services map[string]*idl.Service
+}
+
+func indexSchema(files []*idl.File) *schema {
+	s := &schema{messages: map[string]*idl.Message{}, enums: map[string]*idl.Enum{}, services: map[string]*idl.Service{}}
+	for _, f := range files {
+		for _, msg := range f.AllMessages() {
+			s.messages[msg.QualifiedName()] = msg
+		}
+		for _, enum := range f.AllEnums() {
+			s.enums[enum.QualifiedName()] = enum
+		}
+		for _, srv := range f.Services {
+			if _, ok := s.services[srv.Name]; !ok {
+				s.services[srv.Name] = srv
+			}
+		}
+	}
+	return s
+}
+
+type comparer struct {
+	findings
+}
+
+func (c *comparer) compare(oldFiles, newFiles []*idl.File) {
+	oldSchema, newSchema := indexSchema(oldFiles), indexSchema(newFiles)
+	renamed := map[*idl.Service]bool{}
+	for _, f := range oldFiles {
+		for _, msg := range f.AllMessages() {
+			if next, ok := newSchema.messages[msg.QualifiedName()]; ok {
+				c.message(msg, next)
+			}
+		}
+		for _, enum := range f.AllEnums() {
+			if next, ok := newSchema.enums[enum.QualifiedName()]; ok {
+				c.enum(enum, next)
+			}
+		}
+		for _, srv := range f.Services {
+			if next, ok := newSchema.services[srv.Name]; ok {
+				c.service(srv, next)
+				continue
+			}
+			if next := findRenamedService(srv, oldSchema, newSchema, renamed); next != nil {
+				renamed[next] = true
+				c.report(next.Pos, severityError, "service-removed", "service %s was renamed to %s; peers calling %s fail with unknown method", srv.Name, next.Name, methodNames(srv))
+			} else {
+				c.report(srv.Pos, severityError, "service-removed", "service %s was removed", srv.Name)
+			}
+		}
+	}
+}
+
+// findRenamedService returns the service that is new in next and shares the
+// most rpc names with srv, skipping those already taken by another rename.
+// A rename often comes with rpcs added or removed, so one shared rpc is
+// enough; ties go to the first name in sort order.
+func findRenamedService(srv *idl.Service, prev, next *schema, taken map[*idl.Service]bool) *idl.Service {
+	var names []string
+	for name := range next.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var best *idl.Service
+	bestShared := 0
+	for _, name := range names {
+		cand := next.services[name]
+		if _, ok := prev.services[name]; ok || taken[cand] {
+			continue
+		}
+		if shared := sharedRPCs(srv, cand); shared > bestShared {
+			best, bestShared = cand, shared
+		}
+	}
+	return best
+}
+
+func sharedRPCs(a, b *idl.Service) int {
+	names := map[string]bool{}
+	for _, rpc := range a.RPCs {
+		names[rpc.Name] = true
+	}
+	n := 0
+	for _, rpc := range b.RPCs {
+		if names[rpc.Name] {
+			n++
+		}
+	}
+	return n
+}
+
+// methodNames lists the quoted wire names of the rpcs of srv, such as
+// "Sensor.Send", "Sensor.Stream".
+func methodNames(srv *idl.Service) string {
+	var names []string
+	for _, rpc := range srv.RPCs {
+		names = append(names, strconv.Quote(srv.Name+"."+rpc.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *comparer) service(prev, next *idl.Service) {
+	rpcs := map[string]*idl.RPC{}
+	for _, rpc := range next.RPCs {
+		rpcs[rpc.Name] = rpc
+	}
+	for _, old := range prev.RPCs {
+		rpc, ok := rpcs[old.Name]
+		if !ok {
+			c.report(old.Pos, severityError, "rpc-removed", "rpc %s.%s was removed", prev.Name, old.Name)
+			continue
+		}
+		if from, to := rpcTypeName(old.RequestType, old.Request), rpcTypeName(rpc.RequestType, rpc.Request); from != to {
+			c.report(rpc.Pos, severityError, "rpc-type-changed", "rpc %s.%s request type changed from %s to %s", next.Name, rpc.Name, from, to)
+		}
+		if from, to := rpcTypeName(old.ResponseType, old.Response), rpcTypeName(rpc.ResponseType, rpc.Response); from != to {
+			c.report(rpc.Pos, severityError, "rpc-type-changed", "rpc %s.%s response type changed from %s to %s", next.Name, rpc.Name, from, to)
+		}
+		if from, to := rpcKind(old), rpcKind(rpc); from != to {
+			c.report(rpc.Pos, severityError, "rpc-streaming-changed", "rpc %s.%s changed from %s to %s", next.Name, rpc.Name, from, to)
+		}
+	}
+}
+
+func rpcTypeName(msg *idl.Message, name string) string {
+	if msg == nil {
+		return name
+	}
+	return msg.QualifiedName()
+}
+
+func rpcKind(rpc *idl.RPC) string {
+	switch {
+	case rpc.ClientStreaming && rpc.ServerStreaming:
+		return "bidirectional streaming"
+	case rpc.ClientStreaming:
+		return "client streaming"
+	case rpc.ServerStreaming:
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
+
+func (c *comparer) enum(prev, next *idl.Enum) {
+	values := map[string]*idl.EnumValue{}
+	for _, v := range next.Values {
+		values[v.Name] = v
+	}
+	for _, old := range prev.Values {
+		v, ok := values[old.Name]
+		switch {
+		case !ok:
+			c.report(next.Pos, severityWarning, "enum-value-removed", "enum %s: value %s (%d) was removed", next.FullName(), old.Name, old.Number)
+		case v.Number != old.Number:
+			c.report(v.Pos, severityError, "enum-value-changed", "enum %s: value %s changed from %d to %d", next.FullName(), v.Name, old.Number, v.Number)
+		}
+	}
+}
+
+func (c *comparer) message(prev, next *idl.Message) {
+	byNumber, byName := map[int]*idl.Field{}, map[string]*idl.Field{}
+	for _, field := range next.Fields {
+		byNumber[field.Number] = field
+		byName[field.Name] = field
+	}
+	oldNumbers := map[int]bool{}
+	for _, old := range prev.Fields {
+		oldNumbers[old.Number] = true
+		field, ok := byNumber[old.Number]
+		if ok {
+			c.field(next, old, field)
+			continue
+		}
+		if moved, ok := byName[old.Name]; ok {
+			c.report(moved.Pos, severityError, "field-number-changed", "message %s: field %s changed number from %d to %d", next.FullName(), old.Name, old.Number, moved.Number)
+		} else if !isReserved(next, old.Number) {
+			c.report(next.Pos, severityWarning, "field-removed", "message %s: field %s (%d) was removed without reserving its number", next.FullName(), old.Name, old.Number)
+		}
+	}
+	for _, field := range next.Fields {
+		if !oldNumbers[field.Number] && isReserved(prev, field.Number) {
+			c.report(field.Pos, severityError, "reserved-number-reused", "message %s: field %s uses number %d, which was reserved", next.FullName(), field.Name, field.Number)
+		}
+	}
+}
+
+func isReserved(msg *idl.Message, number int) bool {
+	for _, res := range msg.Reserved {
+		for _, r := range res.Ranges {
+			if r.Contains(number) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *comparer) field(msg *idl.Message, prev, next *idl.Field) {
+	c.oneof(msg, prev, next)
+	from, to := describeField(prev), describeField(next)
+	if prev.Name != next.Name {
+		if from == to {
+			c.report(next.Pos, severityWarning, "field-renamed", "message %s: field %d was renamed from %s to %s", msg.FullName(), next.Number, prev.Name, next.Name)
+		} else {
+			c.report(next.Pos, severityError, "field-number-reused", "message %s: field %d was %s %s and is now %s %s", msg.FullName(), next.Number, from, prev.Name, to, next.Name)
+		}
+		return
+	}
+	if from == to {
+		return
+	}
+	severity := fieldChange(prev, next)
+	note := ""
+	if severity == severityError {
+		note = "; old and new peers decode it differently"
+	}
+	c.report(next.Pos, severity, "field-type-changed", "message %s: field %s changed from %s to %s%s", msg.FullName(), next.Name, from, to, note)
+}
+
+// oneof reports a field that moved into, out of or between oneofs. The
+// encoding is unchanged, but setting another member of a oneof now clears
+// the field, or no longer does, and old peers keep the old behaviour.
+func (c *comparer) oneof(msg *idl.Message, prev, next *idl.Field) {
+	from, to := oneofName(prev), oneofName(next)
+	switch {
+	case from == to:
+	case from == "":
+		c.report(next.Pos, severityWarning, "field-oneof-changed", "message %s: field %d moved into oneof %s", msg.FullName(), next.Number, to)
+	case to == "":
+		c.report(next.Pos, severityWarning, "field-oneof-changed", "message %s: field %d moved out of oneof %s", msg.FullName(), next.Number, from)
+	default:
+		c.report(next.Pos, severityWarning, "field-oneof-changed", "message %s: field %d moved from oneof %s to oneof %s", msg.FullName(), next.Number, from, to)
+	}
+}
+
+func oneofName(field *idl.Field) string {
+	if field.Oneof == nil {
+		return ""
+	}
+	return field.Oneof.Name
+}
+
+// describeField renders the type of field as written in the IDL, with
+// message and enum names qualified by their package.
+func describeField(field *idl.Field) string {
+	typ := field.Type
+	switch {
+	case field.Message != nil:
+		typ = field.Message.QualifiedName()
+	case field.Enum != nil:
+		typ = field.Enum.QualifiedName()
+	}
+	switch {
+	case field.IsMap():
+		return "map<" + field.KeyType + ", " + typ + ">"
+	case field.Repeated:
+		return "repeated " + typ
+	default:
+		return typ
+	}
+}
+
+// encoding classifies how one value of field's type is written. Values of
+// the same encoding decode on either side; changes within one, such as
+// int64 to int32 or string to a message, may still lose data.
+func encoding(typ string, field *idl.Field) string {
+	switch {
+	case field != nil && field.Enum != nil:
+		return "enum"
+	case field != nil && field.Message != nil:
+		return "bytes"
+	}
+	switch typ {
+	case "int32", "int64":
+		return "zigzag"
+	case "uint32", "uint64", "bool":
+		return "varint"
+	case "float", "float32":
+		return "fixed32"
+	case "double", "float64":
+		return "fixed64"
+	default:
+		return "bytes"
+	}
+}
+
+// fieldChange returns the severity of changing a field from prev to next.
+func fieldChange(prev, next *idl.Field) string {
+	from, to := encoding(prev.Type, prev), encoding(next.Type, next)
+	switch {
+	case prev.IsMap() && next.IsMap():
+		return worst(encodingChange(encoding(prev.KeyType, nil), encoding(next.KeyType, nil)), encodingChange(from, to))
+	case prev.IsMap() || next.IsMap():
+		// A map is written as repeated entry messages.
+		if (prev.Repeated && from == "bytes") || (next.Repeated && to == "bytes") {
+			return severityWarning
+		}
+		return severityError
+	case prev.Repeated != next.Repeated:
+		// Repeated numbers are packed, which a singular field does not
+		// accept; a singular field of bytes keeps the last element.
+		if from == "bytes" && to == "bytes" {
+			return severityWarning
+		}
+		return severityError
+	default:
+		return encodingChange(from, to)
+	}
+}
+
+func encodingChange(from, to string) string {
+	switch {
+	case from == to:
+		return severityWarning
+	case (from == "enum" && to == "varint") || (from == "varint" && to == "enum"):
+		return severityWarning
+	default:
+		return severityError
+	}
+}
+
+func worst(a, b string) string {
+	if a == severityError || b == severityError {
+		return severityError
+	}
+	return severityWarning
+}
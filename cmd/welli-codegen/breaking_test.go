@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+func parseSchema(t *testing.T, name, src string) []*idl.File {
+	t.Helper()
+	f, err := idl.Parse(name, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idl.Check(f); err != nil {
+		t.Fatal(err)
+	}
+	return []*idl.File{f}
+}
+
+func TestBreaking(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string // "file:line:col: severity: message (rule)"
+	}{
+		{
+			name: "unchanged",
+			old:  "message M {\n  int32 a = 1;\n}\nservice S {\n  rpc Get (M) returns (M);\n}\n",
+			new:  "message M {\n  int32 a = 1;\n}\nservice S {\n  rpc Get (M) returns (M);\n}\n",
+		},
+		{
+			name: "field number reused",
+			old:  "message M {\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  string b = 1;\n}\n",
+			want: []string{"new:2:3: error: message M: field 1 was int32 a and is now string b (field-number-reused)"},
+		},
+		{
+			name: "field renamed",
+			old:  "message M {\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  int32 b = 1;\n}\n",
+			want: []string{"new:2:3: warning: message M: field 1 was renamed from a to b (field-renamed)"},
+		},
+		{
+			name: "field number changed",
+			old:  "message M {\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  reserved 1;\n  int32 a = 2;\n}\n",
+			want: []string{"new:3:3: error: message M: field a changed number from 1 to 2 (field-number-changed)"},
+		},
+		{
+			name: "field removed",
+			old:  "message M {\n  int32 a = 1;\n  int32 b = 2;\n}\n",
+			new:  "message M {\n  int32 a = 1;\n}\n",
+			want: []string{"new:1:1: warning: message M: field b (2) was removed without reserving its number (field-removed)"},
+		},
+		{
+			name: "field removed and reserved",
+			old:  "message M {\n  int32 a = 1;\n  int32 b = 2;\n}\n",
+			new:  "message M {\n  reserved 2;\n  int32 a = 1;\n}\n",
+		},
+		{
+			name: "reserved number reused",
+			old:  "message M {\n  reserved 2;\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  int32 a = 1;\n  int32 b = 2;\n}\n",
+			want: []string{"new:3:3: error: message M: field b uses number 2, which was reserved (reserved-number-reused)"},
+		},
+		{
+			name: "field type changed encoding",
+			old:  "message M {\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  uint32 a = 1;\n}\n",
+			want: []string{"new:2:3: error: message M: field a changed from int32 to uint32; old and new peers decode it differently (field-type-changed)"},
+		},
+		{
+			name: "field type kept encoding",
+			old:  "message M {\n  int32 a = 1;\n}\n",
+			new:  "message M {\n  int64 a = 1;\n}\n",
+			want: []string{"new:2:3: warning: message M: field a changed from int32 to int64 (field-type-changed)"},
+		},
+		{
+			name: "field moved into a oneof",
+			old:  "message M {\n  int32 a = 1;\n  string b = 2;\n}\n",
+			new:  "message M {\n  oneof choice {\n    int32 a = 1;\n    string b = 2;\n  }\n}\n",
+			want: []string{
+				"new:3:5: warning: message M: field 1 moved into oneof choice (field-oneof-changed)",
+				"new:4:5: warning: message M: field 2 moved into oneof choice (field-oneof-changed)",
+			},
+		},
+		{
+			name: "field moved out of and between oneofs",
+			old:  "message M {\n  oneof x {\n    int32 a = 1;\n    int32 b = 2;\n  }\n}\n",
+			new:  "message M {\n  int32 a = 1;\n  oneof y {\n    int32 b = 2;\n  }\n}\n",
+			want: []string{
+				"new:2:3: warning: message M: field 1 moved out of oneof x (field-oneof-changed)",
+				"new:4:5: warning: message M: field 2 moved from oneof x to oneof y (field-oneof-changed)",
+			},
+		},
+		{
+			name: "enum values",
+			old:  "enum E {\n  E_A = 0;\n  E_B = 1;\n  E_C = 2;\n}\n",
+			new:  "enum E {\n  E_A = 0;\n  E_B = 3;\n}\n",
+			want: []string{
+				"new:1:1: warning: enum E: value E_C (2) was removed (enum-value-removed)",
+				"new:3:3: error: enum E: value E_B changed from 1 to 3 (enum-value-changed)",
+			},
+		},
+		{
+			name: "service removed",
+			old:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n}\n",
+			new:  "message M {}\nservice T {\n  rpc Put (M) returns (M);\n}\n",
+			want: []string{"old:2:1: error: service S was removed (service-removed)"},
+		},
+		{
+			name: "service renamed",
+			old:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			new:  "message M {}\nservice T {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			want: []string{`new:2:1: error: service S was renamed to T; peers calling "S.Get", "S.Put" fail with unknown method (service-removed)`},
+		},
+		{
+			name: "service renamed with rpc changes",
+			old:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			new:  "message M {}\nservice T {\n  rpc Get (M) returns (M);\n  rpc List (M) returns (stream M);\n}\n",
+			want: []string{`new:2:1: error: service S was renamed to T; peers calling "S.Get", "S.Put" fail with unknown method (service-removed)`},
+		},
+		{
+			name: "renamed service picks the closest match",
+			old:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			new: "message M {}\nservice A {\n  rpc Get (M) returns (M);\n}\n" +
+				"service B {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			want: []string{`new:5:1: error: service S was renamed to B; peers calling "S.Get", "S.Put" fail with unknown method (service-removed)`},
+		},
+		{
+			name: "rpc removed",
+			old:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n  rpc Put (M) returns (M);\n}\n",
+			new:  "message M {}\nservice S {\n  rpc Get (M) returns (M);\n}\n",
+			want: []string{"old:4:3: error: rpc S.Put was removed (rpc-removed)"},
+		},
+		{
+			name: "rpc types and streaming changed",
+			old:  "message M {}\nmessage N {}\nservice S {\n  rpc Get (M) returns (M);\n}\n",
+			new:  "message M {}\nmessage N {}\nservice S {\n  rpc Get (N) returns (stream M);\n}\n",
+			want: []string{
+				"new:4:3: error: rpc S.Get request type changed from M to N (rpc-type-changed)",
+				"new:4:3: error: rpc S.Get changed from unary to server streaming (rpc-streaming-changed)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &comparer{}
+			c.compare(parseSchema(t, "old", tt.old), parseSchema(t, "new", tt.new))
+			c.sort()
+			var got []string
+			for _, f := range c.findings {
+				got = append(got, fmt.Sprintf("%s:%d:%d: %s: %s (%s)", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings:\ngot  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
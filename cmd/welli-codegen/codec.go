@@ -0,0 +1,806 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+type scalarDef struct {
+	GoType   string
+	WireType int
+}
+
+var scalarTypes = map[string]scalarDef{
+	"int32":   {"int32", wellsrpc.WireVarint},
+	"int64":   {"int64", wellsrpc.WireVarint},
+	"uint32":  {"uint32", wellsrpc.WireVarint},
+	"uint64":  {"uint64", wellsrpc.WireVarint},
+	"bool":    {"bool", wellsrpc.WireVarint},
+	"float":   {"float32", wellsrpc.WireFixed32},
+	"float32": {"float32", wellsrpc.WireFixed32},
+	"double":  {"float64", wellsrpc.WireFixed64},
+	"float64": {"float64", wellsrpc.WireFixed64},
+	"string":  {"string", wellsrpc.WireBytes},
+	"bytes":   {"[]byte", wellsrpc.WireBytes},
+}
+
+type fieldType struct {
+	Scalar string
+	GoName string
+	Enum   bool
+}
+
+// codeWriter writes one generated file. Types declared in another Go
+// package are qualified with the name under which imports has imported it.
+type codeWriter struct {
+	io.Writer
+	imports *importSet
+
+	// keepUnknown makes messages keep the fields their decoder does not
+	// know and write them back after the known ones.
+	keepUnknown bool
+}
+
+func (f *codeWriter) valueType(field *idl.Field) fieldType {
+	switch {
+	case field.Enum != nil:
+		return fieldType{GoName: f.imports.qualify(field.Enum.File, field.Enum.GoName()), Enum: true}
+	case field.Message != nil:
+		return fieldType{GoName: f.imports.qualify(field.Message.File, field.Message.GoName())}
+	default:
+		return fieldType{Scalar: field.Type}
+	}
+}
+
+func keyType(field *idl.Field) fieldType {
+	return fieldType{Scalar: field.KeyType}
+}
+
+func (t fieldType) isMessage() bool {
+	return t.Scalar == "" && !t.Enum
+}
+
+func (t fieldType) goType() string {
+	switch {
+	case t.Enum:
+		return t.GoName
+	case t.isMessage():
+		return "*" + t.GoName
+	default:
+		return scalarTypes[t.Scalar].GoType
+	}
+}
+
+func (t fieldType) wireType() int {
+	switch {
+	case t.Enum:
+		return wellsrpc.WireVarint
+	case t.isMessage():
+		return wellsrpc.WireBytes
+	default:
+		return scalarTypes[t.Scalar].WireType
+	}
+}
+
+func (t fieldType) packable() bool {
+	return t.wireType() != wellsrpc.WireBytes
+}
+
+func writeCodec(path string, pkg *goPackage, file *idl.File, pkgs *goPackages, keepUnknown bool) error {
+	imports := newImportSet(pkgs, pkg, idl.GoMessageImports...)
+	for _, msg := range file.AllMessages() {
+		for _, field := range msg.Fields {
+			var err error
+			switch {
+			case field.Message != nil:
+				err = imports.add(field.Message.File)
+			case field.Enum != nil:
+				err = imports.add(field.Enum.File)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	f := &codeWriter{Writer: out, imports: imports, keepUnknown: keepUnknown}
+
+	fmt.Fprintf(f, "package %s\n\n", pkg.Name)
+	fmt.Fprintln(f, "import (")
+	msgs, enums := file.AllMessages(), file.AllEnums()
+	if len(msgs) > 0 {
+		fmt.Fprintln(f, `  "errors"`)
+	}
+	if f.readsVarints(msgs) {
+		fmt.Fprintln(f, `  "fmt"`)
+	}
+	if f.usesMath(msgs) {
+		fmt.Fprintln(f, `  "math"`)
+	}
+	if len(enums) > 0 {
+		fmt.Fprintln(f, `  "strconv"`)
+	}
+	if len(msgs) > 0 {
+		fmt.Fprintln(f, `  wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"`)
+	}
+	imports.write(f)
+	fmt.Fprintln(f, ")")
+	for _, enum := range enums {
+		writeEnum(f, enum)
+	}
+	for _, msg := range msgs {
+		fmt.Fprintf(f, "\ntype %s struct {\n", msg.GoName())
+		for _, field := range msg.Fields {
+			switch {
+			case field.Oneof == nil:
+				fmt.Fprintf(f, "  %s %s\n", field.GoName(), f.goFieldType(field))
+			case field.Oneof.Fields[0] == field:
+				fmt.Fprintf(f, "  %s %s\n", field.Oneof.GoName(), msg.GoOneofInterface(field.Oneof))
+			}
+		}
+		if f.keepUnknown {
+			fmt.Fprintln(f, "\n  unknownFields []byte")
+		}
+		fmt.Fprintln(f, "}")
+
+		for _, oneof := range msg.Oneofs {
+			writeOneofTypes(f, msg, oneof)
+		}
+		writeSize(f, msg)
+		writeMarshal(f, msg)
+		writeUnmarshal(f, msg)
+	}
+
+	return formatFile(out)
+}
+
+func writeEnum(f io.Writer, enum *idl.Enum) {
+	name := enum.GoName()
+	fmt.Fprintf(f, "\ntype %s int32\n\n", name)
+	fmt.Fprintln(f, "const (")
+	for _, v := range enum.Values {
+		fmt.Fprintf(f, "  %s_%s %s = %d\n", name, v.Name, name, v.Number)
+	}
+	fmt.Fprintln(f, ")")
+
+	fmt.Fprintf(f, "\nvar %s_name = map[int32]string{\n", name)
+	for _, v := range enum.Values {
+		fmt.Fprintf(f, "  %d: %q,\n", v.Number, v.Name)
+	}
+	fmt.Fprintln(f, "}")
+	fmt.Fprintf(f, "\nvar %s_value = map[string]int32{\n", name)
+	for _, v := range enum.Values {
+		fmt.Fprintf(f, "  %q: %d,\n", v.Name, v.Number)
+	}
+	fmt.Fprintln(f, "}")
+
+	fmt.Fprintf(f, "\nfunc (x %s) String() string {\n", name)
+	fmt.Fprintf(f, "  if s, ok := %s_name[int32(x)]; ok {\n    return s\n  }\n", name)
+	fmt.Fprintln(f, "  return strconv.Itoa(int(x))")
+	fmt.Fprintln(f, "}")
+}
+
+// writeOneofTypes emits the interface held by the oneof field, one wrapper
+// struct per case and a getter per case.
+func writeOneofTypes(f *codeWriter, msg *idl.Message, oneof *idl.Oneof) {
+	iface := msg.GoOneofInterface(oneof)
+	fmt.Fprintf(f, "\ntype %s interface {\n  %s()\n}\n", iface, iface)
+	for _, field := range oneof.Fields {
+		wrapper := msg.GoOneofWrapper(field)
+		fmt.Fprintf(f, "\ntype %s struct {\n  %s %s\n}\n", wrapper, field.GoName(), f.goFieldType(field))
+		fmt.Fprintf(f, "\nfunc (*%s) %s() {}\n", wrapper, iface)
+	}
+	for _, field := range oneof.Fields {
+		fmt.Fprintf(f, "\nfunc (m *%s) Get%s() %s {\n", msg.GoName(), field.GoName(), f.goFieldType(field))
+		fmt.Fprintf(f, "  if m == nil {\n    return %s\n  }\n", zeroValue(f.valueType(field)))
+		fmt.Fprintf(f, "  if x, ok := m.%s.(*%s); ok {\n", oneof.GoName(), msg.GoOneofWrapper(field))
+		fmt.Fprintf(f, "    return x.%s\n  }\n", field.GoName())
+		fmt.Fprintf(f, "  return %s\n}\n", zeroValue(f.valueType(field)))
+	}
+}
+
+// writeSize emits SizeWells and CacheSizeWells, which mirror the field by
+// field decisions of writeMarshal.
+func writeSize(f *codeWriter, msg *idl.Message) {
+	fmt.Fprintf(f, "\n// SizeWells returns the size of the encoding of m.\nfunc (m *%s) SizeWells() int {\n", msg.GoName())
+	fmt.Fprintln(f, "  return m.CacheSizeWells(nil)")
+	fmt.Fprintln(f, "}")
+
+	fmt.Fprintf(f, "\n// CacheSizeWells is SizeWells that also records the sizes of the nested\n// messages in c for MarshalWellsCached.\nfunc (m *%s) CacheSizeWells(c *wellib.SizeCache) int {\n", msg.GoName())
+	fmt.Fprintln(f, "  if m == nil {\n    return 0\n  }")
+	fmt.Fprintln(f, "  n := 0")
+	for _, field := range msg.Fields {
+		name := "m." + field.GoName()
+		t := f.valueType(field)
+		if field.Oneof != nil {
+			if field.Oneof.Fields[0] == field {
+				writeOneofSize(f, msg, field.Oneof)
+			}
+			continue
+		}
+		switch {
+		case field.IsMap():
+			kt := keyType(field)
+			ks, vs := sizeExpr(kt, "k"), sizeExpr(t, "v")
+			fmt.Fprintf(f, "  for %s {\n", rangeClause(name, strings.Contains(ks, "k"), strings.Contains(vs, "v")))
+			fmt.Fprintf(f, "    n += %d + wellib.SizeBytes(1 + %s + 1 + %s)\n", keySize(field.Number, wellsrpc.WireBytes), ks, vs)
+			fmt.Fprintln(f, "  }")
+		case field.Repeated && t.packable():
+			fmt.Fprintf(f, "  if len(%s) > 0 {\n", name)
+			writePackedSize(f, t, name)
+			fmt.Fprintf(f, "    n += %d + wellib.SizeBytes(size)\n", keySize(field.Number, wellsrpc.WireBytes))
+			fmt.Fprintln(f, "  }")
+		case field.Repeated:
+			fmt.Fprintf(f, "  for _, v := range %s {\n", name)
+			fmt.Fprintf(f, "    n += %d + %s\n", keySize(field.Number, t.wireType()), cachedSizeExpr(t, "v"))
+			fmt.Fprintln(f, "  }")
+		case t.Scalar == "string" || t.Scalar == "bytes":
+			fmt.Fprintf(f, "  if len(%s) > 0 {\n", name)
+			fmt.Fprintf(f, "    n += %d + %s\n", keySize(field.Number, t.wireType()), sizeExpr(t, name))
+			fmt.Fprintln(f, "  }")
+		case t.isMessage():
+			fmt.Fprintf(f, "  if %s != nil {\n", name)
+			fmt.Fprintf(f, "    n += %d + %s\n", keySize(field.Number, t.wireType()), cachedSizeExpr(t, name))
+			fmt.Fprintln(f, "  }")
+		default:
+			fmt.Fprintf(f, "  if %s {\n", nonZeroExpr(t, name))
+			fmt.Fprintf(f, "    n += %d + %s\n", keySize(field.Number, t.wireType()), sizeExpr(t, name))
+			fmt.Fprintln(f, "  }")
+		}
+	}
+	if f.keepUnknown {
+		fmt.Fprintln(f, "  n += len(m.unknownFields)")
+	}
+	fmt.Fprintln(f, "  return n")
+	fmt.Fprintln(f, "}")
+}
+
+func writeOneofSize(f *codeWriter, msg *idl.Message, oneof *idl.Oneof) {
+	sizes := make([]string, len(oneof.Fields))
+	usesX := false
+	for i, field := range oneof.Fields {
+		t := f.valueType(field)
+		sizes[i] = fmt.Sprintf("%d + %s", keySize(field.Number, t.wireType()), cachedSizeExpr(t, "x."+field.GoName()))
+		usesX = usesX || strings.Contains(sizes[i], "x.")
+	}
+	if usesX {
+		fmt.Fprintf(f, "  switch x := m.%s.(type) {\n", oneof.GoName())
+	} else {
+		fmt.Fprintf(f, "  switch m.%s.(type) {\n", oneof.GoName())
+	}
+	for i, field := range oneof.Fields {
+		fmt.Fprintf(f, "  case *%s:\n", msg.GoOneofWrapper(field))
+		if usesX && !strings.Contains(sizes[i], "x.") {
+			fmt.Fprintln(f, "    _ = x")
+		}
+		fmt.Fprintf(f, "    n += %s\n", sizes[i])
+	}
+	fmt.Fprintln(f, "  }")
+}
+
+// rangeClause returns a range clause over the map expr that declares only
+// the variables the loop body uses.
+func rangeClause(expr string, useKey, useValue bool) string {
+	switch {
+	case useValue:
+		k := "_"
+		if useKey {
+			k = "k"
+		}
+		return fmt.Sprintf("%s, v := range %s", k, expr)
+	case useKey:
+		return "k := range " + expr
+	default:
+		return "range " + expr
+	}
+}
+
+// writeMarshal emits MarshalWells and MarshalWellsAppend, which size m once
+// and hand the sizes of its nested messages to MarshalWellsCached. A message
+// without nested messages has no sizes to cache and skips that pass.
+func writeMarshal(f *codeWriter, msg *idl.Message) {
+	nested := hasNestedMessages(f, msg)
+	fmt.Fprintf(f, "\nfunc (m *%s) MarshalWells() []byte {\n", msg.GoName())
+	fmt.Fprintln(f, "  if m == nil {\n    return nil\n  }")
+	if nested {
+		fmt.Fprintln(f, "  var c wellib.SizeCache")
+		fmt.Fprintln(f, "  return m.MarshalWellsCached(make([]byte, 0, m.CacheSizeWells(&c)), &c)")
+	} else {
+		fmt.Fprintln(f, "  return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)")
+	}
+	fmt.Fprintln(f, "}")
+
+	fmt.Fprintf(f, "\n// MarshalWellsAppend appends the encoding of m to b and returns the\n// extended buffer.\nfunc (m *%s) MarshalWellsAppend(b []byte) []byte {\n", msg.GoName())
+	fmt.Fprintln(f, "  if m == nil {\n    return b\n  }")
+	if nested {
+		fmt.Fprintln(f, "  var c wellib.SizeCache")
+		fmt.Fprintln(f, "  m.CacheSizeWells(&c)")
+		fmt.Fprintln(f, "  return m.MarshalWellsCached(b, &c)")
+	} else {
+		fmt.Fprintln(f, "  return m.MarshalWellsCached(b, nil)")
+	}
+	fmt.Fprintln(f, "}")
+
+	fmt.Fprintf(f, "\n// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested\n// messages taken from c, which CacheSizeWells filled. A nil c is the same as\n// MarshalWellsAppend.\nfunc (m *%s) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {\n", msg.GoName())
+	fmt.Fprintln(f, "  if m == nil {\n    return b\n  }")
+	if nested {
+		fmt.Fprintln(f, "  if c == nil {\n    return m.MarshalWellsAppend(b)\n  }")
+	}
+	for _, field := range msg.Fields {
+		name := "m." + field.GoName()
+		t := f.valueType(field)
+		if field.Oneof != nil {
+			if field.Oneof.Fields[0] == field {
+				fmt.Fprintln(f)
+				writeOneofMarshal(f, msg, field.Oneof)
+			}
+			continue
+		}
+		fmt.Fprintln(f)
+		switch {
+		case field.IsMap():
+			writeMapEntries(f, field, name)
+		case field.Repeated && t.packable():
+			writePacked(f, field, name)
+		case field.Repeated:
+			fmt.Fprintf(f, "  for _, v := range %s {\n", name)
+			fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, t.wireType()))
+			writeAppendValue(f, t, "v")
+			fmt.Fprintln(f, "  }")
+		case t.Scalar == "string" || t.Scalar == "bytes":
+			fmt.Fprintf(f, "  if len(%s) > 0 {\n", name)
+			fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, t.wireType()))
+			writeAppendValue(f, t, name)
+			fmt.Fprintln(f, "  }")
+		case t.isMessage():
+			fmt.Fprintf(f, "  if %s != nil {\n", name)
+			fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, t.wireType()))
+			writeAppendValue(f, t, name)
+			fmt.Fprintln(f, "  }")
+		default:
+			fmt.Fprintf(f, "  if %s {\n", nonZeroExpr(t, name))
+			fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, t.wireType()))
+			if t.Scalar == "bool" {
+				fmt.Fprintln(f, "    b = append(b, 1)")
+			} else {
+				writeAppendValue(f, t, name)
+			}
+			fmt.Fprintln(f, "  }")
+		}
+	}
+	if f.keepUnknown {
+		fmt.Fprintln(f, "\n  b = append(b, m.unknownFields...)")
+	}
+	fmt.Fprintln(f)
+	fmt.Fprintln(f, "  return b")
+	fmt.Fprintln(f, "}")
+}
+
+// hasNestedMessages reports whether msg has a message field outside a map,
+// whose size CacheSizeWells records.
+func hasNestedMessages(f *codeWriter, msg *idl.Message) bool {
+	for _, field := range msg.Fields {
+		if !field.IsMap() && f.valueType(field).isMessage() {
+			return true
+		}
+	}
+	return false
+}
+
+// nonZeroExpr returns a condition that holds when the scalar expr differs
+// from its default. Like protobuf, defaults are not written; a float is
+// compared by its bits so that -0 is still written.
+func nonZeroExpr(t fieldType, expr string) string {
+	switch {
+	case t.Scalar == "bool":
+		return expr
+	case t.wireType() == wellsrpc.WireFixed32:
+		return fmt.Sprintf("math.Float32bits(%s) != 0", expr)
+	case t.wireType() == wellsrpc.WireFixed64:
+		return fmt.Sprintf("math.Float64bits(%s) != 0", expr)
+	default:
+		return expr + " != 0"
+	}
+}
+
+// usesMath reports whether the marshal code for msgs compares floats by
+// their bits.
+func (f *codeWriter) usesMath(msgs []*idl.Message) bool {
+	for _, msg := range msgs {
+		for _, field := range msg.Fields {
+			if field.Oneof != nil || field.Repeated || field.IsMap() {
+				continue
+			}
+			if w := f.valueType(field).wireType(); w == wellsrpc.WireFixed32 || w == wellsrpc.WireFixed64 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readsVarints reports whether the decoders of msgs read a varint, which
+// every field does but a single float or double.
+func (f *codeWriter) readsVarints(msgs []*idl.Message) bool {
+	for _, msg := range msgs {
+		for _, field := range msg.Fields {
+			if field.Repeated || field.IsMap() {
+				return true
+			}
+			if w := f.valueType(field).wireType(); w == wellsrpc.WireVarint || w == wellsrpc.WireBytes {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeOneofMarshal writes only the case that is set. A set case is written
+// even when it holds the zero value.
+func writeOneofMarshal(f *codeWriter, msg *idl.Message, oneof *idl.Oneof) {
+	fmt.Fprintf(f, "  switch x := m.%s.(type) {\n", oneof.GoName())
+	for _, field := range oneof.Fields {
+		t := f.valueType(field)
+		fmt.Fprintf(f, "  case *%s:\n", msg.GoOneofWrapper(field))
+		fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, t.wireType()))
+		writeAppendValue(f, t, "x."+field.GoName())
+	}
+	fmt.Fprintln(f, "  }")
+}
+
+// writePacked emits a repeated numeric field as a single length-delimited
+// record holding the concatenated element encodings.
+func writePacked(f *codeWriter, field *idl.Field, name string) {
+	t := f.valueType(field)
+	fmt.Fprintf(f, "  if len(%s) > 0 {\n", name)
+	writePackedSize(f, t, name)
+	fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, wellsrpc.WireBytes))
+	fmt.Fprintln(f, "    b = wellib.AppendVarint(b, uint64(size))")
+	fmt.Fprintf(f, "    for _, v := range %s {\n", name)
+	writeAppendValue(f, t, "v")
+	fmt.Fprintln(f, "    }")
+	fmt.Fprintln(f, "  }")
+}
+
+// writePackedSize emits the size of the packed elements of name as size.
+func writePackedSize(f io.Writer, t fieldType, name string) {
+	switch {
+	case t.wireType() == wellsrpc.WireFixed32:
+		fmt.Fprintf(f, "    size := len(%s) * 4\n", name)
+	case t.wireType() == wellsrpc.WireFixed64:
+		fmt.Fprintf(f, "    size := len(%s) * 8\n", name)
+	case t.Scalar == "bool":
+		fmt.Fprintf(f, "    size := len(%s)\n", name)
+	default:
+		fmt.Fprintln(f, "    size := 0")
+		fmt.Fprintf(f, "    for _, v := range %s {\n", name)
+		fmt.Fprintf(f, "      size += wellib.SizeVarint(%s)\n", varintExpr(t, "v"))
+		fmt.Fprintln(f, "    }")
+	}
+}
+
+// writeMapEntries emits one length-delimited entry per map element, each
+// holding the key as field 1 and the value as field 2.
+func writeMapEntries(f *codeWriter, field *idl.Field, name string) {
+	kt, vt := keyType(field), f.valueType(field)
+	if vt.isMessage() {
+		fmt.Fprintln(f, "  var vc wellib.SizeCache")
+	}
+	fmt.Fprintf(f, "  for k, v := range %s {\n", name)
+	valueSize := sizeExpr(vt, "v")
+	if vt.isMessage() {
+		// Map entries are written in another order than CacheSizeWells
+		// visited them, so each value is sized on its own.
+		fmt.Fprintln(f, "    vc.Reset()")
+		fmt.Fprintln(f, "    vs := v.CacheSizeWells(&vc)")
+		valueSize = "wellib.SizeBytes(vs)"
+	}
+	fmt.Fprintf(f, "    size := 1 + %s + 1 + %s\n", sizeExpr(kt, "k"), valueSize)
+	fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(field.Number, wellsrpc.WireBytes))
+	fmt.Fprintln(f, "    b = wellib.AppendVarint(b, uint64(size))")
+	fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(1, kt.wireType()))
+	writeAppendValue(f, kt, "k")
+	fmt.Fprintf(f, "    b = append(b, %s)\n", tagBytes(2, vt.wireType()))
+	if vt.isMessage() {
+		fmt.Fprintln(f, "    b = wellib.AppendVarint(b, uint64(vs))")
+		fmt.Fprintln(f, "    b = v.MarshalWellsCached(b, &vc)")
+	} else {
+		writeAppendValue(f, vt, "v")
+	}
+	fmt.Fprintln(f, "  }")
+}
+
+// sizeExpr returns an expression for the encoded size of a value without its
+// field key.
+func sizeExpr(t fieldType, expr string) string {
+	switch {
+	case t.isMessage():
+		return fmt.Sprintf("wellib.SizeBytes(%s.SizeWells())", expr)
+	case t.Scalar == "bool":
+		return "1"
+	case t.wireType() == wellsrpc.WireFixed32:
+		return "4"
+	case t.wireType() == wellsrpc.WireFixed64:
+		return "8"
+	case t.wireType() == wellsrpc.WireBytes:
+		return fmt.Sprintf("wellib.SizeBytes(len(%s))", expr)
+	default:
+		return fmt.Sprintf("wellib.SizeVarint(%s)", varintExpr(t, expr))
+	}
+}
+
+// cachedSizeExpr is sizeExpr for a value outside a map. The size of a
+// message is recorded in c, in a slot reserved before the message records
+// its own nested messages.
+func cachedSizeExpr(t fieldType, expr string) string {
+	if t.isMessage() {
+		return fmt.Sprintf("wellib.SizeBytes(c.Set(c.Reserve(), %s.CacheSizeWells(c)))", expr)
+	}
+	return sizeExpr(t, expr)
+}
+
+// writeAppendValue emits the encoding of expr without its field key. The
+// size of a message comes from c, in the order cachedSizeExpr recorded it.
+func writeAppendValue(f io.Writer, t fieldType, expr string) {
+	switch {
+	case t.isMessage():
+		fmt.Fprintln(f, "  b = wellib.AppendVarint(b, uint64(c.Next()))")
+		fmt.Fprintf(f, "  b = %s.MarshalWellsCached(b, c)\n", expr)
+	case t.Scalar == "bool":
+		fmt.Fprintf(f, "  if %s {\n    b = append(b, 1)\n  } else {\n    b = append(b, 0)\n  }\n", expr)
+	case t.wireType() == wellsrpc.WireVarint:
+		fmt.Fprintf(f, "  b = wellib.AppendVarint(b, %s)\n", varintExpr(t, expr))
+	case t.wireType() == wellsrpc.WireFixed32:
+		fmt.Fprintf(f, "  b = wellib.AppendFloat32LE(b, %s)\n", expr)
+	case t.wireType() == wellsrpc.WireFixed64:
+		fmt.Fprintf(f, "  b = wellib.AppendFloat64LE(b, %s)\n", expr)
+	case t.Scalar == "string":
+		fmt.Fprintf(f, "  b = wellib.AppendString(b, %s)\n", expr)
+	default:
+		fmt.Fprintf(f, "  b = wellib.AppendBytes(b, %s)\n", expr)
+	}
+}
+
+// varintExpr converts expr to the uint64 written on the wire. Signed integers
+// are zigzag encoded; enums are sign-extended like protobuf enums.
+func varintExpr(t fieldType, expr string) string {
+	switch t.Scalar {
+	case "int32":
+		return fmt.Sprintf("wellib.ZigzagEncode(int64(%s))", expr)
+	case "int64":
+		return fmt.Sprintf("wellib.ZigzagEncode(%s)", expr)
+	case "uint64":
+		return expr
+	default:
+		return fmt.Sprintf("uint64(%s)", expr)
+	}
+}
+
+func writeUnmarshal(f *codeWriter, msg *idl.Message) {
+	fmt.Fprintf(f, "\nfunc (m *%s) UnmarshalWells(b []byte) error {\n", msg.GoName())
+	fmt.Fprintln(f, "  return m.UnmarshalWellsDepth(b, wellib.MaxDepth)")
+	fmt.Fprintln(f, "}")
+	fmt.Fprintln(f, "\n// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth")
+	fmt.Fprintln(f, "// more levels of nested messages.")
+	fmt.Fprintf(f, "func (m *%s) UnmarshalWellsDepth(b []byte, depth int) error {\n", msg.GoName())
+	fmt.Fprintln(f, "  if depth < 0 {\n    return wellib.ErrMaxDepth\n  }")
+	fmt.Fprintln(f, "  var i int")
+	fmt.Fprintln(f, "  for i < len(b) {")
+	if f.keepUnknown {
+		fmt.Fprintln(f, "    start := i")
+	}
+	// A message without fields skips every field, whatever its number.
+	if len(msg.Fields) == 0 {
+		fmt.Fprintln(f, "    _, wireType, n := wellib.ReadTag(b[i:])")
+	} else {
+		fmt.Fprintln(f, "    num, wireType, n := wellib.ReadTag(b[i:])")
+	}
+	fmt.Fprintf(f, "    if n == 0 {\n      return errors.New(\"%s: invalid field key\")\n    }\n", msg.FullName())
+	fmt.Fprintln(f, "    i += n")
+	fmt.Fprintln(f, "    switch {")
+	for _, field := range msg.Fields {
+		name := "m." + field.GoName()
+		errPrefix := msg.FullName() + "." + field.Name
+		t := f.valueType(field)
+		switch {
+		case field.Oneof != nil:
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, t.wireType()))
+			writeDecodeValue(f, t, errPrefix)
+			fmt.Fprintf(f, "      m.%s = &%s{%s: v}\n", field.Oneof.GoName(), msg.GoOneofWrapper(field), field.GoName())
+		case field.IsMap():
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, wellsrpc.WireBytes))
+			writeMapEntryDecode(f, field, name, errPrefix)
+		case field.Repeated && t.packable():
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, wellsrpc.WireBytes))
+			writeReadLength(f, errPrefix)
+			fmt.Fprintln(f, "      end := i + int(l)")
+			fmt.Fprintln(f, "      for i < end {")
+			writeDecodeValue(f, t, errPrefix)
+			fmt.Fprintf(f, "        %s = append(%s, v)\n", name, name)
+			fmt.Fprintln(f, "      }")
+			fmt.Fprintf(f, "      if i != end {\n        return errors.New(\"%s: malformed packed data\")\n      }\n", errPrefix)
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, t.wireType()))
+			writeDecodeValue(f, t, errPrefix)
+			fmt.Fprintf(f, "      %s = append(%s, v)\n", name, name)
+		case field.Repeated:
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, t.wireType()))
+			writeDecodeValue(f, t, errPrefix)
+			fmt.Fprintf(f, "      %s = append(%s, v)\n", name, name)
+		case t.isMessage():
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, wellsrpc.WireBytes))
+			writeReadLength(f, errPrefix)
+			fmt.Fprintf(f, "      if %s == nil {\n        %s = &%s{}\n      }\n", name, name, t.GoName)
+			fmt.Fprintf(f, "      if err := %s.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {\n        return err\n      }\n", name)
+			fmt.Fprintln(f, "      i += int(l)")
+		default:
+			fmt.Fprintf(f, "    case %s:\n", fieldCase("num", "wireType", field.Number, t.wireType()))
+			writeDecodeValue(f, t, errPrefix)
+			fmt.Fprintf(f, "      %s = v\n", name)
+		}
+	}
+	fmt.Fprintln(f, "    default:")
+	fmt.Fprintln(f, "      n, err := wellib.SkipField(b[i:], wireType)")
+	fmt.Fprintln(f, "      if err != nil {\n        return err\n      }")
+	fmt.Fprintln(f, "      i += n")
+	if f.keepUnknown {
+		fmt.Fprintln(f, "      m.unknownFields = append(m.unknownFields, b[start:i]...)")
+	}
+	fmt.Fprintln(f, "    }")
+	fmt.Fprintln(f, "  }")
+	fmt.Fprintln(f, "  return nil")
+	fmt.Fprintln(f, "}")
+}
+
+func writeMapEntryDecode(f *codeWriter, field *idl.Field, name, errPrefix string) {
+	kt, vt := keyType(field), f.valueType(field)
+	writeReadLength(f, errPrefix)
+	fmt.Fprintf(f, "      if %s == nil {\n        %s = make(%s)\n      }\n", name, name, f.goFieldType(field))
+	fmt.Fprintln(f, "      end := i + int(l)")
+	fmt.Fprintf(f, "      var mk %s\n", kt.goType())
+	fmt.Fprintf(f, "      var mv %s\n", vt.goType())
+	fmt.Fprintln(f, "      for i < end {")
+	fmt.Fprintln(f, "        en, et, n := wellib.ReadTag(b[i:])")
+	fmt.Fprintf(f, "        if n == 0 {\n          return errors.New(\"%s: invalid entry key\")\n        }\n", errPrefix)
+	fmt.Fprintln(f, "        i += n")
+	fmt.Fprintln(f, "        switch {")
+	fmt.Fprintf(f, "        case %s:\n", fieldCase("en", "et", 1, kt.wireType()))
+	writeDecodeValue(f, kt, errPrefix)
+	fmt.Fprintln(f, "          mk = v")
+	fmt.Fprintf(f, "        case %s:\n", fieldCase("en", "et", 2, vt.wireType()))
+	writeDecodeValue(f, vt, errPrefix)
+	fmt.Fprintln(f, "          mv = v")
+	fmt.Fprintln(f, "        default:")
+	fmt.Fprintln(f, "          n, err := wellib.SkipField(b[i:], et)")
+	fmt.Fprintln(f, "          if err != nil {\n            return err\n          }")
+	fmt.Fprintln(f, "          i += n")
+	fmt.Fprintln(f, "        }")
+	fmt.Fprintln(f, "      }")
+	fmt.Fprintf(f, "      if i != end {\n        return errors.New(\"%s: malformed map entry\")\n      }\n", errPrefix)
+	if vt.isMessage() {
+		fmt.Fprintf(f, "      if mv == nil {\n        mv = &%s{}\n      }\n", vt.GoName)
+	}
+	fmt.Fprintf(f, "      %s[mk] = mv\n", name)
+}
+
+// writeDecodeValue emits code that decodes one value of type t at b[i:] into
+// a new variable v and advances i past it.
+func writeDecodeValue(f io.Writer, t fieldType, errPrefix string) {
+	switch t.wireType() {
+	case wellsrpc.WireVarint:
+		fmt.Fprintln(f, "      x, n, err := wellib.ReadVarint(b[i:])")
+		fmt.Fprintf(f, "      if err != nil {\n        return fmt.Errorf(\"%s: %%w\", err)\n      }\n", errPrefix)
+		fmt.Fprintln(f, "      i += n")
+		switch {
+		case t.Enum:
+			fmt.Fprintf(f, "      v := %s(int32(x))\n", t.GoName)
+		case t.Scalar == "int32":
+			fmt.Fprintln(f, "      v := int32(wellib.ZigzagDecode(x))")
+		case t.Scalar == "int64":
+			fmt.Fprintln(f, "      v := wellib.ZigzagDecode(x)")
+		case t.Scalar == "uint32":
+			fmt.Fprintln(f, "      v := uint32(x)")
+		case t.Scalar == "uint64":
+			fmt.Fprintln(f, "      v := x")
+		case t.Scalar == "bool":
+			fmt.Fprintln(f, "      v := x != 0")
+		}
+	case wellsrpc.WireFixed32:
+		fmt.Fprintf(f, "      if i+4 > len(b) {\n        return errors.New(\"%s: truncated\")\n      }\n", errPrefix)
+		fmt.Fprintln(f, "      v := wellib.ReadFloat32LE(b[i : i+4])")
+		fmt.Fprintln(f, "      i += 4")
+	case wellsrpc.WireFixed64:
+		fmt.Fprintf(f, "      if i+8 > len(b) {\n        return errors.New(\"%s: truncated\")\n      }\n", errPrefix)
+		fmt.Fprintln(f, "      v := wellib.ReadFloat64LE(b[i : i+8])")
+		fmt.Fprintln(f, "      i += 8")
+	default:
+		writeReadLength(f, errPrefix)
+		switch t.Scalar {
+		case "string":
+			fmt.Fprintln(f, "      v := string(b[i : i+int(l)])")
+		case "bytes":
+			fmt.Fprintln(f, "      v := append([]byte(nil), b[i:i+int(l)]...)")
+		default:
+			fmt.Fprintf(f, "      v := &%s{}\n", t.GoName)
+			fmt.Fprintln(f, "      if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {\n        return err\n      }")
+		}
+		fmt.Fprintln(f, "      i += int(l)")
+	}
+}
+
+// writeReadLength emits code that reads a length prefix into l and checks
+// that l bytes follow it.
+func writeReadLength(f io.Writer, errPrefix string) {
+	fmt.Fprintln(f, "      l, n, err := wellib.ReadVarint(b[i:])")
+	fmt.Fprintf(f, "      if err != nil {\n        return fmt.Errorf(\"%s: length: %%w\", err)\n      }\n", errPrefix)
+	fmt.Fprintln(f, "      i += n")
+	fmt.Fprintf(f, "      if uint64(len(b)-i) < l {\n        return errors.New(\"%s: truncated\")\n      }\n", errPrefix)
+}
+
+func zeroValue(t fieldType) string {
+	switch {
+	case t.isMessage() || t.Scalar == "bytes":
+		return "nil"
+	case t.Scalar == "string":
+		return `""`
+	case t.Scalar == "bool":
+		return "false"
+	default:
+		return "0"
+	}
+}
+
+func (f *codeWriter) goFieldType(field *idl.Field) string {
+	switch {
+	case field.IsMap():
+		return "map[" + keyType(field).goType() + "]" + f.valueType(field).goType()
+	case field.Repeated:
+		return "[]" + f.valueType(field).goType()
+	default:
+		return f.valueType(field).goType()
+	}
+}
+
+// fieldCase returns the switch case matching a key that wellib.ReadTag
+// decoded into numVar and typeVar.
+func fieldCase(numVar, typeVar string, num, wireType int) string {
+	return fmt.Sprintf("%s == %d && %s == wellib.%s", numVar, num, typeVar, wireTypeName(wireType))
+}
+
+func wireTypeName(wireType int) string {
+	switch wireType {
+	case wellsrpc.WireVarint:
+		return "WireVarint"
+	case wellsrpc.WireFixed64:
+		return "WireFixed64"
+	case wellsrpc.WireFixed32:
+		return "WireFixed32"
+	default:
+		return "WireBytes"
+	}
+}
+
+// keySize returns the size of the key of field tag.
+func keySize(tag, wireType int) int {
+	return len(wellsrpc.AppendTag(nil, tag, wireType))
+}
+
+// tagBytes returns the key of field tag as a list of byte literals, so
+// encoders append a constant instead of calling wellib.AppendTag.
+func tagBytes(tag, wireType int) string {
+	enc := wellsrpc.AppendTag(nil, tag, wireType)
+	parts := make([]string, len(enc))
+	for i, c := range enc {
+		parts[i] = fmt.Sprintf("0x%02X", c)
+	}
+	return strings.Join(parts, ", ")
+}
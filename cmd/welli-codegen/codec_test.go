@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nestedIDL = `package acme.nest;
+
+message Shared {
+  string id = 1;
+}
+
+message Outer {
+  message Inner {
+    enum Kind {
+      KIND_NONE = 0;
+      KIND_LEAF = 1;
+    }
+    Kind kind = 1;
+    Shared shared = 2;
+  }
+  message Shared {
+    int32 local = 1;
+  }
+  Inner inner = 1;
+  repeated Inner.Kind kinds = 2;
+  Shared shared = 3;
+  acme.nest.Shared top = 4;
+  map<string, Inner> named = 5;
+}
+`
+
+// generateOne generates the single IDL file src in a test module and returns
+// the module directory and the generated messages.
+func generateOne(t *testing.T, name, src, out string) (string, string) {
+	t.Helper()
+	dir := newTestModule(t, map[string]string{name + ".wb.idl": src})
+	generate(t, dir, "idl", "gen")
+	b, err := os.ReadFile(filepath.Join(dir, "gen", filepath.FromSlash(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, string(b)
+}
+
+func TestGenerateNestedTypes(t *testing.T) {
+	dir, src := generateOne(t, "nest", nestedIDL, "acme/nest/nest.wells.go")
+	// Nested types are named after their enclosing messages, and a field
+	// refers to the innermost declaration in scope.
+	for _, want := range []string{
+		"type Outer struct {\n\tInner  *Outer_Inner\n\tKinds  []Outer_Inner_Kind\n\tShared *Outer_Shared\n\tTop    *Shared\n\tNamed  map[string]*Outer_Inner\n",
+		"type Outer_Inner struct {\n\tKind   Outer_Inner_Kind\n\tShared *Outer_Shared\n",
+		"type Outer_Shared struct {\n\tLocal int32\n",
+		"type Outer_Inner_Kind int32",
+		"Outer_Inner_Kind_KIND_LEAF Outer_Inner_Kind = 1",
+		"func (x Outer_Inner_Kind) String() string {",
+		"func (m *Outer_Inner) MarshalWells() []byte {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, src)
+		}
+	}
+	goBuild(t, dir)
+}
+
+func TestGenerateEnum(t *testing.T) {
+	const src = `package acme.enums;
+
+enum Status {
+  STATUS_UNKNOWN = 0;
+  STATUS_FAILED  = -1;
+  STATUS_OK      = 200;
+}
+`
+	dir, got := generateOne(t, "enums", src, "acme/enums/enums.wells.go")
+	for _, want := range []string{
+		"type Status int32\n",
+		"const (\n\tStatus_STATUS_UNKNOWN Status = 0\n\tStatus_STATUS_FAILED  Status = -1\n\tStatus_STATUS_OK      Status = 200\n)\n",
+		"var Status_name = map[int32]string{\n\t0:   \"STATUS_UNKNOWN\",\n\t-1:  \"STATUS_FAILED\",\n\t200: \"STATUS_OK\",\n}\n",
+		"var Status_value = map[string]int32{\n\t\"STATUS_UNKNOWN\": 0,\n\t\"STATUS_FAILED\":  -1,\n\t\"STATUS_OK\":      200,\n}\n",
+		"func (x Status) String() string {\n\tif s, ok := Status_name[int32(x)]; ok {\n\t\treturn s\n\t}\n\treturn strconv.Itoa(int(x))\n}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, got)
+		}
+	}
+	goBuild(t, dir)
+}
+
+// TestGenerateImports builds decoders that use less than most: one for a
+// message without fields, which ignores field numbers, and one that reads no
+// varints, so the file must not import fmt for the errors of ReadVarint.
+func TestGenerateImports(t *testing.T) {
+	const src = `package acme.floats;
+
+message Empty {}
+
+message Point {
+  float x  = 1;
+  double y = 2;
+}
+`
+	dir, got := generateOne(t, "floats", src, "acme/floats/floats.wells.go")
+	if strings.Contains(got, `"fmt"`) {
+		t.Errorf("generated code imports fmt:\n%s", got)
+	}
+	goBuild(t, dir)
+}
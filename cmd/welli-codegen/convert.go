@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	outDir := fs.String("out", "", "Directory for the converted files (default: next to each input)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `
+Usage:
+  welli-codegen convert [-out <dir>] <file.proto | file.wb.idl>...
+
+Converts proto3 files to .wb.idl and .wb.idl files to proto3. The output is
+named after the input with the other extension.
+
+Types are mapped so both sides use the same wire encoding:
+  proto sint32, sint64   <->  idl int32, int64 (zigzag)
+  proto int32, int64      ->  idl uint32, uint64 (plain varint)
+  proto float, double    <->  idl float, double
+
+Constructs the IDL cannot express, such as fixed-width integer types,
+extensions and custom or field options, are left out and reported as
+errors; [packed = true] is accepted, since the IDL always packs repeated
+numbers. Conversions that change a Go type are reported as warnings. The
+exit status is 1 when anything was left out.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var notes findings
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+
+		var out []byte
+		var target string
+		switch {
+		case strings.HasSuffix(path, ".proto"):
+			file, fileNotes, err := idl.ParseProto(path, src)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "❌ Error:", err)
+				return 2
+			}
+			for _, n := range fileNotes {
+				if n.Dropped {
+					notes.report(n.Pos, severityError, "unsupported", "%s", n.Msg)
+				} else {
+					notes.report(n.Pos, severityWarning, "converted", "%s", n.Msg)
+				}
+			}
+			out = idl.Format(file)
+			target = strings.TrimSuffix(path, ".proto") + ".wb.idl"
+		case strings.HasSuffix(path, ".wb.idl"):
+			file, err := idl.Parse(path, src)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "❌ Error:", err)
+				return 2
+			}
+			out = idl.FormatProto(file)
+			target = strings.TrimSuffix(path, ".wb.idl") + ".proto"
+		default:
+			fmt.Fprintf(os.Stderr, "❌ Error: %s: expected a .proto or .wb.idl file\n", path)
+			return 2
+		}
+
+		if *outDir != "" {
+			if err := os.MkdirAll(*outDir, 0755); err != nil {
+				fmt.Fprintln(os.Stderr, "❌ Error:", err)
+				return 2
+			}
+			target = filepath.Join(*outDir, filepath.Base(target))
+		}
+		if err := os.WriteFile(target, out, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+		fmt.Printf("✅ %s -> %s\n", path, target)
+	}
+
+	notes.sort()
+	notes.write(os.Stderr, "text")
+	return notes.status(false)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// finding is one problem reported by the lint and breaking commands.
+type finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+type findings []finding
+
+func (fs *findings) report(pos idl.Pos, severity, rule, format string, args ...any) {
+	*fs = append(*fs, finding{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// reportLoadError records an error returned by idl.Load under the rule
+// "check".
+func (fs *findings) reportLoadError(err error) {
+	var list idl.ErrorList
+	var single *idl.Error
+	switch {
+	case errors.As(err, &list):
+		for _, e := range list {
+			fs.report(e.Pos, severityError, "check", "%s", e.Msg)
+		}
+	case errors.As(err, &single):
+		fs.report(single.Pos, severityError, "check", "%s", single.Msg)
+	default:
+		fs.report(idl.Pos{}, severityError, "check", "%s", err)
+	}
+}
+
+func (fs findings) sort() {
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// write prints fs as "file:line:col: severity: message (rule)" lines, or as
+// a JSON array when format is "json".
+func (fs findings) write(w io.Writer, format string) {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if fs == nil {
+			fs = findings{}
+		}
+		_ = enc.Encode(fs)
+		return
+	}
+	for _, f := range fs {
+		fmt.Fprintf(w, "%s:%d:%d: %s: %s (%s)\n", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+	}
+}
+
+// status returns the exit status for fs: 1 if it holds an error, or any
+// finding at all when strict is set.
+func (fs findings) status(strict bool) int {
+	for _, f := range fs {
+		if f.Severity == severityError || strict {
+			return 1
+		}
+	}
+	return 0
+}
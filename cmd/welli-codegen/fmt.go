@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := fs.Bool("w", false, "Write the result back to the source files")
+	check := fs.Bool("check", false, "List files that are not formatted and exit with status 1 if there are any")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `
+Usage:
+  welli-codegen fmt [-w | -check] <path>...
+
+Rewrites .wb.idl files in canonical form: two-space indentation, fields
+written as "type name = N;" with aligned numbers, sorted imports, and the
+package, imports and options ahead of the declarations. Comments are kept.
+Without -w or -check the formatted files are printed to standard output.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (*write && *check) {
+		fs.Usage()
+		return 2
+	}
+
+	var files []string
+	for _, path := range fs.Args() {
+		found, _, err := findIDLFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+		files = append(files, found...)
+	}
+
+	status := 0
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+		file, err := idl.Parse(path, src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+		out := idl.Format(file)
+		switch {
+		case *check:
+			if !bytes.Equal(src, out) {
+				fmt.Println(path)
+				status = 1
+			}
+		case *write:
+			if bytes.Equal(src, out) {
+				continue
+			}
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				fmt.Fprintln(os.Stderr, "❌ Error:", err)
+				return 2
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+	return status
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text or json")
+	strict := fs.Bool("strict", false, "Exit with status 1 on warnings as well as errors")
+	var importPaths stringList
+	fs.Var(&importPaths, "I", "Directory to search for imported IDL files (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `
+Usage:
+  welli-codegen lint [-format text|json] [-strict] [-I <dir>]... <path>...
+
+Checks .wb.idl files and reports, as errors:
+  check        problems the generator rejects, such as undefined types or
+               declarations that generate the same Go identifier
+  go-keyword   Go package names that are Go keywords
+
+and, as warnings:
+  naming       PascalCase messages, enums, services and rpcs; snake_case
+               fields and oneofs; UPPER_SNAKE_CASE enum values
+  field-number fields without an explicit number
+  number-gap   skipped field numbers that are not reserved
+  unused       messages no field or rpc refers to, when the files declare
+               a service
+
+The exit status is 1 when errors are found (or warnings, with -strict) and
+2 on usage errors.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "❌ Error: unknown format %q\n", *format)
+		return 2
+	}
+
+	var files []string
+	for _, path := range fs.Args() {
+		found, isDir, err := findIDLFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Error:", err)
+			return 2
+		}
+		if isDir {
+			importPaths = append(importPaths, path)
+		}
+		files = append(files, found...)
+	}
+
+	findings := lintFiles(importPaths, files)
+	findings.write(os.Stdout, *format)
+	return findings.status(*strict)
+}
+
+// lintFiles loads paths and returns every finding, sorted by position.
+func lintFiles(importPaths []string, paths []string) findings {
+	l := &linter{}
+	files, err := idl.Load(importPaths, paths...)
+	if err != nil {
+		l.reportLoadError(err)
+	}
+	if files != nil {
+		l.lint(files)
+	}
+	l.sort()
+	return l.findings
+}
+
+type linter struct {
+	findings
+}
+
+func (l *linter) lint(files []*idl.File) {
+	all := withImports(files)
+	hasService := false
+	for _, f := range files {
+		hasService = hasService || len(f.Services) > 0
+		l.naming(f)
+		l.keywords(f)
+		for _, msg := range f.AllMessages() {
+			l.numbers(msg)
+		}
+	}
+	if hasService {
+		l.unused(files, all)
+	}
+}
+
+// withImports returns files followed by everything they import, each once.
+func withImports(files []*idl.File) []*idl.File {
+	var out []*idl.File
+	seen := map[*idl.File]bool{}
+	var walk func(f *idl.File)
+	walk = func(f *idl.File) {
+		if f == nil || seen[f] {
+			return
+		}
+		seen[f] = true
+		out = append(out, f)
+		for _, imp := range f.Imports {
+			walk(imp.File)
+		}
+	}
+	for _, f := range files {
+		walk(f)
+	}
+	return out
+}
+
+var (
+	pascalCase     = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	snakeCase      = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	upperSnakeCase = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+)
+
+func (l *linter) naming(f *idl.File) {
+	for _, msg := range f.AllMessages() {
+		if !pascalCase.MatchString(msg.Name) {
+			l.report(msg.Pos, severityWarning, "naming", "message %s should be PascalCase", msg.FullName())
+		}
+		for _, oneof := range msg.Oneofs {
+			if !snakeCase.MatchString(oneof.Name) {
+				l.report(oneof.Pos, severityWarning, "naming", "message %s: oneof %s should be snake_case", msg.FullName(), oneof.Name)
+			}
+		}
+		for _, field := range msg.Fields {
+			if !snakeCase.MatchString(field.Name) {
+				l.report(field.Pos, severityWarning, "naming", "message %s: field %s should be snake_case", msg.FullName(), field.Name)
+			}
+		}
+	}
+	for _, enum := range f.AllEnums() {
+		if !pascalCase.MatchString(enum.Name) {
+			l.report(enum.Pos, severityWarning, "naming", "enum %s should be PascalCase", enum.FullName())
+		}
+		for _, v := range enum.Values {
+			if !upperSnakeCase.MatchString(v.Name) {
+				l.report(v.Pos, severityWarning, "naming", "enum %s: value %s should be UPPER_SNAKE_CASE", enum.FullName(), v.Name)
+			}
+		}
+	}
+	for _, srv := range f.Services {
+		if !pascalCase.MatchString(srv.Name) {
+			l.report(srv.Pos, severityWarning, "naming", "service %s should be PascalCase", srv.Name)
+		}
+		for _, rpc := range srv.RPCs {
+			if !pascalCase.MatchString(rpc.Name) {
+				l.report(rpc.Pos, severityWarning, "naming", "service %s: rpc %s should be PascalCase", srv.Name, rpc.Name)
+			}
+		}
+	}
+}
+
+// keywords reports Go package names that are Go keywords. Check rejects
+// every other name that does not generate a valid Go identifier.
+func (l *linter) keywords(f *idl.File) {
+	if pkg, err := newGoPackages(".").of(f); err == nil && token.IsKeyword(pkg.Name) {
+		l.report(idl.Pos{Filename: f.Name, Line: 1, Column: 1}, severityError, "go-keyword", "Go package name %s is a Go keyword", pkg.Name)
+	}
+}
+
+func (l *linter) numbers(msg *idl.Message) {
+	for _, field := range msg.Fields {
+		if !field.HasNumber {
+			l.report(field.Pos, severityWarning, "field-number", "message %s: field %s has no explicit number; it gets %d from its position", msg.FullName(), field.Name, field.Number)
+		}
+	}
+
+	var used []int
+	for _, field := range msg.Fields {
+		used = append(used, field.Number)
+	}
+	sort.Ints(used)
+	var reserved []idl.Range
+	for _, res := range msg.Reserved {
+		reserved = append(reserved, res.Ranges...)
+	}
+	sort.Slice(reserved, func(i, j int) bool { return reserved[i].Start < reserved[j].Start })
+
+	next := 1
+	for _, n := range used {
+		if n > next {
+			for _, gap := range unreserved(idl.Range{Start: next, End: n - 1}, reserved) {
+				if gap.Start == gap.End {
+					l.report(msg.Pos, severityWarning, "number-gap", "message %s: field number %d is skipped but not reserved", msg.FullName(), gap.Start)
+				} else {
+					l.report(msg.Pos, severityWarning, "number-gap", "message %s: field numbers %d to %d are skipped but not reserved", msg.FullName(), gap.Start, gap.End)
+				}
+			}
+		}
+		if n+1 > next {
+			next = n + 1
+		}
+	}
+}
+
+// unreserved returns the parts of r not covered by reserved, which must be
+// sorted by Start.
+func unreserved(r idl.Range, reserved []idl.Range) []idl.Range {
+	var out []idl.Range
+	start := r.Start
+	for _, res := range reserved {
+		if res.End < start || res.Start > r.End {
+			continue
+		}
+		if res.Start > start {
+			out = append(out, idl.Range{Start: start, End: res.Start - 1})
+		}
+		start = res.End + 1
+		if start > r.End {
+			return out
+		}
+	}
+	return append(out, idl.Range{Start: start, End: r.End})
+}
+
+// unused reports messages in linted files that no field or rpc refers to.
+func (l *linter) unused(linted, all []*idl.File) {
+	used := map[*idl.Message]bool{}
+	for _, f := range all {
+		for _, msg := range f.AllMessages() {
+			for _, field := range msg.Fields {
+				if field.Message != nil && field.Message != msg {
+					used[field.Message] = true
+				}
+			}
+		}
+		for _, srv := range f.Services {
+			for _, rpc := range srv.RPCs {
+				used[rpc.RequestType] = true
+				used[rpc.ResponseType] = true
+			}
+		}
+	}
+	for _, f := range linted {
+		for _, msg := range f.AllMessages() {
+			if !used[msg] {
+				l.report(msg.Pos, severityWarning, "unused", "message %s is not used by any field or rpc", msg.FullName())
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		file   string
+		want   []string // "line:col: severity: message (rule)"
+		status int
+	}{
+		{"clean.wb.idl", nil, 0},
+		{"check.wb.idl", []string{
+			"4:3: error: message Order: field item has undefined type Missing (check)",
+		}, 1},
+		{"naming.wb.idl", []string{
+			"3:1: warning: message reading_v1 should be PascalCase (naming)",
+			"4:3: warning: message reading_v1: field TimeStamp should be snake_case (naming)",
+			"5:3: warning: message reading_v1: oneof Source should be snake_case (naming)",
+			"10:1: warning: enum unit should be PascalCase (naming)",
+			"11:3: warning: enum unit: value celsius should be UPPER_SNAKE_CASE (naming)",
+			"15:1: warning: service Sensor_service should be PascalCase (naming)",
+			"16:3: warning: service Sensor_service: rpc send_reading should be PascalCase (naming)",
+		}, 0},
+		{"numbers.wb.idl", []string{
+			"3:1: warning: message Gaps: field number 2 is skipped but not reserved (number-gap)",
+			"3:1: warning: message Gaps: field number 5 is skipped but not reserved (number-gap)",
+			"3:1: warning: message Gaps: field numbers 7 to 8 are skipped but not reserved (number-gap)",
+			"8:3: warning: message Gaps: field d has no explicit number; it gets 10 from its position (field-number)",
+		}, 0},
+		{"unused.wb.idl", []string{
+			"13:1: warning: message Orphan is not used by any field or rpc (unused)",
+		}, 0},
+		{"keyword.wb.idl", []string{
+			"1:1: error: Go package name go is a Go keyword (go-keyword)",
+			"3:1: error: message type generates Go identifier type, which is a Go keyword (check)",
+			"3:1: warning: message type should be PascalCase (naming)",
+			"6:3: error: service Lookup: rpc select generates Go identifier select, which is a Go keyword (check)",
+			"6:3: warning: service Lookup: rpc select should be PascalCase (naming)",
+		}, 1},
+		{"goname.wb.idl", []string{
+			"4:3: error: message Order: field marshal_wells generates Go identifier MarshalWells, already used by the MarshalWells method (check)",
+			"6:5: error: message Order: the getter of oneof case name generates Go identifier GetName, already used by field get_name at testdata/lint/goname.wb.idl:8:3 (check)",
+			"13:1: error: service Store generates Go identifier StoreClient, already used by message StoreClient at testdata/lint/goname.wb.idl:11:1 (check)",
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "lint", tt.file)
+			fs := lintFiles(nil, []string{path})
+			var got []string
+			for _, f := range fs {
+				if f.File != path {
+					t.Errorf("finding in %s, want %s", f.File, path)
+				}
+				got = append(got, fmt.Sprintf("%d:%d: %s: %s (%s)", f.Line, f.Column, f.Severity, f.Message, f.Rule))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lint %s:\ngot  %q\nwant %q", tt.file, got, tt.want)
+			}
+			if status := fs.status(false); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			strict := 0
+			if len(tt.want) > 0 {
+				strict = 1
+			}
+			if status := fs.status(true); status != strict {
+				t.Errorf("strict status = %d, want %d", status, strict)
+			}
+		})
+	}
+}
+
+func TestLintJSON(t *testing.T) {
+	path := filepath.Join("testdata", "lint", "check.wb.idl")
+	var buf bytes.Buffer
+	lintFiles(nil, []string{path}).write(&buf, "json")
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.Bytes())
+	}
+	want := []map[string]any{{
+		"file":     path,
+		"line":     float64(4),
+		"column":   float64(3),
+		"severity": "error",
+		"rule":     "check",
+		"message":  "message Order: field item has undefined type Missing",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON findings = %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	lintFiles(nil, []string{filepath.Join("testdata", "lint", "clean.wb.idl")}).write(&buf, "json")
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("JSON without findings = %q, want an empty array", got)
+	}
+}
@@ -1,42 +1,68 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"go/format"
+	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
 )
 
-type rpcDef struct {
-	Method string
-	Req    string
-	Res    string
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-type messageDef struct {
-	Name   string
-	Fields []fieldDef
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
 }
 
-type fieldDef struct {
+type serviceDef struct {
 	Name string
-	Type string
-	Tag  int
+	RPCs []rpcDef
+}
+
+type rpcDef struct {
+	Method       string
+	Req          string
+	Res          string
+	ClientStream bool
+	ServerStream bool
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "breaking":
+			os.Exit(runBreaking(os.Args[2:]))
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "convert":
+			os.Exit(runConvert(os.Args[2:]))
+		}
+	}
+
 	var (
-		idlPath  string
-		outDir   string
-		showHelp bool
+		idlPath        string
+		outDir         string
+		importPaths    stringList
+		discardUnknown bool
+		showHelp       bool
 	)
 
 	flag.StringVar(&idlPath, "idl", "", "Path to .wb.idl file or directory containing IDL files")
 	flag.StringVar(&outDir, "out", "", "Output directory for generated Go code")
+	flag.Var(&importPaths, "I", "Directory to search for imported IDL files (repeatable)")
+	flag.BoolVar(&discardUnknown, "discard-unknown", false, "Generate decoders that drop unknown fields instead of keeping them")
 	flag.BoolVar(&showHelp, "help", false, "Show usage help")
 	flag.BoolVar(&showHelp, "h", false, "Show usage help (shorthand)")
 	flag.Parse()
@@ -51,33 +77,13 @@ func main() {
 		os.Exit(1)
 	}
 
-	info, err := os.Stat(idlPath)
+	files, isDir, err := findIDLFiles(idlPath)
 	if err != nil {
 		fmt.Println("❌ Error:", err)
 		os.Exit(1)
 	}
-
-	var files []string
-	if info.IsDir() {
-		err := filepath.Walk(idlPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() && strings.HasSuffix(info.Name(), ".wb.idl") {
-				files = append(files, path)
-			}
-			return nil
-		})
-		if err != nil {
-			fmt.Println("❌ Failed to scan directory:", err)
-			os.Exit(1)
-		}
-	} else {
-		if !strings.HasSuffix(info.Name(), ".wb.idl") {
-			fmt.Println("❌ Error: IDL file must have .wb.idl extension")
-			os.Exit(1)
-		}
-		files = append(files, idlPath)
+	if isDir {
+		importPaths = append(importPaths, idlPath)
 	}
 
 	if len(files) == 0 {
@@ -85,14 +91,56 @@ func main() {
 		return
 	}
 
-	for _, f := range files {
-		fmt.Printf("⚙️  Generating from %s...\n", f)
-		if err := generateService(f, outDir); err != nil {
-			fmt.Println("❌ Failed:", f, "error:", err)
+	loaded, err := idl.Load(importPaths, files...)
+	if err != nil {
+		fmt.Println("❌ Failed:", err)
+		os.Exit(1)
+	}
+
+	pkgs := newGoPackages(outDir)
+	failed := false
+	for _, f := range loaded {
+		fmt.Printf("⚙️  Generating from %s...\n", f.Name)
+		if err := generateFile(f, pkgs, discardUnknown); err != nil {
+			fmt.Println("❌ Failed:", f.Name, "error:", err)
+			failed = true
 		} else {
-			fmt.Printf("✅ Successfully generated from %s\n", f)
+			fmt.Printf("✅ Successfully generated from %s\n", f.Name)
 		}
 	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// findIDLFiles returns path itself when it is a .wb.idl file, or every
+// .wb.idl file below it when it is a directory.
+func findIDLFiles(path string) ([]string, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		if !strings.HasSuffix(info.Name(), ".wb.idl") {
+			return nil, false, fmt.Errorf("IDL file must have .wb.idl extension: %s", path)
+		}
+		return []string{path}, false, nil
+	}
+
+	var files []string
+	err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".wb.idl") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to scan directory: %w", err)
+	}
+	return files, true, nil
 }
 
 func printHelp() {
@@ -100,141 +148,132 @@ func printHelp() {
 WellsRPC Code Generator
 
 Usage:
-  welli-codegen -idl <path> -out <output_dir>
+  welli-codegen -idl <path> -out <output_dir> [-I <dir>]... [-discard-unknown]
+  welli-codegen lint [-format text|json] [-strict] [-I <dir>]... <path>...
+  welli-codegen breaking -old <path> -new <path> [-format text|json] [-strict]
+  welli-codegen fmt [-w | -check] <path>...
+  welli-codegen convert [-out <dir>] <file.proto | file.wb.idl>...
 
 Examples:
   welli-codegen -idl ./idl/sensor.wb.idl -out ./wellsrpc
   welli-codegen -idl ./idl -out ./generated
+  welli-codegen -idl ./idl/sensor.wb.idl -I ./idl -out ./generated
+  welli-codegen lint -format json ./idl
+  welli-codegen breaking -old ./idl-v1 -new ./idl
+  welli-codegen fmt -check ./idl
+  welli-codegen convert -out ./idl ./proto/sensor.proto
 
 Options:
   -idl        Path to .wb.idl file or directory containing IDL files
   -out        Output directory for generated Go code
+  -I          Directory to search for imported IDL files; may be repeated.
+              Imports are looked up next to the importing file first, and
+              the -idl directory is searched when it is one
+  -discard-unknown
+              Drop unknown fields when decoding. By default messages keep
+              them and write them back, so a service on an older schema
+              passes on fields added by newer peers
   -h, --help  Show this help message
 `)
 }
 
-func generateService(idlPath, outBase string) error {
-	data, err := os.ReadFile(idlPath)
+// generateFile writes the code for one IDL file into the Go package chosen
+// by pkgs: <base>.wells.go for its messages and enums and, when it declares
+// services, <base>_server.wells.go and <base>_client.wells.go for all of them.
+// Each message is generated only by the file that declares it.
+func generateFile(file *idl.File, pkgs *goPackages, discardUnknown bool) error {
+	pkg, err := pkgs.of(file)
 	if err != nil {
 		return err
 	}
-
-	scanner := bufio.NewScanner(strings.NewReader(string(data)))
-	var srvName string
-	rpcs := []rpcDef{}
-	messages := []messageDef{}
-
-	serviceRe := regexp.MustCompile(`^service\s+(\w+)`)
-	rpcRe := regexp.MustCompile(`rpc\s+(\w+)\s*\(\s*(\w+)\s*\)\s*returns\s*\(\s*(\w+)\s*\)`)
-	messageRe := regexp.MustCompile(`^message\s+(\w+)`)
-	fieldRe := regexp.MustCompile(`^(\w+)\s+(\w+);`)
-
-	var currentMsg *messageDef
-	tagCounter := 1
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "//") {
-			continue
+	if err := os.MkdirAll(pkg.Dir, 0755); err != nil {
+		return err
+	}
+	base := filepath.Join(pkg.Dir, idlBaseName(file))
+	if len(file.Messages) > 0 || len(file.Enums) > 0 {
+		if err := writeCodec(base+".wells.go", pkg, file, pkgs, !discardUnknown); err != nil {
+			return err
 		}
+	}
+	if len(file.Services) == 0 {
+		fmt.Println("📦 Generated messages:", file.Name, "→", pkg.Dir)
+		return nil
+	}
 
-		if m := serviceRe.FindStringSubmatch(line); m != nil {
-			srvName = m[1]
-		}
-		if m := rpcRe.FindStringSubmatch(line); m != nil {
-			rpcs = append(rpcs, rpcDef{Method: m[1], Req: m[2], Res: m[3]})
+	imports := newImportSet(pkgs, pkg, idl.GoServiceImports...)
+	var services []serviceDef
+	for _, srv := range file.Services {
+		if len(srv.RPCs) == 0 {
+			return fmt.Errorf("service %s has no rpc definitions", srv.Name)
 		}
-		if m := messageRe.FindStringSubmatch(line); m != nil {
-			if currentMsg != nil {
-				messages = append(messages, *currentMsg)
+		def := serviceDef{Name: srv.Name}
+		for _, r := range srv.RPCs {
+			if r.Name == "Close" {
+				return fmt.Errorf("service %s: rpc Close clashes with the generated client's Close method", srv.Name)
 			}
-			currentMsg = &messageDef{Name: m[1], Fields: []fieldDef{}}
-			tagCounter = 1
-			continue
-		}
-		if currentMsg != nil {
-			if f := fieldRe.FindStringSubmatch(line); f != nil {
-				currentMsg.Fields = append(currentMsg.Fields, fieldDef{
-					Type: f[1],
-					Name: f[2],
-					Tag:  tagCounter,
-				})
-				tagCounter++
+			if err := imports.add(r.RequestType.File); err != nil {
+				return err
 			}
+			if err := imports.add(r.ResponseType.File); err != nil {
+				return err
+			}
+			def.RPCs = append(def.RPCs, rpcDef{
+				Method:       r.Name,
+				Req:          imports.qualify(r.RequestType.File, r.RequestType.GoName()),
+				Res:          imports.qualify(r.ResponseType.File, r.ResponseType.GoName()),
+				ClientStream: r.ClientStreaming,
+				ServerStream: r.ServerStreaming,
+			})
 		}
-	}
-	if currentMsg != nil {
-		messages = append(messages, *currentMsg)
+		services = append(services, def)
 	}
 
-	if srvName == "" || len(rpcs) == 0 {
-		return fmt.Errorf("no valid service or rpc definition in %s", idlPath)
-	}
-
-	pkgDir := filepath.Join(outBase, strings.ToLower(srvName))
-	if err := os.MkdirAll(pkgDir, 0755); err != nil {
-		return err
-	}
-
-	if err := writeCodec(pkgDir, messages); err != nil {
-		return err
-	}
-	if err := writeServer(pkgDir, srvName, rpcs); err != nil {
+	if err := writeServer(base+"_server.wells.go", pkg, services, imports); err != nil {
 		return err
 	}
-	if err := writeClient(pkgDir, srvName, rpcs); err != nil {
+	if err := writeClient(base+"_client.wells.go", pkg, services, imports); err != nil {
 		return err
 	}
 
-	fmt.Println("📦 Generated service:", srvName, "→", pkgDir)
+	for _, srv := range services {
+		fmt.Println("📦 Generated service:", srv.Name, "→", pkg.Dir)
+	}
 	return nil
 }
 
-func writeCodec(pkgDir string, messages []messageDef) error {
-	file := filepath.Join(pkgDir, "codec.go")
-	f, err := os.Create(file)
+func writeServer(path string, pkg *goPackage, services []serviceDef, imports *importSet) error {
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	fmt.Fprintf(f, "package %s\n\n", filepath.Base(pkgDir))
-	for _, msg := range messages {
-		fmt.Fprintf(f, "\ntype %s struct {\n", msg.Name)
-		for _, field := range msg.Fields {
-			fmt.Fprintf(f, "  %s %s\n", strings.Title(field.Name), mapType(field.Type))
-		}
-		fmt.Fprintln(f, "}")
+	fmt.Fprintf(f, "package %s\n\n", pkg.Name)
+	fmt.Fprintln(f, `import (
+  "context"
+  wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"`)
+	imports.write(f)
+	fmt.Fprintln(f, ")")
 
-		fmt.Fprintf(f, "\nfunc (m *%s) MarshalWells() []byte { return nil }\n", msg.Name)
-		fmt.Fprintf(f, "func (m *%s) UnmarshalWells(b []byte) error { return nil }\n", msg.Name)
+	for _, srv := range services {
+		writeServiceServer(f, srv.Name, srv.RPCs)
 	}
-
 	return formatFile(f)
 }
 
-func writeServer(pkgDir, srvName string, rpcs []rpcDef) error {
-	file := filepath.Join(pkgDir, "server.go")
-	f, err := os.Create(file)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	fmt.Fprintf(f, "package %s\n\n", filepath.Base(pkgDir))
-	fmt.Fprintln(f, `import (
-  "context"
-  wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
-)`)
-
+func writeServiceServer(f io.Writer, srvName string, rpcs []rpcDef) {
 	fmt.Fprintf(f, "\ntype %sServer interface {\n", srvName)
 	for _, r := range rpcs {
-		fmt.Fprintf(f, "  %s(ctx context.Context, req *%s) (*%s, error)\n", r.Method, r.Req, r.Res)
+		fmt.Fprintf(f, "  %s\n", serverMethod(srvName, r))
 	}
 	fmt.Fprintln(f, "}")
 
 	fmt.Fprintf(f, "\nfunc Register%sServer(srv *wellib.RPCServer, impl %sServer) {\n", srvName, srvName)
 	for _, r := range rpcs {
+		if r.ClientStream || r.ServerStream {
+			writeStreamRegister(f, srvName, r)
+			continue
+		}
 		fmt.Fprintf(f, "  srv.Register(\"%s.%s\", func(ctx context.Context, payload []byte) ([]byte, error) {\n", srvName, r.Method)
 		fmt.Fprintf(f, "    var req %s\n", r.Req)
 		fmt.Fprintln(f, "    if err := req.UnmarshalWells(payload); err != nil { return nil, err }")
@@ -245,48 +284,72 @@ func writeServer(pkgDir, srvName string, rpcs []rpcDef) error {
 	}
 	fmt.Fprintln(f, "}")
 
-	return formatFile(f)
+	for _, r := range rpcs {
+		if r.ClientStream || r.ServerStream {
+			writeStreamServerType(f, srvName, r)
+		}
+	}
 }
 
-func writeClient(pkgDir, srvName string, rpcs []rpcDef) error {
-	file := filepath.Join(pkgDir, "client.go")
-	f, err := os.Create(file)
+func writeClient(path string, pkg *goPackage, services []serviceDef, imports *importSet) error {
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	fmt.Fprintf(f, "package %s\n\n", filepath.Base(pkgDir))
+	fmt.Fprintf(f, "package %s\n\n", pkg.Name)
 	fmt.Fprintln(f, `import (
   "context"
-  wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
-)`)
+  "errors"
+  wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"`)
+	imports.write(f)
+	fmt.Fprintln(f, ")")
+
+	for _, srv := range services {
+		writeServiceClient(f, srv.Name, srv.RPCs)
+	}
+	return formatFile(f)
+}
+
+// writeServiceClient emits the <Srv>Client interface, an unexported
+// implementation and the New/Dial constructors returning it.
+func writeServiceClient(f io.Writer, srvName string, rpcs []rpcDef) {
+	iface, impl := srvName+"Client", idl.GoClientImpl(srvName)
+	fmt.Fprintf(f, "\n// %s is the client API for %s.\n", iface, srvName)
+	fmt.Fprintf(f, "type %s interface {\n", iface)
+	for _, r := range rpcs {
+		fmt.Fprintf(f, "  %s\n", clientMethod(srvName, r))
+	}
+	fmt.Fprintf(f, "  // Close closes the connection opened by Dial%s. It does nothing for a\n", iface)
+	fmt.Fprintf(f, "  // client from New%s, whose connection belongs to the caller.\n", iface)
+	fmt.Fprintln(f, "  Close() error")
+	fmt.Fprintln(f, "}")
 
-	fmt.Fprintf(f, "\ntype %sClient struct {\n  c *wellib.RPCClient\n}\n\n", srvName)
-	fmt.Fprintf(f, "func New%sClient(addr string) *%sClient {\n", srvName, srvName)
-	fmt.Fprintln(f, "  conn, _ := wellib.Dial(addr, nil)")
-	fmt.Fprintf(f, "  return &%sClient{c: conn}\n}\n", srvName)
+	fmt.Fprintf(f, "\ntype %s struct {\n  c *wellib.RPCClient\n  owned bool // c was dialed by Dial%s\n}\n", impl, iface)
+	fmt.Fprintf(f, "\n// New%s returns a %s that issues calls over cc.\n", iface, iface)
+	fmt.Fprintf(f, "func New%s(cc *wellib.RPCClient) (%s, error) {\n", iface, iface)
+	fmt.Fprintf(f, "  if cc == nil {\n    return nil, errors.New(\"New%s: nil client\")\n  }\n", iface)
+	fmt.Fprintf(f, "  return &%s{c: cc}, nil\n}\n", impl)
+	fmt.Fprintf(f, "\n// Dial%s connects to addr and returns a %s using that connection.\n", iface, iface)
+	fmt.Fprintf(f, "func Dial%s(addr string, opts ...wellib.DialOption) (%s, error) {\n", iface, iface)
+	fmt.Fprintln(f, "  cc, err := wellib.DialWithOptions(addr, opts...)")
+	fmt.Fprintln(f, "  if err != nil {\n    return nil, err\n  }")
+	fmt.Fprintf(f, "  return &%s{c: cc, owned: true}, nil\n}\n", impl)
+	fmt.Fprintf(f, "\nfunc (c *%s) Close() error {\n  if !c.owned {\n    return nil\n  }\n  return c.c.Close()\n}\n", impl)
 
 	for _, r := range rpcs {
-		fmt.Fprintf(f, "\nfunc (c *%sClient) %s(ctx context.Context, req *%s) (*%s, error) {\n", srvName, r.Method, r.Req, r.Res)
+		if r.ClientStream || r.ServerStream {
+			writeStreamClientMethod(f, srvName, r)
+			writeStreamClientType(f, srvName, r)
+			continue
+		}
+		fmt.Fprintf(f, "\nfunc (c *%s) %s {\n", impl, clientMethod(srvName, r))
 		fmt.Fprintf(f, "  var out %s\n", r.Res)
 		fmt.Fprintf(f, "  if err := c.c.Call(ctx, \"%s.%s\", req, &out); err != nil { return nil, err }\n", srvName, r.Method)
 		fmt.Fprintln(f, "  return &out, nil")
 		fmt.Fprintln(f, "}")
 	}
-
-	return formatFile(f)
-}
-
-func mapType(t string) string {
-	switch t {
-	case "int64", "int32", "float32", "float64", "string", "bool":
-		return t
-	case "bytes":
-		return "[]byte"
-	default:
-		return "*" + t
-	}
 }
 
 func formatFile(f *os.File) error {
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// goPackage is the Go package that holds the code generated for an IDL file.
+type goPackage struct {
+	Name       string
+	Dir        string
+	ImportPath string
+}
+
+// goPackages decides where the code for each IDL file is generated. Files
+// that resolve to the same directory share one Go package.
+//
+// "option go_package = "import/path;name";" names the package directly; the
+// ";name" suffix is optional. When the import path lies inside the module
+// enclosing the output directory the code goes to the matching directory,
+// otherwise to <out>/<name>. Without go_package, "package acme.common;" is
+// generated into <out>/acme/common, and a file with neither goes to a
+// directory named after the file. The import path is then derived from the
+// go.mod enclosing the output directory.
+type goPackages struct {
+	outBase string
+	cache   map[*idl.File]*goPackage
+	dirs    map[string]*goPackage
+}
+
+func newGoPackages(outBase string) *goPackages {
+	return &goPackages{outBase: outBase, cache: map[*idl.File]*goPackage{}, dirs: map[string]*goPackage{}}
+}
+
+func (p *goPackages) of(file *idl.File) (*goPackage, error) {
+	if pkg, ok := p.cache[file]; ok {
+		return pkg, nil
+	}
+
+	pkg := &goPackage{}
+	if opt := file.Option("go_package"); opt != "" {
+		importPath, name := opt, ""
+		if i := strings.LastIndexByte(opt, ';'); i >= 0 {
+			importPath, name = opt[:i], opt[i+1:]
+		}
+		if name == "" {
+			name = path.Base(importPath)
+		}
+		pkg.Name = goPackageName(name)
+		pkg.ImportPath = importPath
+		pkg.Dir = filepath.Join(p.outBase, pkg.Name)
+		if root, module, ok := findModule(p.outBase); ok {
+			if importPath == module {
+				pkg.Dir = root
+			} else if rel := strings.TrimPrefix(importPath, module+"/"); rel != importPath {
+				pkg.Dir = filepath.Join(root, filepath.FromSlash(rel))
+			}
+		}
+	} else {
+		rel := idlBaseName(file)
+		if file.Package != "" {
+			rel = strings.ReplaceAll(file.Package, ".", "/")
+		}
+		pkg.Name = goPackageName(path.Base(rel))
+		pkg.Dir = filepath.Join(p.outBase, filepath.FromSlash(rel))
+	}
+
+	// findModule returns an absolute root, so the import path is derived
+	// from the absolute directory; -out is usually relative.
+	dir, err := filepath.Abs(pkg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.ImportPath == "" {
+		if root, module, ok := findModule(dir); ok {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return nil, fmt.Errorf("%s: cannot place %s inside module %s: %w", file.Name, pkg.Dir, module, err)
+			}
+			pkg.ImportPath = module
+			if rel != "." {
+				pkg.ImportPath = module + "/" + filepath.ToSlash(rel)
+			}
+		}
+	}
+	if prev, ok := p.dirs[dir]; ok {
+		if prev.Name != pkg.Name {
+			return nil, fmt.Errorf("%s: Go package %s conflicts with package %s generated into %s", file.Name, pkg.Name, prev.Name, prev.Dir)
+		}
+		pkg = prev
+	}
+	p.dirs[dir] = pkg
+	p.cache[file] = pkg
+	return pkg, nil
+}
+
+// idlBaseName returns the file name of f without its ".wb.idl" suffix.
+func idlBaseName(f *idl.File) string {
+	return strings.TrimSuffix(filepath.Base(f.Name), ".wb.idl")
+}
+
+// goPackageName turns s into a valid Go package name.
+func goPackageName(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "p" + name
+	}
+	return name
+}
+
+// findModule returns the root directory and module path of the nearest
+// go.mod above dir. dir does not need to exist yet.
+func findModule(dir string) (string, string, bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if module, ok := readModulePath(filepath.Join(root, "go.mod")); ok {
+			return root, module, true
+		}
+		if filepath.Dir(root) == root {
+			return "", "", false
+		}
+	}
+}
+
+func readModulePath(gomod string) (string, bool) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		path := strings.TrimSpace(strings.TrimPrefix(line, "module"))
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		return path, path != ""
+	}
+	return "", false
+}
+
+// importSet collects the Go packages one generated file refers to and the
+// names they are imported under.
+type importSet struct {
+	pkgs    *goPackages
+	self    *goPackage
+	aliases map[string]string
+	paths   []string
+	taken   map[string]bool
+}
+
+// newImportSet returns an empty set for a file in package self. reserved
+// lists names the file already imports, which aliases must avoid.
+func newImportSet(pkgs *goPackages, self *goPackage, reserved ...string) *importSet {
+	s := &importSet{pkgs: pkgs, self: self, aliases: map[string]string{}, taken: map[string]bool{self.Name: true}}
+	for _, name := range reserved {
+		s.taken[name] = true
+	}
+	return s
+}
+
+// add imports the package generated for file unless it is s.self.
+func (s *importSet) add(file *idl.File) error {
+	pkg, err := s.pkgs.of(file)
+	if err != nil {
+		return err
+	}
+	if pkg == s.self {
+		return nil
+	}
+	if pkg.ImportPath == "" {
+		return fmt.Errorf("cannot determine the Go import path for %s: set option go_package or generate into a Go module", file.Name)
+	}
+	if _, ok := s.aliases[pkg.ImportPath]; ok {
+		return nil
+	}
+	alias := pkg.Name
+	for n := 2; s.taken[alias]; n++ {
+		alias = pkg.Name + strconv.Itoa(n)
+	}
+	s.taken[alias] = true
+	s.aliases[pkg.ImportPath] = alias
+	s.paths = append(s.paths, pkg.ImportPath)
+	return nil
+}
+
+// qualify returns name as written in s.self for a type declared in file.
+func (s *importSet) qualify(file *idl.File, name string) string {
+	pkg, err := s.pkgs.of(file)
+	if err != nil || pkg == s.self {
+		return name
+	}
+	return s.aliases[pkg.ImportPath] + "." + name
+}
+
+// write emits the import specs, to be placed inside an import block.
+func (s *importSet) write(w io.Writer) {
+	for _, path := range s.paths {
+		fmt.Fprintf(w, "  %s %q\n", s.aliases[path], path)
+	}
+}
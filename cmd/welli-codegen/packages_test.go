@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+const commonIDL = `package acme.common;
+
+message Money {
+  string currency = 1;
+  int64 units = 2;
+}
+`
+
+const devIDL = `package acme.dev;
+
+import "common.wb.idl";
+
+message Order {
+  string id = 1;
+  common.Money total = 2;
+}
+
+service OrderService {
+  rpc Quote (Order) returns (common.Money);
+  rpc Watch (Order) returns (stream common.Money);
+}
+`
+
+const billingIDL = `option go_package = "example.com/gen/api/billing;billing";
+
+import "common.wb.idl";
+
+message Invoice {
+  acme.common.Money amount = 1;
+}
+
+service BillingService {
+  rpc Charge (acme.common.Money) returns (Invoice);
+}
+`
+
+// newTestModule creates a module example.com/gen that uses this repository
+// through a replace directive, with the IDL files in its idl directory.
+func newTestModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repo, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	gomod := "module example.com/gen\n\ngo 1.18\n\n" +
+		"require github.com/welliardiansyah/wells-rpc v0.0.0\n\n" +
+		"replace github.com/welliardiansyah/wells-rpc => " + repo + "\n"
+	writeTestFile(t, filepath.Join(dir, "go.mod"), gomod)
+	for name, src := range files {
+		writeTestFile(t, filepath.Join(dir, "idl", name), src)
+	}
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// generate runs the steps of the default command from the working
+// directory dir, as "welli-codegen -idl idlPath -out out" would.
+func generate(t *testing.T, dir, idlPath, out string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	entries, err := os.ReadDir(idlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		files = append(files, filepath.Join(idlPath, e.Name()))
+	}
+	loaded, err := idl.Load([]string{idlPath}, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs := newGoPackages(out)
+	for _, f := range loaded {
+		if err := generateFile(f, pkgs, false); err != nil {
+			t.Fatalf("generating %s: %v", f.Name, err)
+		}
+	}
+}
+
+func goBuild(t *testing.T, dir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateImportsOtherPackage(t *testing.T) {
+	tests := []struct {
+		name string
+		out  func(dir string) string
+	}{
+		{"relative out", func(string) string { return "gen" }},
+		{"absolute out", func(dir string) string { return filepath.Join(dir, "gen") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestModule(t, map[string]string{
+				"common.wb.idl":  commonIDL,
+				"dev.wb.idl":     devIDL,
+				"billing.wb.idl": billingIDL,
+			})
+			generate(t, dir, "idl", tt.out(dir))
+
+			const common = `common "example.com/gen/gen/acme/common"`
+			for _, name := range []string{
+				"gen/acme/dev/dev.wells.go",
+				"gen/acme/dev/dev_client.wells.go",
+				"gen/acme/dev/dev_server.wells.go",
+				"api/billing/billing.wells.go",
+				"api/billing/billing_client.wells.go",
+			} {
+				src, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(src), common) {
+					t.Errorf("%s does not import %s:\n%s", name, common, src)
+				}
+			}
+			goBuild(t, dir)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// serverMethod returns the server interface method for r. A server-streaming
+// rpc receives its single request up front; a client- or bidi-streaming rpc
+// reads requests from the stream.
+func serverMethod(srvName string, r rpcDef) string {
+	switch {
+	case r.ClientStream:
+		return fmt.Sprintf("%s(ctx context.Context, stream *%s) error", r.Method, idl.GoStreamServer(srvName, r.Method))
+	case r.ServerStream:
+		return fmt.Sprintf("%s(ctx context.Context, req *%s, stream *%s) error", r.Method, r.Req, idl.GoStreamServer(srvName, r.Method))
+	default:
+		return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", r.Method, r.Req, r.Res)
+	}
+}
+
+func writeStreamRegister(f io.Writer, srvName string, r rpcDef) {
+	fmt.Fprintf(f, "  srv.RegisterStream(\"%s.%s\", func(ctx context.Context, s *wellib.Stream) error {\n", srvName, r.Method)
+	if r.ClientStream {
+		fmt.Fprintf(f, "    return impl.%s(ctx, &%s{stream: s})\n", r.Method, idl.GoStreamServer(srvName, r.Method))
+	} else {
+		fmt.Fprintln(f, "    b, err := s.Recv(ctx)")
+		fmt.Fprintln(f, "    if err != nil { return err }")
+		fmt.Fprintf(f, "    var req %s\n", r.Req)
+		fmt.Fprintln(f, "    if err := req.UnmarshalWells(b); err != nil { return err }")
+		fmt.Fprintf(f, "    return impl.%s(ctx, &req, &%s{stream: s})\n", r.Method, idl.GoStreamServer(srvName, r.Method))
+	}
+	fmt.Fprintln(f, "  })")
+}
+
+func writeStreamServerType(f io.Writer, srvName string, r rpcDef) {
+	name := idl.GoStreamServer(srvName, r.Method)
+	fmt.Fprintf(f, "\n// %s is the server side of %s.%s.\n", name, srvName, r.Method)
+	fmt.Fprintf(f, "type %s struct {\n  stream *wellib.Stream\n}\n", name)
+	switch {
+	case r.ServerStream:
+		writeStreamSend(f, name, "Send", r.Res)
+	default:
+		fmt.Fprintf(f, "\n// SendAndClose sends the response and ends the stream.\n")
+		fmt.Fprintf(f, "func (s *%s) SendAndClose(m *%s) error {\n", name, r.Res)
+		fmt.Fprintln(f, "  if err := s.stream.Send(m.MarshalWells()); err != nil { return err }")
+		fmt.Fprintln(f, "  return s.stream.CloseSend()")
+		fmt.Fprintln(f, "}")
+	}
+	if r.ClientStream {
+		writeStreamRecv(f, name, "Recv", r.Req)
+	}
+}
+
+// clientMethod returns the <Srv>Client method for r. A server-streaming rpc
+// sends its single request when the stream is opened.
+func clientMethod(srvName string, r rpcDef) string {
+	switch {
+	case r.ClientStream:
+		return fmt.Sprintf("%s(ctx context.Context) (*%s, error)", r.Method, idl.GoStreamClient(srvName, r.Method))
+	case r.ServerStream:
+		return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", r.Method, r.Req, idl.GoStreamClient(srvName, r.Method))
+	default:
+		return fmt.Sprintf("%s(ctx context.Context, req *%s) (*%s, error)", r.Method, r.Req, r.Res)
+	}
+}
+
+func writeStreamClientMethod(f io.Writer, srvName string, r rpcDef) {
+	name := idl.GoStreamClient(srvName, r.Method)
+	fmt.Fprintf(f, "\nfunc (c *%s) %s {\n", idl.GoClientImpl(srvName), clientMethod(srvName, r))
+	fmt.Fprintf(f, "  s, err := c.c.OpenStream(ctx, \"%s.%s\")\n", srvName, r.Method)
+	fmt.Fprintln(f, "  if err != nil { return nil, err }")
+	if !r.ClientStream {
+		fmt.Fprintln(f, "  if err := s.Send(req.MarshalWells()); err != nil {\n    s.Close()\n    return nil, err\n  }")
+		fmt.Fprintln(f, "  if err := s.CloseSend(); err != nil {\n    s.Close()\n    return nil, err\n  }")
+	}
+	fmt.Fprintf(f, "  return &%s{stream: s}, nil\n", name)
+	fmt.Fprintln(f, "}")
+}
+
+func writeStreamClientType(f io.Writer, srvName string, r rpcDef) {
+	name := idl.GoStreamClient(srvName, r.Method)
+	fmt.Fprintf(f, "\n// %s is the client side of %s.%s.\n", name, srvName, r.Method)
+	fmt.Fprintf(f, "type %s struct {\n  stream *wellib.Stream\n}\n", name)
+	if r.ClientStream {
+		writeStreamSend(f, name, "Send", r.Req)
+	}
+	switch {
+	case r.ClientStream && r.ServerStream:
+		writeStreamRecv(f, name, "Recv", r.Res)
+		fmt.Fprintf(f, "\n// CloseSend tells the server that no more requests follow.\n")
+		fmt.Fprintf(f, "func (s *%s) CloseSend() error {\n  return s.stream.CloseSend()\n}\n", name)
+	case r.ClientStream:
+		fmt.Fprintf(f, "\n// CloseAndRecv tells the server that no more requests follow and waits\n// for its response.\n")
+		fmt.Fprintf(f, "func (s *%s) CloseAndRecv(ctx context.Context) (*%s, error) {\n", name, r.Res)
+		fmt.Fprintln(f, "  if err := s.stream.CloseSend(); err != nil { return nil, err }")
+		writeStreamDecode(f, r.Res)
+		fmt.Fprintln(f, "}")
+	default:
+		writeStreamRecv(f, name, "Recv", r.Res)
+	}
+	fmt.Fprintf(f, "\n// Close abandons the stream.\n")
+	fmt.Fprintf(f, "func (s *%s) Close() {\n  s.stream.Close()\n}\n", name)
+}
+
+func writeStreamSend(f io.Writer, recv, method, typ string) {
+	fmt.Fprintf(f, "\nfunc (s *%s) %s(m *%s) error {\n", recv, method, typ)
+	fmt.Fprintln(f, "  return s.stream.Send(m.MarshalWells())")
+	fmt.Fprintln(f, "}")
+}
+
+// writeStreamRecv emits a typed Recv; it returns wellib.ErrStreamClosed once
+// the peer has finished sending.
+func writeStreamRecv(f io.Writer, recv, method, typ string) {
+	fmt.Fprintf(f, "\nfunc (s *%s) %s(ctx context.Context) (*%s, error) {\n", recv, method, typ)
+	writeStreamDecode(f, typ)
+	fmt.Fprintln(f, "}")
+}
+
+func writeStreamDecode(f io.Writer, typ string) {
+	fmt.Fprintln(f, "  b, err := s.stream.Recv(ctx)")
+	fmt.Fprintln(f, "  if err != nil { return nil, err }")
+	fmt.Fprintf(f, "  var m %s\n", typ)
+	fmt.Fprintln(f, "  if err := m.UnmarshalWells(b); err != nil { return nil, err }")
+	fmt.Fprintln(f, "  return &m, nil")
+}
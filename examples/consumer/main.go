@@ -12,6 +12,28 @@ import (
 	codec "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/codec_generated"
 )
 
+// sensorServer implements codec.SensorServiceServer.
+type sensorServer struct{}
+
+func (sensorServer) SendReading(ctx context.Context, req *codec.SensorReading) (*codec.Ack, error) {
+	fmt.Printf("received unary: ts=%d temp=%.2f hum=%.2f payload=%s\n",
+		req.Timestamp, req.Temperature, req.Humidity, string(req.Payload))
+	return &codec.Ack{Success: true}, nil
+}
+
+func (sensorServer) StreamReadings(ctx context.Context, stream *codec.SensorService_StreamReadingsServer) error {
+	for {
+		r, err := stream.Recv(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("stream recv: ts=%d temp=%.2f payload=%s\n", r.Timestamp, r.Temperature, string(r.Payload))
+		if err := stream.Send(&codec.Ack{Success: true}); err != nil {
+			return err
+		}
+	}
+}
+
 func main() {
 	addr := "127.0.0.1:9000"
 	srv := wellsrpc.NewRPCServer()
@@ -34,32 +56,7 @@ func main() {
 		return handler(ctx, payload)
 	})
 
-	srv.Register("SensorService.SendReading", func(ctx context.Context, payload []byte) ([]byte, error) {
-		var req codec.SensorReading
-		if err := req.UnmarshalWells(payload); err != nil {
-			return nil, err
-		}
-		fmt.Printf("received unary: ts=%d temp=%.2f hum=%.2f payload=%s\n",
-			req.Timestamp, req.Temperature, req.Humidity, string(req.Payload))
-		ack := codec.Ack{Success: true}
-		return ack.MarshalWells(), nil
-	})
-
-	srv.RegisterStream("SensorService.StreamReadings", func(ctx context.Context, s *wellsrpc.Stream) error {
-		for {
-			msg, err := s.Recv(ctx)
-			if err != nil {
-				return err
-			}
-			var r codec.SensorReading
-			if err := r.UnmarshalWells(msg); err != nil {
-				continue
-			}
-			fmt.Printf("stream recv: ts=%d temp=%.2f payload=%s\n", r.Timestamp, r.Temperature, string(r.Payload))
-			ack := codec.Ack{Success: true}
-			_ = s.Send(ack.MarshalWells())
-		}
-	})
+	codec.RegisterSensorServiceServer(srv, sensorServer{})
 
 	log.Println("listening", addr)
 	if err := srv.Serve(addr); err != nil {
@@ -28,7 +28,9 @@ func main() {
 		}
 	}
 
-	client, err := wellsrpc.Dial(addr, tlsCfg)
+	client, err := codec.DialSensorServiceClient(addr,
+		wellsrpc.WithTLSConfig(tlsCfg),
+		wellsrpc.WithDialTimeout(3*time.Second))
 	if err != nil {
 		log.Fatal("dial:", err)
 	}
@@ -40,18 +42,19 @@ func main() {
 		Humidity:    60.5,
 		Payload:     []byte("hello unary"),
 	}
-	var ack codec.Ack
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	if err := client.Call(ctx, "SensorService.SendReading", req, &ack); err != nil {
+	ack, err := client.SendReading(ctx, req)
+	if err != nil {
 		log.Fatal("rpc unary err:", err)
 	}
 	fmt.Println("Got unary ack:", ack.Success)
 
-	stream, err := client.OpenStream(context.Background(), "SensorService.StreamReadings")
+	stream, err := client.StreamReadings(context.Background())
 	if err != nil {
 		log.Fatal("open stream:", err)
 	}
+	defer stream.Close()
 	for i := 0; i < 3; i++ {
 		msg := &codec.SensorReading{
 			Timestamp:   time.Now().Unix(),
@@ -59,21 +62,21 @@ func main() {
 			Humidity:    50,
 			Payload:     []byte(fmt.Sprintf("stream item %d", i)),
 		}
-		if err := stream.Send(msg.MarshalWells()); err != nil {
+		if err := stream.Send(msg); err != nil {
 			log.Println("send:", err)
 			break
 		}
 		ctx2, cancel2 := context.WithTimeout(context.Background(), 2*time.Second)
-		b, err := stream.Recv(ctx2)
+		a, err := stream.Recv(ctx2)
 		cancel2()
 		if err != nil {
 			log.Println("recv ack err:", err)
 			break
 		}
-		var a codec.Ack
-		_ = a.UnmarshalWells(b)
 		fmt.Println("stream ack:", a.Success)
 		time.Sleep(100 * time.Millisecond)
 	}
-	stream.Close()
+	if err := stream.CloseSend(); err != nil {
+		log.Println("close send:", err)
+	}
 }
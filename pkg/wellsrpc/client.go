@@ -22,6 +22,7 @@ type RPCClient struct {
 	nextStream uint32
 
 	unaryInterceptors []UnaryClientInterceptor
+	callTimeout       time.Duration
 
 	streams   map[uint32]*Stream
 	streamsMu sync.Mutex
@@ -31,27 +32,42 @@ type RPCClient struct {
 
 func NewRPCClient(conn net.Conn) *RPCClient {
 	c := &RPCClient{
-		conn:    conn,
-		pending: make(map[uint32]*pendingResponse),
-		streams: make(map[uint32]*Stream),
-		closed:  make(chan struct{}),
+		conn:        conn,
+		pending:     make(map[uint32]*pendingResponse),
+		streams:     make(map[uint32]*Stream),
+		closed:      make(chan struct{}),
+		callTimeout: defaultCallTimeout,
 	}
 	go c.readLoop()
 	return c
 }
 
 func Dial(addr string, tlsCfg *tls.Config) (*RPCClient, error) {
+	return DialWithOptions(addr, WithTLSConfig(tlsCfg))
+}
+
+// DialWithOptions connects to addr and returns a client configured by opts.
+func DialWithOptions(addr string, opts ...DialOption) (*RPCClient, error) {
+	o := dialOptions{callTimeout: defaultCallTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	dialer := &net.Dialer{Timeout: o.dialTimeout}
 	var conn net.Conn
 	var err error
-	if tlsCfg != nil {
-		conn, err = tls.Dial("tcp", addr, tlsCfg)
+	if o.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, o.tlsConfig)
 	} else {
-		conn, err = net.Dial("tcp", addr)
+		conn, err = dialer.Dial("tcp", addr)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return NewRPCClient(conn), nil
+	c := NewRPCClient(conn)
+	c.callTimeout = o.callTimeout
+	c.unaryInterceptors = append(c.unaryInterceptors, o.unaryInterceptors...)
+	return c, nil
 }
 
 func (c *RPCClient) Close() error {
@@ -60,6 +76,12 @@ func (c *RPCClient) Close() error {
 		return nil
 	default:
 		close(c.closed)
+		c.streamsMu.Lock()
+		for id, st := range c.streams {
+			st.Close()
+			delete(c.streams, id)
+		}
+		c.streamsMu.Unlock()
 		return c.conn.Close()
 	}
 }
@@ -110,11 +132,21 @@ func (c *RPCClient) readLoop() {
 		if st != nil {
 			switch frame.Type {
 			case FrameTypeStreamData:
-				select {
-				case st.recvCh <- frame.Payload:
-				default:
+				if !st.deliver(frame.Payload) {
+					// Fail only this stream and tell the peer to stop
+					// sending; the reader must keep serving the others.
+					st.abort(ErrStreamOverflow)
+					c.streamsMu.Lock()
+					delete(c.streams, frame.StreamID)
+					c.streamsMu.Unlock()
+					c.mu.Lock()
+					_ = WriteFrame(c.conn, &Frame{Type: FrameTypeError, StreamID: frame.StreamID, Payload: []byte(ErrStreamOverflow.Error())})
+					c.mu.Unlock()
+				}
+			case FrameTypeStreamClose, FrameTypeError:
+				if frame.Type == FrameTypeError {
+					st.closeRecv(errors.New(string(frame.Payload)))
 				}
-			case FrameTypeStreamClose:
 				st.Close()
 				c.streamsMu.Lock()
 				delete(c.streams, frame.StreamID)
@@ -181,9 +213,9 @@ func (c *RPCClient) Call(ctx context.Context, method string, req WelliMarshaller
 	}
 
 	ctx2 := ctx
-	if _, ok := ctx.Deadline(); !ok {
+	if _, ok := ctx.Deadline(); !ok && c.callTimeout > 0 {
 		var cancel context.CancelFunc
-		ctx2, cancel = context.WithTimeout(ctx, 10*time.Second)
+		ctx2, cancel = context.WithTimeout(ctx, c.callTimeout)
 		defer cancel()
 	}
 
@@ -202,6 +234,12 @@ func (c *RPCClient) OpenStream(ctx context.Context, method string) (*Stream, err
 		defer c.mu.Unlock()
 		return WriteFrame(c.conn, f)
 	})
+	stream.closeSend = func() error {
+		f := &Frame{Type: FrameTypeStreamClose, StreamID: streamID}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return WriteFrame(c.conn, f)
+	}
 
 	c.streamsMu.Lock()
 	c.streams[streamID] = stream
@@ -0,0 +1,5 @@
+// Package codecgenerated holds the codec and service stubs generated from
+// examples/sensor/sensor.wb.idl.
+package codecgenerated
+
+//go:generate go run ../../../cmd/welli-codegen -idl ../../../examples/sensor/sensor.wb.idl -out .
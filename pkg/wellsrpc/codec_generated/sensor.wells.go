@@ -2,8 +2,9 @@ package codecgenerated
 
 import (
 	"errors"
-
-	wellsrpc "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"fmt"
+	wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"math"
 )
 
 type SensorReading struct {
@@ -11,136 +12,245 @@ type SensorReading struct {
 	Temperature float32
 	Humidity    float32
 	Payload     []byte
+
+	unknownFields []byte
 }
 
-type Ack struct {
-	Success bool
+// SizeWells returns the size of the encoding of m.
+func (m *SensorReading) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *SensorReading) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if m.Timestamp != 0 {
+		n += 1 + wellib.SizeVarint(wellib.ZigzagEncode(m.Timestamp))
+	}
+	if math.Float32bits(m.Temperature) != 0 {
+		n += 1 + 4
+	}
+	if math.Float32bits(m.Humidity) != 0 {
+		n += 1 + 4
+	}
+	if len(m.Payload) > 0 {
+		n += 1 + wellib.SizeBytes(len(m.Payload))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *SensorReading) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)
 }
 
-func (s *SensorReading) MarshalWells() []byte {
-	buf := wellsrpc.GetBuffer()
-	defer wellsrpc.PutBuffer(buf)
-	b := *buf
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *SensorReading) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	return m.MarshalWellsCached(b, nil)
+}
 
-	b = append(b, 0x08)
-	b = append(b, wellsrpc.EncodeVarint(wellsrpc.ZigzagEncode(s.Timestamp))...)
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *SensorReading) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
 
-	b = append(b, 0x15)
-	wellsrpc.WriteFloat32LE(&b, s.Temperature)
+	if m.Timestamp != 0 {
+		b = append(b, 0x08)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(m.Timestamp))
+	}
 
-	b = append(b, 0x1D)
-	wellsrpc.WriteFloat32LE(&b, s.Humidity)
+	if math.Float32bits(m.Temperature) != 0 {
+		b = append(b, 0x15)
+		b = wellib.AppendFloat32LE(b, m.Temperature)
+	}
 
-	if len(s.Payload) > 0 {
+	if math.Float32bits(m.Humidity) != 0 {
+		b = append(b, 0x1D)
+		b = wellib.AppendFloat32LE(b, m.Humidity)
+	}
+
+	if len(m.Payload) > 0 {
 		b = append(b, 0x22)
-		b = append(b, wellsrpc.EncodeVarint(uint64(len(s.Payload)))...)
-		b = append(b, s.Payload...)
+		b = wellib.AppendBytes(b, m.Payload)
 	}
 
-	out := make([]byte, len(b))
-	copy(out, b)
-	return out
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *SensorReading) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
 }
 
-func (s *SensorReading) UnmarshalWells(b []byte) error {
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *SensorReading) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
 	var i int
 	for i < len(b) {
-		tag := b[i]
-		i++
-		fieldNum := int(tag >> 3)
-		wireType := int(tag & 0x7)
-		switch fieldNum {
-		case 1:
-			val, n := wellsrpc.DecodeVarint(b[i:])
-			if n == 0 {
-				return errors.New("invalid varint timestamp")
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("SensorReading: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("SensorReading.timestamp: %w", err)
 			}
-			s.Timestamp = wellsrpc.ZigzagDecode(val)
 			i += n
-		case 2:
+			v := wellib.ZigzagDecode(x)
+			m.Timestamp = v
+		case num == 2 && wireType == wellib.WireFixed32:
 			if i+4 > len(b) {
-				return errors.New("temperature truncated")
+				return errors.New("SensorReading.temperature: truncated")
 			}
-			s.Temperature = wellsrpc.ReadFloat32LE(b[i : i+4])
+			v := wellib.ReadFloat32LE(b[i : i+4])
 			i += 4
-		case 3:
+			m.Temperature = v
+		case num == 3 && wireType == wellib.WireFixed32:
 			if i+4 > len(b) {
-				return errors.New("humidity truncated")
+				return errors.New("SensorReading.humidity: truncated")
 			}
-			s.Humidity = wellsrpc.ReadFloat32LE(b[i : i+4])
+			v := wellib.ReadFloat32LE(b[i : i+4])
 			i += 4
-		case 4:
-			length, n := wellsrpc.DecodeVarint(b[i:])
-			if n == 0 {
-				return errors.New("invalid payload length")
+			m.Humidity = v
+		case num == 4 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("SensorReading.payload: length: %w", err)
 			}
 			i += n
-			if i+int(length) > len(b) {
-				return errors.New("payload too short")
+			if uint64(len(b)-i) < l {
+				return errors.New("SensorReading.payload: truncated")
 			}
-			s.Payload = append([]byte(nil), b[i:i+int(length)]...)
-			i += int(length)
+			v := append([]byte(nil), b[i:i+int(l)]...)
+			i += int(l)
+			m.Payload = v
 		default:
-			switch wireType {
-			case 0:
-				_, n := wellsrpc.DecodeVarint(b[i:])
-				if n == 0 {
-					return errors.New("invalid varint in skip")
-				}
-				i += n
-			case 2:
-				l, n := wellsrpc.DecodeVarint(b[i:])
-				if n == 0 {
-					return errors.New("invalid length in skip")
-				}
-				i += n + int(l)
-			case 5:
-				i += 4
-			case 1:
-				i += 8
-			default:
-				return errors.New("unknown wire type")
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
 			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
 		}
 	}
 	return nil
 }
 
-func (a *Ack) MarshalWells() []byte {
-	buf := wellsrpc.GetBuffer()
-	defer wellsrpc.PutBuffer(buf)
-	b := *buf
-	b = append(b, 0x08)
-	if a.Success {
+type Ack struct {
+	Success bool
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Ack) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Ack) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if m.Success {
+		n += 1 + 1
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Ack) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Ack) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	return m.MarshalWellsCached(b, nil)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Ack) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+
+	if m.Success {
+		b = append(b, 0x08)
 		b = append(b, 1)
-	} else {
-		b = append(b, 0)
 	}
-	out := make([]byte, len(b))
-	copy(out, b)
-	return out
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Ack) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
 }
 
-func (a *Ack) UnmarshalWells(b []byte) error {
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Ack) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
 	var i int
 	for i < len(b) {
-		tag := b[i]
-		i++
-		fieldNum := int(tag >> 3)
-		switch fieldNum {
-		case 1:
-			val, n := wellsrpc.DecodeVarint(b[i:])
-			if n == 0 {
-				return errors.New("invalid varint for ack")
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Ack: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Ack.success: %w", err)
 			}
-			a.Success = val != 0
 			i += n
+			v := x != 0
+			m.Success = v
 		default:
-			_, n := wellsrpc.DecodeVarint(b[i:])
-			if n == 0 {
-				return errors.New("invalid skip")
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
 			}
 			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
 		}
 	}
 	return nil
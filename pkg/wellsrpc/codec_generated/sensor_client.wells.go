@@ -0,0 +1,92 @@
+package codecgenerated
+
+import (
+	"context"
+	"errors"
+	wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+// SensorServiceClient is the client API for SensorService.
+type SensorServiceClient interface {
+	SendReading(ctx context.Context, req *SensorReading) (*Ack, error)
+	StreamReadings(ctx context.Context) (*SensorService_StreamReadingsClient, error)
+	// Close closes the connection opened by DialSensorServiceClient. It does nothing for a
+	// client from NewSensorServiceClient, whose connection belongs to the caller.
+	Close() error
+}
+
+type sensorServiceClient struct {
+	c     *wellib.RPCClient
+	owned bool // c was dialed by DialSensorServiceClient
+}
+
+// NewSensorServiceClient returns a SensorServiceClient that issues calls over cc.
+func NewSensorServiceClient(cc *wellib.RPCClient) (SensorServiceClient, error) {
+	if cc == nil {
+		return nil, errors.New("NewSensorServiceClient: nil client")
+	}
+	return &sensorServiceClient{c: cc}, nil
+}
+
+// DialSensorServiceClient connects to addr and returns a SensorServiceClient using that connection.
+func DialSensorServiceClient(addr string, opts ...wellib.DialOption) (SensorServiceClient, error) {
+	cc, err := wellib.DialWithOptions(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &sensorServiceClient{c: cc, owned: true}, nil
+}
+
+func (c *sensorServiceClient) Close() error {
+	if !c.owned {
+		return nil
+	}
+	return c.c.Close()
+}
+
+func (c *sensorServiceClient) SendReading(ctx context.Context, req *SensorReading) (*Ack, error) {
+	var out Ack
+	if err := c.c.Call(ctx, "SensorService.SendReading", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *sensorServiceClient) StreamReadings(ctx context.Context) (*SensorService_StreamReadingsClient, error) {
+	s, err := c.c.OpenStream(ctx, "SensorService.StreamReadings")
+	if err != nil {
+		return nil, err
+	}
+	return &SensorService_StreamReadingsClient{stream: s}, nil
+}
+
+// SensorService_StreamReadingsClient is the client side of SensorService.StreamReadings.
+type SensorService_StreamReadingsClient struct {
+	stream *wellib.Stream
+}
+
+func (s *SensorService_StreamReadingsClient) Send(m *SensorReading) error {
+	return s.stream.Send(m.MarshalWells())
+}
+
+func (s *SensorService_StreamReadingsClient) Recv(ctx context.Context) (*Ack, error) {
+	b, err := s.stream.Recv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var m Ack
+	if err := m.UnmarshalWells(b); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// CloseSend tells the server that no more requests follow.
+func (s *SensorService_StreamReadingsClient) CloseSend() error {
+	return s.stream.CloseSend()
+}
+
+// Close abandons the stream.
+func (s *SensorService_StreamReadingsClient) Close() {
+	s.stream.Close()
+}
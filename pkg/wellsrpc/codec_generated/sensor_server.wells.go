@@ -0,0 +1,49 @@
+package codecgenerated
+
+import (
+	"context"
+	wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+type SensorServiceServer interface {
+	SendReading(ctx context.Context, req *SensorReading) (*Ack, error)
+	StreamReadings(ctx context.Context, stream *SensorService_StreamReadingsServer) error
+}
+
+func RegisterSensorServiceServer(srv *wellib.RPCServer, impl SensorServiceServer) {
+	srv.Register("SensorService.SendReading", func(ctx context.Context, payload []byte) ([]byte, error) {
+		var req SensorReading
+		if err := req.UnmarshalWells(payload); err != nil {
+			return nil, err
+		}
+		resp, err := impl.SendReading(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		return resp.MarshalWells(), nil
+	})
+	srv.RegisterStream("SensorService.StreamReadings", func(ctx context.Context, s *wellib.Stream) error {
+		return impl.StreamReadings(ctx, &SensorService_StreamReadingsServer{stream: s})
+	})
+}
+
+// SensorService_StreamReadingsServer is the server side of SensorService.StreamReadings.
+type SensorService_StreamReadingsServer struct {
+	stream *wellib.Stream
+}
+
+func (s *SensorService_StreamReadingsServer) Send(m *Ack) error {
+	return s.stream.Send(m.MarshalWells())
+}
+
+func (s *SensorService_StreamReadingsServer) Recv(ctx context.Context) (*SensorReading, error) {
+	b, err := s.stream.Recv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var m SensorReading
+	if err := m.UnmarshalWells(b); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
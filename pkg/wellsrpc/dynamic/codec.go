@@ -0,0 +1,451 @@
+package dynamic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+var errTruncated = errors.New("truncated")
+
+// valueType is the type of a single value: a field's element, or a map key.
+type valueType struct {
+	scalar string // IDL scalar name; "" for enums and messages
+	enum   *idl.Enum
+	msg    *idl.Message
+}
+
+func valueOf(f *idl.Field) valueType {
+	if f.Message != nil || f.Enum != nil {
+		return valueType{enum: f.Enum, msg: f.Message}
+	}
+	return valueType{scalar: f.Type}
+}
+
+func keyOf(f *idl.Field) valueType {
+	return valueType{scalar: f.KeyType}
+}
+
+func (t valueType) wireType() int {
+	switch t.scalar {
+	case "":
+		if t.msg != nil {
+			return wellsrpc.WireBytes
+		}
+		return wellsrpc.WireVarint
+	case "float", "float32":
+		return wellsrpc.WireFixed32
+	case "double", "float64":
+		return wellsrpc.WireFixed64
+	case "string", "bytes":
+		return wellsrpc.WireBytes
+	default:
+		return wellsrpc.WireVarint
+	}
+}
+
+func (t valueType) packable() bool {
+	return t.wireType() != wellsrpc.WireBytes
+}
+
+func (t valueType) zero() any {
+	switch t.scalar {
+	case "":
+		if t.msg != nil {
+			return nil
+		}
+		return int32(0)
+	case "int32":
+		return int32(0)
+	case "int64":
+		return int64(0)
+	case "uint32":
+		return uint32(0)
+	case "uint64":
+		return uint64(0)
+	case "float", "float32":
+		return float32(0)
+	case "double", "float64":
+		return float64(0)
+	case "bool":
+		return false
+	case "string":
+		return ""
+	default:
+		return []byte(nil)
+	}
+}
+
+func (t valueType) check(v any) error {
+	if t.msg != nil {
+		sub, ok := v.(*Message)
+		if !ok || sub == nil {
+			return fmt.Errorf("needs a *Message of type %s, not %T", t.msg.FullName(), v)
+		}
+		if sub.desc != t.msg {
+			return fmt.Errorf("needs a message of type %s, not %s", t.msg.FullName(), sub.desc.FullName())
+		}
+		return nil
+	}
+	if want := t.zero(); reflect.TypeOf(want) != reflect.TypeOf(v) {
+		return fmt.Errorf("needs %T, not %T", want, v)
+	}
+	return nil
+}
+
+// isDefault reports whether v is the default of t, which is not written for
+// a singular field. Floats are compared by their bits so that -0 is written.
+func (t valueType) isDefault(v any) bool {
+	switch x := v.(type) {
+	case int32:
+		return x == 0
+	case int64:
+		return x == 0
+	case uint32:
+		return x == 0
+	case uint64:
+		return x == 0
+	case float32:
+		return math.Float32bits(x) == 0
+	case float64:
+		return math.Float64bits(x) == 0
+	case bool:
+		return !x
+	case string:
+		return x == ""
+	case []byte:
+		return len(x) == 0
+	}
+	return false
+}
+
+func (t valueType) appendValue(b []byte, v any) []byte {
+	switch x := v.(type) {
+	case *Message:
+		return wellsrpc.AppendBytes(b, x.MarshalWellsAppend(nil))
+	case int32:
+		if t.scalar == "int32" {
+			return wellsrpc.AppendVarint(b, wellsrpc.ZigzagEncode(int64(x)))
+		}
+		return wellsrpc.AppendVarint(b, uint64(x)) // enums are sign-extended
+	case int64:
+		return wellsrpc.AppendVarint(b, wellsrpc.ZigzagEncode(x))
+	case uint32:
+		return wellsrpc.AppendVarint(b, uint64(x))
+	case uint64:
+		return wellsrpc.AppendVarint(b, x)
+	case float32:
+		return wellsrpc.AppendFloat32LE(b, x)
+	case float64:
+		return wellsrpc.AppendFloat64LE(b, x)
+	case bool:
+		if x {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case string:
+		return wellsrpc.AppendString(b, x)
+	case []byte:
+		return wellsrpc.AppendBytes(b, x)
+	}
+	panic(fmt.Sprintf("dynamic: unexpected value %T", v))
+}
+
+// decode decodes one value of type t at the start of b, in a message that
+// may hold depth more levels of nested messages.
+func (t valueType) decode(b []byte, depth int) (any, int, error) {
+	switch t.wireType() {
+	case wellsrpc.WireFixed32:
+		if len(b) < 4 {
+			return nil, 0, errTruncated
+		}
+		return wellsrpc.ReadFloat32LE(b), 4, nil
+	case wellsrpc.WireFixed64:
+		if len(b) < 8 {
+			return nil, 0, errTruncated
+		}
+		return wellsrpc.ReadFloat64LE(b), 8, nil
+	case wellsrpc.WireBytes:
+		l, n, err := wellsrpc.ReadVarint(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		if uint64(len(b)-n) < l {
+			return nil, 0, errTruncated
+		}
+		data := b[n : n+int(l)]
+		switch {
+		case t.msg != nil:
+			sub := New(t.msg)
+			if err := sub.unmarshal(data, depth-1); err != nil {
+				return nil, 0, err
+			}
+			return sub, n + int(l), nil
+		case t.scalar == "string":
+			return string(data), n + int(l), nil
+		default:
+			return append([]byte(nil), data...), n + int(l), nil
+		}
+	}
+
+	x, n, err := wellsrpc.ReadVarint(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch t.scalar {
+	case "int32":
+		return int32(wellsrpc.ZigzagDecode(x)), n, nil
+	case "int64":
+		return wellsrpc.ZigzagDecode(x), n, nil
+	case "uint32":
+		return uint32(x), n, nil
+	case "uint64":
+		return x, n, nil
+	case "bool":
+		return x != 0, n, nil
+	default:
+		return int32(x), n, nil
+	}
+}
+
+// MarshalWells returns the encoding of m, in the same field order as the
+// generated code for its type. Map entries are written in key order.
+func (m *Message) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsAppend(nil)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Message) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	for _, f := range m.desc.Fields {
+		v, ok := m.values[f.Number]
+		if !ok {
+			continue
+		}
+		vt := valueOf(f)
+		switch {
+		case f.IsMap():
+			b = appendMap(b, f, v.(map[any]any))
+		case f.Repeated && vt.packable():
+			elems := v.([]any)
+			if len(elems) == 0 {
+				continue
+			}
+			var packed []byte
+			for _, e := range elems {
+				packed = vt.appendValue(packed, e)
+			}
+			b = wellsrpc.AppendTag(b, f.Number, wellsrpc.WireBytes)
+			b = wellsrpc.AppendBytes(b, packed)
+		case f.Repeated:
+			for _, e := range v.([]any) {
+				b = wellsrpc.AppendTag(b, f.Number, vt.wireType())
+				b = vt.appendValue(b, e)
+			}
+		case f.Oneof != nil || !vt.isDefault(v):
+			b = wellsrpc.AppendTag(b, f.Number, vt.wireType())
+			b = vt.appendValue(b, v)
+		}
+	}
+	return append(b, m.unknown...)
+}
+
+func appendMap(b []byte, f *idl.Field, entries map[any]any) []byte {
+	kt, vt := keyOf(f), valueOf(f)
+	for _, k := range SortedKeys(entries) {
+		var entry []byte
+		entry = wellsrpc.AppendTag(entry, 1, kt.wireType())
+		entry = kt.appendValue(entry, k)
+		entry = wellsrpc.AppendTag(entry, 2, vt.wireType())
+		entry = vt.appendValue(entry, entries[k])
+		b = wellsrpc.AppendTag(b, f.Number, wellsrpc.WireBytes)
+		b = wellsrpc.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// SortedKeys returns the keys of a map field's value in ascending order:
+// numbers by value, strings bytewise and false before true.
+func SortedKeys(entries map[any]any) []any {
+	keys := make([]any, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+	return keys
+}
+
+func lessKey(a, b any) bool {
+	switch x := a.(type) {
+	case int32:
+		return x < b.(int32)
+	case int64:
+		return x < b.(int64)
+	case uint32:
+		return x < b.(uint32)
+	case uint64:
+		return x < b.(uint64)
+	case bool:
+		return !x && b.(bool)
+	case string:
+		return x < b.(string)
+	}
+	return false
+}
+
+// UnmarshalWells decodes b into m. Like the generated decoders it merges:
+// repeated fields and maps are extended and set message fields are merged
+// into, and fields with an unexpected wire type are kept as unknown.
+func (m *Message) UnmarshalWells(b []byte) error {
+	return m.unmarshal(b, wellsrpc.MaxDepth)
+}
+
+// unmarshal is UnmarshalWells for a message that may hold depth more levels
+// of nested messages.
+func (m *Message) unmarshal(b []byte, depth int) error {
+	if depth < 0 {
+		return wellsrpc.ErrMaxDepth
+	}
+	name := m.desc.FullName()
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellsrpc.ReadTag(b[i:])
+		if n == 0 {
+			return fmt.Errorf("%s: invalid field key", name)
+		}
+		i += n
+
+		f := m.fieldByNumber(num)
+		if f != nil {
+			vt := valueOf(f)
+			var err error
+			n, err = m.decodeField(f, vt, wireType, b[i:], depth)
+			if errors.Is(err, wellsrpc.ErrMaxDepth) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", name, f.Name, err)
+			}
+		}
+		if f == nil || n < 0 {
+			n, err := wellsrpc.SkipField(b[i:], wireType)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			i += n
+			m.unknown = append(m.unknown, b[start:i]...)
+			continue
+		}
+		i += n
+	}
+	return nil
+}
+
+// decodeField decodes the value of f at the start of b. It returns -1 when
+// the wire type does not match f, so the field is kept as unknown.
+func (m *Message) decodeField(f *idl.Field, vt valueType, wireType int, b []byte, depth int) (int, error) {
+	switch {
+	case f.IsMap() && wireType == wellsrpc.WireBytes:
+		return m.decodeEntry(f, b, depth)
+	case f.Repeated && vt.packable() && wireType == wellsrpc.WireBytes:
+		l, n, err := wellsrpc.ReadVarint(b)
+		if err != nil {
+			return 0, err
+		}
+		if uint64(len(b)-n) < l {
+			return 0, errTruncated
+		}
+		elems, _ := m.values[f.Number].([]any)
+		packed := b[n : n+int(l)]
+		for len(packed) > 0 {
+			v, vn, err := vt.decode(packed, depth)
+			if err != nil {
+				return 0, err
+			}
+			elems = append(elems, v)
+			packed = packed[vn:]
+		}
+		m.values[f.Number] = elems
+		return n + int(l), nil
+	case f.IsMap() || wireType != vt.wireType():
+		return -1, nil
+	}
+
+	if vt.msg != nil && !f.Repeated && f.Oneof == nil {
+		if sub, ok := m.values[f.Number].(*Message); ok {
+			l, n, err := wellsrpc.ReadVarint(b)
+			if err != nil {
+				return 0, err
+			}
+			if uint64(len(b)-n) < l {
+				return 0, errTruncated
+			}
+			return n + int(l), sub.unmarshal(b[n:n+int(l)], depth-1)
+		}
+	}
+	v, n, err := vt.decode(b, depth)
+	if err != nil {
+		return 0, err
+	}
+	if f.Repeated {
+		elems, _ := m.values[f.Number].([]any)
+		m.values[f.Number] = append(elems, v)
+	} else {
+		m.set(f, v)
+	}
+	return n, nil
+}
+
+func (m *Message) decodeEntry(f *idl.Field, b []byte, depth int) (int, error) {
+	l, n, err := wellsrpc.ReadVarint(b)
+	if err != nil {
+		return 0, err
+	}
+	if uint64(len(b)-n) < l {
+		return 0, errTruncated
+	}
+	kt, vt := keyOf(f), valueOf(f)
+	key, value := kt.zero(), vt.zero()
+	entry := b[n : n+int(l)]
+	for len(entry) > 0 {
+		num, wireType, en := wellsrpc.ReadTag(entry)
+		if en == 0 {
+			return 0, errors.New("invalid entry key")
+		}
+		entry = entry[en:]
+		switch {
+		case num == 1 && wireType == kt.wireType():
+			key, en, err = kt.decode(entry, depth)
+		case num == 2 && wireType == vt.wireType():
+			value, en, err = vt.decode(entry, depth)
+		default:
+			en, err = wellsrpc.SkipField(entry, wireType)
+		}
+		if err != nil {
+			return 0, err
+		}
+		entry = entry[en:]
+	}
+	if vt.msg != nil && value == nil {
+		value = New(vt.msg)
+	}
+	entries, _ := m.values[f.Number].(map[any]any)
+	if entries == nil {
+		entries = map[any]any{}
+		m.values[f.Number] = entries
+	}
+	entries[key] = value
+	return n + int(l), nil
+}
@@ -0,0 +1,182 @@
+package dynamic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/internal/wiretest"
+)
+
+func loadSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Load(nil, wiretest.IDL(), "../../../examples/sensor/sensor.wb.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newMessage(t *testing.T, s *Schema, name string) *Message {
+	t.Helper()
+	m, err := s.NewMessage(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// Dynamic messages read and write the bytes of the generated messages.
+func TestMatchesGenerated(t *testing.T) {
+	s := loadSchema(t)
+	for _, f := range wiretest.Fixtures {
+		b, _ := hex.DecodeString(f.Hex)
+		m := newMessage(t, s, wiretest.Name(f.Msg))
+		if err := m.UnmarshalWells(b); err != nil {
+			t.Errorf("%s: UnmarshalWells(%s): %v", f.Name, f.Hex, err)
+			continue
+		}
+		if got := hex.EncodeToString(m.MarshalWells()); got != f.Hex {
+			t.Errorf("%s: MarshalWells() = %s, want %s", f.Name, got, f.Hex)
+		}
+		got := wiretest.New(f.Msg)
+		if err := got.UnmarshalWells(m.MarshalWells()); err != nil || !reflect.DeepEqual(got, f.Msg) {
+			t.Errorf("%s: generated message decoded %+v, %v, want %+v", f.Name, got, err, f.Msg)
+		}
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	s := loadSchema(t)
+	c := newMessage(t, s, "Composite")
+	inner := newMessage(t, s, "Inner")
+	if err := inner.Set("name", "n"); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]any{
+		"ids":    []any{int32(1), int32(-1)},
+		"counts": map[any]any{"b": int64(2), "a": int64(1)},
+		"inner":  inner,
+		"number": int32(7),
+	} {
+		if err := c.Set(name, v); err != nil {
+			t.Fatalf("Set(%q): %v", name, err)
+		}
+	}
+	if err := c.Set("word", "w"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Has("number") || c.Get("word") != "w" {
+		t.Errorf("setting word kept number: %v", c)
+	}
+
+	var got wiretest.Composite
+	if err := got.UnmarshalWells(c.MarshalWells()); err != nil {
+		t.Fatal(err)
+	}
+	want := wiretest.Composite{
+		Ids:    []int32{1, -1},
+		Counts: map[string]int64{"a": 1, "b": 2},
+		Inner:  &wiretest.Inner{Name: "n"},
+		Choice: &wiretest.Composite_Word{Word: "w"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+	// Map entries are written in key order.
+	if enc := c.MarshalWells(); !bytes.Contains(enc, []byte{0x22, 0x05, 0x0a, 0x01, 'a', 0x10, 0x02, 0x22}) {
+		t.Errorf("MarshalWells() = %x, want the entry for a first", enc)
+	}
+
+	if got := newMessage(t, s, "Scalars").Get("u64"); got != uint64(0) {
+		t.Errorf("Get of an unset uint64 = %#v, want uint64(0)", got)
+	}
+	for name, v := range map[string]any{
+		"ids":     []int32{1},
+		"inner":   newMessage(t, s, "Composite"),
+		"number":  7,
+		"missing": int32(1),
+	} {
+		if err := c.Set(name, v); err == nil {
+			t.Errorf("Set(%q, %T) succeeded, want an error", name, v)
+		}
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	s := loadSchema(t)
+	// Field 100 is unknown, and field 1 arrives with the wrong wire type.
+	in, _ := hex.DecodeString("0a016e" + "a0069601" + "0d01020304")
+	m := newMessage(t, s, "Inner")
+	if err := m.UnmarshalWells(in); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(m.Unknown()); got != "a00696010d01020304" {
+		t.Errorf("Unknown() = %s", got)
+	}
+	if got := m.MarshalWells(); !bytes.Equal(got, in) {
+		t.Errorf("MarshalWells() = %x, want %x", got, in)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range []struct{ in, err string }{
+		{"08", "Composite.ids: truncated varint"},
+		{"0a05", "Composite.ids: truncated"},
+		{"2a020a05", "Composite.inner: Inner.name: truncated"},
+		{"0a04ffffffff", "Composite.ids: truncated varint"},
+		{"00", "Composite: invalid field key"},
+	} {
+		b, _ := hex.DecodeString(tt.in)
+		err := newMessage(t, s, "Composite").UnmarshalWells(b)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("UnmarshalWells(%s) error = %v, want %q", tt.in, err, tt.err)
+		}
+	}
+	b, _ := hex.DecodeString("08ffffffffffffffffffff01")
+	err := newMessage(t, s, "Scalars").UnmarshalWells(b)
+	if !errors.Is(err, wellsrpc.ErrOverflow) {
+		t.Errorf("UnmarshalWells of an 11-byte varint: %v, want ErrOverflow", err)
+	}
+}
+
+// Messages nested past wellsrpc.MaxDepth are rejected like in the generated
+// decoders, also through repeated fields and map values.
+func TestMaxDepth(t *testing.T) {
+	s := loadSchema(t)
+	tests := []struct {
+		depth int
+		keys  []byte
+		err   error
+	}{
+		{wellsrpc.MaxDepth, []byte{0x12}, nil},
+		{wellsrpc.MaxDepth + 1, []byte{0x12}, wellsrpc.ErrMaxDepth},
+		{3000000, []byte{0x12}, wellsrpc.ErrMaxDepth},
+		{3000000, []byte{0x1a, 0x22, 0x12}, wellsrpc.ErrMaxDepth}, // children, then named entries
+	}
+	for _, tt := range tests {
+		b := wiretest.Nested(tt.depth, tt.keys...)
+		if err := newMessage(t, s, "Node").UnmarshalWells(b); !errors.Is(err, tt.err) {
+			t.Errorf("depth %d through %x: UnmarshalWells() = %v, want %v", tt.depth, tt.keys, err, tt.err)
+		}
+	}
+}
+
+func TestMethod(t *testing.T) {
+	s := loadSchema(t)
+	rpc := s.Method("SensorService.SendReading")
+	if rpc == nil || rpc.RequestType.Name != "SensorReading" || rpc.ResponseType.Name != "Ack" {
+		t.Fatalf("Method(SensorService.SendReading) = %+v", rpc)
+	}
+	if s.Method("SensorService.Missing") != nil {
+		t.Error("Method of an unknown rpc is not nil")
+	}
+	if _, err := s.NewMessage("Missing"); err == nil {
+		t.Error("NewMessage of an unknown type succeeded")
+	}
+}
@@ -0,0 +1,183 @@
+package dynamic
+
+import (
+	"fmt"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// Message is a message of a schema type whose fields are held as Go values:
+//
+//	int32, int64, uint32, uint64   int32, int64, uint32, uint64
+//	float32 (float), float64       float32, float64
+//	bool, string, bytes            bool, string, []byte
+//	enums                          int32, the value number
+//	messages                       *Message of the field's type
+//	repeated fields                []any of the element values
+//	maps                           map[any]any of key and element values
+//
+// Like generated messages, a Message keeps the fields its type does not
+// declare and writes them back after the known ones.
+type Message struct {
+	desc    *idl.Message
+	values  map[int]any
+	unknown []byte
+}
+
+// New returns an empty message of type desc, which must come from checked
+// IDL files.
+func New(desc *idl.Message) *Message {
+	return &Message{desc: desc, values: map[int]any{}}
+}
+
+// Descriptor returns the type of m.
+func (m *Message) Descriptor() *idl.Message {
+	return m.desc
+}
+
+// Field returns the field of m's type with the given name, or nil.
+func (m *Message) Field(name string) *idl.Field {
+	for _, f := range m.desc.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (m *Message) fieldByNumber(num int) *idl.Field {
+	for _, f := range m.desc.Fields {
+		if f.Number == num {
+			return f
+		}
+	}
+	return nil
+}
+
+// Has reports whether the field name is set.
+func (m *Message) Has(name string) bool {
+	f := m.Field(name)
+	if f == nil {
+		return false
+	}
+	_, ok := m.values[f.Number]
+	return ok
+}
+
+// Get returns the value of the field name. An unset scalar or enum field
+// returns its default; an unset message, repeated or map field, an unset
+// oneof case and an unknown name return nil.
+func (m *Message) Get(name string) any {
+	f := m.Field(name)
+	if f == nil {
+		return nil
+	}
+	if v, ok := m.values[f.Number]; ok {
+		return v
+	}
+	if f.Repeated || f.IsMap() || f.Oneof != nil {
+		return nil
+	}
+	return valueOf(f).zero()
+}
+
+// Set sets the field name to v, which must have the Go type listed on
+// Message. Setting a oneof case clears the other cases.
+func (m *Message) Set(name string, v any) error {
+	f := m.Field(name)
+	if f == nil {
+		return fmt.Errorf("dynamic: message %s has no field %q", m.desc.FullName(), name)
+	}
+	if err := checkField(f, v); err != nil {
+		return fmt.Errorf("dynamic: %s.%s: %w", m.desc.FullName(), f.Name, err)
+	}
+	m.set(f, v)
+	return nil
+}
+
+func (m *Message) set(f *idl.Field, v any) {
+	if f.Oneof != nil {
+		for _, other := range f.Oneof.Fields {
+			delete(m.values, other.Number)
+		}
+	}
+	m.values[f.Number] = v
+}
+
+// Clear unsets the field name.
+func (m *Message) Clear(name string) {
+	if f := m.Field(name); f != nil {
+		delete(m.values, f.Number)
+	}
+}
+
+// Range calls fn for each set field in declaration order, until fn returns
+// false.
+func (m *Message) Range(fn func(f *idl.Field, v any) bool) {
+	for _, f := range m.desc.Fields {
+		if v, ok := m.values[f.Number]; ok {
+			if !fn(f, v) {
+				return
+			}
+		}
+	}
+}
+
+// Unknown returns the encoded fields that m's type does not declare.
+func (m *Message) Unknown() []byte {
+	return m.unknown
+}
+
+// SetUnknown replaces the unknown fields of m; nil drops them.
+func (m *Message) SetUnknown(b []byte) {
+	m.unknown = b
+}
+
+// String returns the field values of m for debugging.
+func (m *Message) String() string {
+	s := m.desc.FullName() + "{"
+	first := true
+	m.Range(func(f *idl.Field, v any) bool {
+		if !first {
+			s += " "
+		}
+		first = false
+		s += fmt.Sprintf("%s:%v", f.Name, v)
+		return true
+	})
+	return s + "}"
+}
+
+func checkField(f *idl.Field, v any) error {
+	switch {
+	case f.IsMap():
+		entries, ok := v.(map[any]any)
+		if !ok {
+			return fmt.Errorf("map field needs map[any]any, not %T", v)
+		}
+		kt, vt := keyOf(f), valueOf(f)
+		for k, e := range entries {
+			if err := kt.check(k); err != nil {
+				return fmt.Errorf("key: %w", err)
+			}
+			if err := vt.check(e); err != nil {
+				return fmt.Errorf("value for key %v: %w", k, err)
+			}
+		}
+		return nil
+	case f.Repeated:
+		elems, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("repeated field needs []any, not %T", v)
+		}
+		vt := valueOf(f)
+		for i, e := range elems {
+			if err := vt.check(e); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	default:
+		return valueOf(f).check(v)
+	}
+}
@@ -0,0 +1,109 @@
+// Package dynamic encodes and decodes Wells messages with a schema loaded at
+// runtime instead of generated Go types. It is meant for gateways, proxies
+// and debugging tools that handle services they were not compiled against.
+//
+// A Message is wire-compatible with the generated code for its type: each
+// decodes what the other encodes. The bytes are not always identical, as a
+// Message writes map entries in key order and generated code in map
+// iteration order. A Message can stand in for a generated message anywhere a
+// wellsrpc.WelliMarshaller is expected, such as RPCClient.Call.
+package dynamic
+
+import (
+	"fmt"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// Schema indexes the messages, enums and rpcs of a set of checked IDL files
+// and of the files they import.
+type Schema struct {
+	files    []*idl.File
+	messages map[string]*idl.Message
+	enums    map[string]*idl.Enum
+	methods  map[string]*idl.RPC
+}
+
+// Load loads and checks the named IDL files like the code generator does
+// and returns their schema.
+func Load(importPaths []string, filenames ...string) (*Schema, error) {
+	files, err := idl.Load(importPaths, filenames...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSchema(files...), nil
+}
+
+// NewSchema returns the schema of files, which must have passed idl.Check.
+func NewSchema(files ...*idl.File) *Schema {
+	s := &Schema{
+		messages: map[string]*idl.Message{},
+		enums:    map[string]*idl.Enum{},
+		methods:  map[string]*idl.RPC{},
+	}
+	seen := map[*idl.File]bool{}
+	var add func(*idl.File)
+	add = func(f *idl.File) {
+		if f == nil || seen[f] {
+			return
+		}
+		seen[f] = true
+		s.files = append(s.files, f)
+		for _, msg := range f.AllMessages() {
+			s.messages[msg.QualifiedName()] = msg
+		}
+		for _, enum := range f.AllEnums() {
+			s.enums[enum.QualifiedName()] = enum
+		}
+		for _, srv := range f.Services {
+			for _, rpc := range srv.RPCs {
+				name := srv.Name + "." + rpc.Name
+				if _, ok := s.methods[name]; !ok {
+					s.methods[name] = rpc
+				}
+			}
+		}
+		for _, imp := range f.Imports {
+			add(imp.File)
+		}
+	}
+	for _, f := range files {
+		add(f)
+	}
+	return s
+}
+
+// Files returns the files of the schema, the given ones first and then
+// their imports.
+func (s *Schema) Files() []*idl.File {
+	return s.files
+}
+
+// Message returns the message with the package-qualified name, such as
+// "acme.sensor.SensorReading", or nil.
+func (s *Schema) Message(name string) *idl.Message {
+	return s.messages[name]
+}
+
+// Enum returns the enum with the package-qualified name, or nil.
+func (s *Schema) Enum(name string) *idl.Enum {
+	return s.enums[name]
+}
+
+// Method returns the rpc registered under name, which has the
+// "Service.Method" form used by RPCServer.Register and RPCClient.Call, or
+// nil. When services of several packages share a name, the first one loaded
+// wins.
+func (s *Schema) Method(name string) *idl.RPC {
+	return s.methods[name]
+}
+
+// NewMessage returns an empty message of the type with the package-qualified
+// name.
+func (s *Schema) NewMessage(name string) (*Message, error) {
+	desc := s.Message(name)
+	if desc == nil {
+		return nil, fmt.Errorf("dynamic: unknown message %q", name)
+	}
+	return New(desc), nil
+}
@@ -5,18 +5,91 @@ import (
 	"math"
 )
 
-func WriteFloat32LE(buf *[]byte, val float32) {
+// AppendFloat32LE appends val to dst as 4 little-endian bytes.
+func AppendFloat32LE(dst []byte, val float32) []byte {
 	u := math.Float32bits(val)
-	var tmp [4]byte
-	binary.LittleEndian.PutUint32(tmp[:], u)
-	*buf = append(*buf, tmp[:]...)
+	return append(dst, byte(u), byte(u>>8), byte(u>>16), byte(u>>24))
 }
 
-func WriteFloat64LE(buf *[]byte, val float64) {
+// AppendFloat64LE appends val to dst as 8 little-endian bytes.
+func AppendFloat64LE(dst []byte, val float64) []byte {
 	u := math.Float64bits(val)
-	var tmp [8]byte
-	binary.LittleEndian.PutUint64(tmp[:], u)
-	*buf = append(*buf, tmp[:]...)
+	return append(dst, byte(u), byte(u>>8), byte(u>>16), byte(u>>24),
+		byte(u>>32), byte(u>>40), byte(u>>48), byte(u>>56))
+}
+
+// AppendBytes appends v to dst with its varint length in front.
+func AppendBytes(dst, v []byte) []byte {
+	return append(AppendVarint(dst, uint64(len(v))), v...)
+}
+
+// AppendString is AppendBytes for a string.
+func AppendString(dst []byte, v string) []byte {
+	return append(AppendVarint(dst, uint64(len(v))), v...)
+}
+
+// SizeCache holds the sizes of the nested messages of a generated message,
+// in the order MarshalWellsCached writes them. CacheSizeWells fills it, so a
+// marshal sizes each nested message once rather than once per enclosing
+// message. The first sizes are held inline, so a SizeCache on the stack
+// marshals a small message without allocating. CacheSizeWells records
+// nothing in a nil *SizeCache, and MarshalWellsCached sizes the nested
+// messages itself when given one.
+type SizeCache struct {
+	n     int
+	next  int
+	small [16]int
+	more  []int
+}
+
+// Reserve adds a slot for the size of a nested message that is about to be
+// sized and returns its index. Reserving before the message's own nested
+// messages keeps the slots in the order they are written.
+func (c *SizeCache) Reserve() int {
+	if c == nil {
+		return -1
+	}
+	if c.n >= len(c.small) {
+		c.more = append(c.more, 0)
+	}
+	c.n++
+	return c.n - 1
+}
+
+// Set records n in slot i and returns n.
+func (c *SizeCache) Set(i, n int) int {
+	if c != nil {
+		*c.slot(i) = n
+	}
+	return n
+}
+
+// Next returns the next recorded size.
+func (c *SizeCache) Next() int {
+	n := *c.slot(c.next)
+	c.next++
+	return n
+}
+
+// Reset empties c for reuse.
+func (c *SizeCache) Reset() {
+	c.n, c.next = 0, 0
+	c.more = c.more[:0]
+}
+
+func (c *SizeCache) slot(i int) *int {
+	if i < len(c.small) {
+		return &c.small[i]
+	}
+	return &c.more[i-len(c.small)]
+}
+
+func WriteFloat32LE(buf *[]byte, val float32) {
+	*buf = AppendFloat32LE(*buf, val)
+}
+
+func WriteFloat64LE(buf *[]byte, val float64) {
+	*buf = AppendFloat64LE(*buf, val)
 }
 
 func ReadFloat32LE(b []byte) float32 {
@@ -47,6 +47,7 @@ func WriteFrame(w io.Writer, f *Frame) error {
 	}
 
 	binary.LittleEndian.PutUint32(buf[:4], totalLen)
+	*bufp = buf
 	_, err := w.Write(buf)
 	return err
 }
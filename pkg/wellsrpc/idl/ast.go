@@ -0,0 +1,258 @@
+package idl
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Pos struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Pos) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+type File struct {
+	Name       string
+	Package    string
+	PackagePos Pos
+	PackageEnd Pos // position of the ';' ending the package statement
+	Imports    []*Import
+	Options    []*Option
+	Messages   []*Message
+	Enums      []*Enum
+	Services   []*Service
+
+	// Comments lists every comment in the file in source order.
+	Comments []*Comment
+}
+
+// Comment is a "//" line comment or a "/* */" block comment. Text is the
+// comment as written, including its delimiters.
+type Comment struct {
+	Pos  Pos
+	Text string
+
+	// Trailing is set when the comment follows other tokens on its first
+	// line, as in "int32 id = 1; // id".
+	Trailing bool
+}
+
+// Option returns the value of the file-level option name, or "" when the
+// option is not set.
+func (f *File) Option(name string) string {
+	for _, opt := range f.Options {
+		if opt.Name == name {
+			return opt.Value
+		}
+	}
+	return ""
+}
+
+// Import is an "import "path";" statement. Path is written relative to the
+// importing file or to one of the loader's import paths.
+type Import struct {
+	Pos  Pos
+	Path string
+	End  Pos // position of the final ';'
+
+	// Set by Load once the imported file has been parsed.
+	File *File
+}
+
+// AllMessages returns every message declared in f, with nested messages
+// following their parent.
+func (f *File) AllMessages() []*Message {
+	var out []*Message
+	var walk func([]*Message)
+	walk = func(msgs []*Message) {
+		for _, m := range msgs {
+			out = append(out, m)
+			walk(m.Messages)
+		}
+	}
+	walk(f.Messages)
+	return out
+}
+
+// AllEnums returns every enum declared in f, including nested ones.
+func (f *File) AllEnums() []*Enum {
+	out := append([]*Enum(nil), f.Enums...)
+	for _, m := range f.AllMessages() {
+		out = append(out, m.Enums...)
+	}
+	return out
+}
+
+type Option struct {
+	Pos    Pos
+	Name   string
+	Value  string
+	Quoted bool
+	End    Pos // position of the final ';'
+}
+
+type Message struct {
+	Pos      Pos
+	Name     string
+	File     *File
+	Parent   *Message
+	Fields   []*Field
+	Oneofs   []*Oneof
+	Messages []*Message
+	Enums    []*Enum
+	Reserved []*Reserved
+	Options  []*Option
+	End      Pos // position of the closing '}'
+}
+
+// FullName returns the dotted name of m, including the messages it is
+// nested in.
+func (m *Message) FullName() string {
+	if m.Parent == nil {
+		return m.Name
+	}
+	return m.Parent.FullName() + "." + m.Name
+}
+
+// QualifiedName returns FullName prefixed with the package of the file that
+// declares m.
+func (m *Message) QualifiedName() string {
+	return qualify(m.File, m.FullName())
+}
+
+type Field struct {
+	Pos       Pos
+	Name      string
+	Type      string
+	Number    int
+	HasNumber bool
+	Repeated  bool
+	KeyType   string
+	Oneof     *Oneof
+	End       Pos // position of the final ';'
+
+	// Set by Check once Type is resolved; both are nil for scalars.
+	Message *Message
+	Enum    *Enum
+}
+
+func (f *Field) IsMap() bool {
+	return f.KeyType != ""
+}
+
+// Oneof groups fields of which at most one is set. Its fields are also
+// listed in the enclosing Message.Fields.
+type Oneof struct {
+	Pos    Pos
+	Name   string
+	Fields []*Field
+	End    Pos
+}
+
+type Reserved struct {
+	Pos    Pos
+	Ranges []Range
+	Names  []string
+	End    Pos // position of the final ';'
+}
+
+// Range is an inclusive range of field numbers.
+type Range struct {
+	Start int
+	End   int
+}
+
+func (r Range) Contains(n int) bool {
+	return n >= r.Start && n <= r.End
+}
+
+type Enum struct {
+	Pos     Pos
+	Name    string
+	File    *File
+	Parent  *Message
+	Values  []*EnumValue
+	Options []*Option
+	End     Pos
+}
+
+func (e *Enum) FullName() string {
+	if e.Parent == nil {
+		return e.Name
+	}
+	return e.Parent.FullName() + "." + e.Name
+}
+
+func (e *Enum) QualifiedName() string {
+	return qualify(e.File, e.FullName())
+}
+
+func qualify(f *File, name string) string {
+	if f == nil || f.Package == "" {
+		return name
+	}
+	return f.Package + "." + name
+}
+
+type EnumValue struct {
+	Pos    Pos
+	Name   string
+	Number int
+	End    Pos // position of the final ';'
+}
+
+type Service struct {
+	Pos     Pos
+	Name    string
+	RPCs    []*RPC
+	Options []*Option
+	End     Pos
+}
+
+type RPC struct {
+	Pos             Pos
+	Name            string
+	Request         string
+	Response        string
+	ClientStreaming bool
+	ServerStreaming bool
+	Options         []*Option
+	End             Pos // position of the final ';' or '}'
+
+	// Set by Check once Request and Response are resolved.
+	RequestType  *Message
+	ResponseType *Message
+}
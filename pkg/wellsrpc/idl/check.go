@@ -0,0 +1,303 @@
+package idl
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+// Check assigns numbers to fields declared without one, resolves type
+// references and reports every semantic problem found in files and in the
+// files they import. A field without a number takes the number following the
+// previous field in the same message. Type names are looked up from the
+// innermost enclosing message outwards and then through the enclosing
+// packages, so nested declarations shadow outer ones. A file only sees its own
+// declarations and those of the files it imports directly; imports must have
+// been resolved, as Load does. Declarations that would generate invalid or
+// clashing Go identifiers are reported too.
+func Check(files ...*File) error {
+	var errs ErrorList
+	errorf := func(pos Pos, format string, args ...any) {
+		errs = append(errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	var all []*File
+	seen := map[*File]bool{}
+	var collect func(f *File)
+	collect = func(f *File) {
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		for _, imp := range f.Imports {
+			if imp.File == nil {
+				errorf(imp.Pos, "import %q has not been loaded", imp.Path)
+				continue
+			}
+			collect(imp.File)
+		}
+		all = append(all, f)
+	}
+	for _, f := range files {
+		collect(f)
+	}
+
+	syms := symbols{}
+	for _, f := range all {
+		for _, msg := range f.AllMessages() {
+			if prev, ok := syms[msg.QualifiedName()]; ok {
+				errorf(msg.Pos, "%s already declared at %s", msg.QualifiedName(), prev.pos())
+			}
+			syms[msg.QualifiedName()] = symbol{msg: msg}
+		}
+		for _, enum := range f.AllEnums() {
+			if prev, ok := syms[enum.QualifiedName()]; ok {
+				errorf(enum.Pos, "%s already declared at %s", enum.QualifiedName(), prev.pos())
+			}
+			syms[enum.QualifiedName()] = symbol{enum: enum}
+		}
+	}
+
+	services := map[string]*Service{}
+	for _, f := range all {
+		resolve := func(scope *Message, name string) (symbol, bool) {
+			sym, ok := syms.lookup(f, scope, name)
+			if !ok {
+				return sym, false
+			}
+			if file := sym.file(); file != f && !imports(f, file) {
+				return sym, false
+			}
+			return sym, true
+		}
+
+		for _, enum := range f.AllEnums() {
+			errs = append(errs, checkEnum(enum)...)
+		}
+		for _, msg := range f.AllMessages() {
+			errs = append(errs, checkMessage(msg)...)
+			for _, field := range msg.Fields {
+				if IsScalar(field.Type) {
+					continue
+				}
+				sym, ok := resolve(msg, field.Type)
+				if !ok {
+					errorf(field.Pos, "message %s: field %s has undefined type %s%s", msg.FullName(), field.Name, field.Type, sym.hint(f))
+					continue
+				}
+				field.Message, field.Enum = sym.msg, sym.enum
+			}
+		}
+
+		for _, srv := range f.Services {
+			name := qualify(f, srv.Name)
+			if prev, ok := services[name]; ok {
+				errorf(srv.Pos, "service %s already declared at %s", srv.Name, prev.Pos)
+			}
+			services[name] = srv
+			rpcs := map[string]bool{}
+			for _, rpc := range srv.RPCs {
+				if rpcs[rpc.Name] {
+					errorf(rpc.Pos, "service %s: rpc %s declared more than once", srv.Name, rpc.Name)
+				}
+				rpcs[rpc.Name] = true
+				message := func(name string) *Message {
+					sym, ok := resolve(nil, name)
+					if !ok || sym.msg == nil {
+						errorf(rpc.Pos, "service %s: rpc %s uses %s, which is not a message%s", srv.Name, rpc.Name, name, sym.hint(f))
+						return nil
+					}
+					return sym.msg
+				}
+				rpc.RequestType = message(rpc.Request)
+				rpc.ResponseType = message(rpc.Response)
+			}
+		}
+	}
+	errs = append(errs, checkGoNames(all)...)
+	return errs.Err()
+}
+
+func imports(f, dep *File) bool {
+	for _, imp := range f.Imports {
+		if imp.File == dep {
+			return true
+		}
+	}
+	return false
+}
+
+type symbol struct {
+	msg  *Message
+	enum *Enum
+}
+
+func (s symbol) pos() Pos {
+	if s.msg != nil {
+		return s.msg.Pos
+	}
+	return s.enum.Pos
+}
+
+func (s symbol) file() *File {
+	switch {
+	case s.msg != nil:
+		return s.msg.File
+	case s.enum != nil:
+		return s.enum.File
+	}
+	return nil
+}
+
+// hint explains why a symbol that exists was not visible from f.
+func (s symbol) hint(f *File) string {
+	if file := s.file(); file != nil && file != f {
+		return fmt.Sprintf(" (declared in %s, which %s does not import)", file.Name, f.Name)
+	}
+	return ""
+}
+
+// symbols maps package-qualified names to declarations.
+type symbols map[string]symbol
+
+// lookup resolves name as seen from scope in file f: first relative to each
+// enclosing message, then relative to the file's package and each of its
+// parent packages.
+func (s symbols) lookup(f *File, scope *Message, name string) (symbol, bool) {
+	for ; scope != nil; scope = scope.Parent {
+		if sym, ok := s[scope.QualifiedName()+"."+name]; ok {
+			return sym, true
+		}
+	}
+	for pkg := f.Package; pkg != ""; {
+		if sym, ok := s[pkg+"."+name]; ok {
+			return sym, true
+		}
+		i := strings.LastIndexByte(pkg, '.')
+		if i < 0 {
+			break
+		}
+		pkg = pkg[:i]
+	}
+	sym, ok := s[name]
+	return sym, ok
+}
+
+func checkEnum(enum *Enum) ErrorList {
+	var errs ErrorList
+	errorf := func(pos Pos, format string, args ...any) {
+		errs = append(errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if len(enum.Values) == 0 {
+		errorf(enum.Pos, "enum %s has no values", enum.FullName())
+		return errs
+	}
+	if enum.Values[0].Number != 0 {
+		errorf(enum.Values[0].Pos, "enum %s: first value %s must be zero", enum.FullName(), enum.Values[0].Name)
+	}
+	names := map[string]bool{}
+	numbers := map[int]*EnumValue{}
+	for _, val := range enum.Values {
+		if names[val.Name] {
+			errorf(val.Pos, "enum %s: value %s declared more than once", enum.FullName(), val.Name)
+		}
+		names[val.Name] = true
+		if val.Number < math.MinInt32 || val.Number > math.MaxInt32 {
+			errorf(val.Pos, "enum %s: value %s = %d does not fit in int32", enum.FullName(), val.Name, val.Number)
+		}
+		if prev, ok := numbers[val.Number]; ok {
+			errorf(val.Pos, "enum %s: value %s reuses number %d of %s", enum.FullName(), val.Name, val.Number, prev.Name)
+		}
+		numbers[val.Number] = val
+	}
+	return errs
+}
+
+func checkMessage(msg *Message) ErrorList {
+	var errs ErrorList
+	errorf := func(pos Pos, format string, args ...any) {
+		errs = append(errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	reservedNames := map[string]bool{}
+	var reservedRanges []Range
+	for _, res := range msg.Reserved {
+		for _, name := range res.Names {
+			if reservedNames[name] {
+				errorf(res.Pos, "message %s: name %q reserved more than once", msg.FullName(), name)
+			}
+			reservedNames[name] = true
+		}
+		for _, r := range res.Ranges {
+			switch {
+			case r.Start < 1 || r.End > wellsrpc.MaxFieldNumber:
+				errorf(res.Pos, "message %s: reserved range %d to %d outside 1 to %d", msg.FullName(), r.Start, r.End, wellsrpc.MaxFieldNumber)
+			case r.Start > r.End:
+				errorf(res.Pos, "message %s: reserved range %d to %d is empty", msg.FullName(), r.Start, r.End)
+			}
+			for _, prev := range reservedRanges {
+				if r.Start <= prev.End && prev.Start <= r.End {
+					errorf(res.Pos, "message %s: reserved range %d to %d overlaps %d to %d", msg.FullName(), r.Start, r.End, prev.Start, prev.End)
+				}
+			}
+			reservedRanges = append(reservedRanges, r)
+		}
+	}
+
+	names := map[string]*Field{}
+	oneofs := map[string]bool{}
+	for _, oneof := range msg.Oneofs {
+		if oneofs[oneof.Name] {
+			errorf(oneof.Pos, "message %s: oneof %s declared more than once", msg.FullName(), oneof.Name)
+		}
+		oneofs[oneof.Name] = true
+		if len(oneof.Fields) == 0 {
+			errorf(oneof.Pos, "message %s: oneof %s has no fields", msg.FullName(), oneof.Name)
+		}
+	}
+	numbers := map[int]*Field{}
+	next := 1
+	for _, field := range msg.Fields {
+		if !field.HasNumber {
+			field.Number = next
+		}
+		next = field.Number + 1
+
+		if prev, ok := names[field.Name]; ok {
+			errorf(field.Pos, "message %s: field %q already declared at %s", msg.FullName(), field.Name, prev.Pos)
+		}
+		names[field.Name] = field
+		if oneofs[field.Name] {
+			errorf(field.Pos, "message %s: field %s has the same name as a oneof", msg.FullName(), field.Name)
+		}
+		if field.IsMap() && !IsMapKey(field.KeyType) {
+			errorf(field.Pos, "message %s: field %s has invalid map key type %s", msg.FullName(), field.Name, field.KeyType)
+		}
+		if reservedNames[field.Name] {
+			errorf(field.Pos, "message %s: field name %q is reserved", msg.FullName(), field.Name)
+		}
+
+		switch {
+		case field.Number < 1:
+			errorf(field.Pos, "message %s: field %s has number %d, must be positive", msg.FullName(), field.Name, field.Number)
+			continue
+		case field.Number > wellsrpc.MaxFieldNumber:
+			errorf(field.Pos, "message %s: field %s has number %d, maximum is %d", msg.FullName(), field.Name, field.Number, wellsrpc.MaxFieldNumber)
+			continue
+		}
+		if prev, ok := numbers[field.Number]; ok {
+			errorf(field.Pos, "message %s: field %s reuses number %d of field %s", msg.FullName(), field.Name, field.Number, prev.Name)
+		}
+		numbers[field.Number] = field
+		for _, r := range reservedRanges {
+			if r.Contains(field.Number) {
+				errorf(field.Pos, "message %s: field %s uses reserved number %d", msg.FullName(), field.Name, field.Number)
+				break
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,439 @@
+package idl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckFieldNumbers(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		line, col int
+		msg       string
+	}{
+		{
+			"duplicate number",
+			"int32 a = 1;\n\tint32 b = 1;",
+			3, 2, "message M: field b reuses number 1 of field a",
+		},
+		{
+			"duplicate implicit number",
+			"int32 a = 2;\n\t1: int32 b;\n\tint32 c;",
+			4, 2, "message M: field c reuses number 2 of field a",
+		},
+		{
+			"zero",
+			"int32 a = 0;",
+			2, 2, "message M: field a has number 0, must be positive",
+		},
+		{
+			"negative",
+			"-3: int32 a;",
+			2, 2, "message M: field a has number -3, must be positive",
+		},
+		{
+			"above maximum",
+			"int32 a = 536870912;",
+			2, 2, "message M: field a has number 536870912, maximum is 536870911",
+		},
+		{
+			"implicit number above maximum",
+			"int32 a = 536870911;\n\tint32 b;",
+			3, 2, "message M: field b has number 536870912, maximum is 536870911",
+		},
+		{
+			"reserved number",
+			"reserved 2, 5;\n\tint32 a = 5;",
+			3, 2, "message M: field a uses reserved number 5",
+		},
+		{
+			"number inside reserved range",
+			"reserved 10 to 20;\n\tint32 a = 15;",
+			3, 2, "message M: field a uses reserved number 15",
+		},
+		{
+			"number inside reserved range to max",
+			"reserved 100 to max;\n\tint32 a = 536870911;",
+			3, 2, "message M: field a uses reserved number 536870911",
+		},
+		{
+			"reserved name",
+			"reserved \"old\";\n\tint32 old = 1;",
+			3, 2, `message M: field name "old" is reserved`,
+		},
+		{
+			"reserved range out of bounds",
+			"reserved 0 to 4;",
+			2, 2, "message M: reserved range 0 to 4 outside 1 to 536870911",
+		},
+		{
+			"empty reserved range",
+			"reserved 9 to 3;",
+			2, 2, "message M: reserved range 9 to 3 is empty",
+		},
+		{
+			"overlapping reserved ranges",
+			"reserved 1 to 5;\n\treserved 4 to 8;",
+			3, 2, "message M: reserved range 4 to 8 overlaps 1 to 5",
+		},
+		{
+			"name reserved twice",
+			"reserved \"a\", \"a\";",
+			2, 2, `message M: name "a" reserved more than once`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "message M {\n\t" + tt.body + "\n}\n"
+			f, err := Parse("check.wb.idl", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Check(f)
+			var errs ErrorList
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("Check error = %v, want exactly one error", err)
+			}
+			want := Pos{Filename: "check.wb.idl", Line: tt.line, Column: tt.col}
+			if errs[0].Pos != want || errs[0].Msg != tt.msg {
+				t.Errorf("Check error = %v, want %v: %s", errs[0], want, tt.msg)
+			}
+		})
+	}
+}
+
+func TestCheckValidNumbers(t *testing.T) {
+	src := `message M {
+	reserved 2, 10 to 20;
+	reserved "old";
+	int32 a = 1;
+	int32 b = 3;
+	int32 c;
+	int32 d = 21;
+	int32 e = 536870911;
+}
+`
+	f, err := Parse("check.wb.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(f); err != nil {
+		t.Fatal(err)
+	}
+	if c := f.Messages[0].Fields[2]; c.Number != 4 {
+		t.Errorf("implicit number of c = %d, want 4", c.Number)
+	}
+}
+
+func TestCheckEnums(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		line, col int
+		msg       string
+	}{
+		{
+			"no values",
+			"",
+			1, 1, "enum E has no values",
+		},
+		{
+			"first value not zero",
+			"E_A = 1;\n\tE_B = 0;",
+			2, 2, "enum E: first value E_A must be zero",
+		},
+		{
+			"duplicate name",
+			"E_A = 0;\n\tE_B = 1;\n\tE_A = 2;",
+			4, 2, "enum E: value E_A declared more than once",
+		},
+		{
+			"reused number",
+			"E_A = 0;\n\tE_B = 1;\n\tE_C = 1;",
+			4, 2, "enum E: value E_C reuses number 1 of E_B",
+		},
+		{
+			"above int32",
+			"E_A = 0;\n\tE_B = 2147483648;",
+			3, 2, "enum E: value E_B = 2147483648 does not fit in int32",
+		},
+		{
+			"below int32",
+			"E_A = 0;\n\tE_B = -2147483649;",
+			3, 2, "enum E: value E_B = -2147483649 does not fit in int32",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "enum E {\n\t" + tt.body + "\n}\n"
+			f, err := Parse("check.wb.idl", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Check(f)
+			var errs ErrorList
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("Check error = %v, want exactly one error", err)
+			}
+			want := Pos{Filename: "check.wb.idl", Line: tt.line, Column: tt.col}
+			if errs[0].Pos != want || errs[0].Msg != tt.msg {
+				t.Errorf("Check error = %v, want %v: %s", errs[0], want, tt.msg)
+			}
+		})
+	}
+}
+
+func TestCheckValidEnum(t *testing.T) {
+	src := "enum E {\n\tE_ZERO = 0;\n\tE_MIN = -2147483648;\n\tE_MAX = 2147483647;\n}\n"
+	f, err := Parse("check.wb.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	base, err := Parse("base.wb.idl", []byte(`package acme;
+message Shared {}
+enum Kind { KIND_NONE = 0; }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := Parse("app.wb.idl", []byte(`package acme.app;
+import "base.wb.idl";
+message Outer {
+	message Inner {
+		message Deep {}
+		Deep deep = 1;
+		Kind kind = 2;
+	}
+	enum Kind { KIND_UNSPECIFIED = 0; }
+	message Shared {}
+	Inner inner = 1;
+	Shared shared = 2;
+	acme.Shared outer_shared = 3;
+	Inner.Deep deep = 4;
+}
+message User {
+	Outer.Inner inner = 1;
+	Outer.Inner.Deep deep = 2;
+	Shared shared = 3;
+	Kind kind = 4;
+	acme.app.Outer.Kind outer_kind = 5;
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Imports[0].File = base
+	if err := Check(app); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"acme.app.Outer.Inner.deep":   "acme.app.Outer.Inner.Deep", // nested in the field's message
+		"acme.app.Outer.Inner.kind":   "acme.app.Outer.Kind",       // nested in an enclosing message
+		"acme.app.Outer.inner":        "acme.app.Outer.Inner",
+		"acme.app.Outer.shared":       "acme.app.Outer.Shared", // shadows acme.Shared
+		"acme.app.Outer.outer_shared": "acme.Shared",
+		"acme.app.Outer.deep":         "acme.app.Outer.Inner.Deep",
+		"acme.app.User.inner":         "acme.app.Outer.Inner", // relative to the package
+		"acme.app.User.deep":          "acme.app.Outer.Inner.Deep",
+		"acme.app.User.shared":        "acme.Shared", // relative to the parent package
+		"acme.app.User.kind":          "acme.Kind",
+		"acme.app.User.outer_kind":    "acme.app.Outer.Kind", // fully qualified
+	}
+	for _, msg := range app.AllMessages() {
+		for _, field := range msg.Fields {
+			var got string
+			switch {
+			case field.Message != nil:
+				got = field.Message.QualifiedName()
+			case field.Enum != nil:
+				got = field.Enum.QualifiedName()
+			}
+			name := msg.QualifiedName() + "." + field.Name
+			if got != want[name] {
+				t.Errorf("%s resolved to %q, want %q", name, got, want[name])
+			}
+			delete(want, name)
+		}
+	}
+	for name := range want {
+		t.Errorf("field %s not found", name)
+	}
+}
+
+func TestCheckScopeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		line, col int
+		msg       string
+	}{
+		{
+			"nested type outside its message",
+			"message Outer {\n\tmessage Inner {}\n}\nmessage User {\n\tInner inner = 1;\n}\n",
+			5, 2, "message User: field inner has undefined type Inner",
+		},
+		{
+			"sibling's nested type",
+			"message A {\n\tmessage Inner {}\n}\nmessage B {\n\tmessage Other {\n\t\tInner inner = 1;\n\t}\n}\n",
+			6, 3, "message B.Other: field inner has undefined type Inner",
+		},
+		{
+			"nested name declared twice",
+			"message Outer {\n\tmessage Inner {}\n\tenum Inner { INNER_NONE = 0; }\n}\n",
+			3, 2, "Outer.Inner already declared at check.wb.idl:2:2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse("check.wb.idl", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Check(f)
+			var errs ErrorList
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("Check error = %v, want exactly one error", err)
+			}
+			want := Pos{Filename: "check.wb.idl", Line: tt.line, Column: tt.col}
+			if errs[0].Pos != want || errs[0].Msg != tt.msg {
+				t.Errorf("Check error = %v, want %v: %s", errs[0], want, tt.msg)
+			}
+		})
+	}
+}
+
+func TestCheckGoNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		line, col int
+		msg       string
+	}{
+		{
+			"blank field",
+			"message M {\n\tint32 _ = 1;\n}\n",
+			2, 2, "message M: field _ generates an empty Go identifier",
+		},
+		{
+			"field starting with a digit",
+			"message M {\n\tint32 _1 = 1;\n}\n",
+			2, 2, "message M: field _1 generates Go identifier 1, which is not valid",
+		},
+		{
+			"blank oneof",
+			"message M {\n\toneof _ {\n\t\tint32 a = 1;\n\t}\n}\n",
+			2, 2, "message M: oneof _ generates an empty Go identifier",
+		},
+		{
+			"keyword message",
+			"message func {}\n",
+			1, 1, "message func generates Go identifier func, which is a Go keyword",
+		},
+		{
+			"blank message",
+			"message _ {}\n",
+			1, 1, "message _ generates Go identifier _, which is not valid",
+		},
+		{
+			"message named after an import",
+			"message fmt {}\n",
+			1, 1, "message fmt generates Go identifier fmt, already used by the import of package fmt in the generated code",
+		},
+		{
+			"message named after the runtime import",
+			"message wellib {}\n",
+			1, 1, "message wellib generates Go identifier wellib, already used by the import of package wellib in the generated code",
+		},
+		{
+			"message named after a service import",
+			"message context {}\nservice S {\n\trpc Get (context) returns (context);\n}\n",
+			1, 1, "message context generates Go identifier context, already used by the import of package context in the generated code",
+		},
+		{
+			"fields",
+			"message M {\n\tint32 user_id = 1;\n\tint32 userId = 2;\n}\n",
+			3, 2, "message M: field userId generates Go identifier UserId, already used by field user_id at check.wb.idl:2:2",
+		},
+		{
+			"field and method",
+			"message M {\n\tbytes size_wells = 1;\n}\n",
+			2, 2, "message M: field size_wells generates Go identifier SizeWells, already used by the SizeWells method",
+		},
+		{
+			"field and oneof getter",
+			"message M {\n\toneof choice {\n\t\tint32 id = 1;\n\t}\n\tint32 get_id = 2;\n}\n",
+			3, 3, "message M: the getter of oneof case id generates Go identifier GetId, already used by field get_id at check.wb.idl:5:2",
+		},
+		{
+			"nested and flat messages",
+			"message Outer {\n\tmessage Inner {}\n}\nmessage Outer_Inner {}\n",
+			4, 1, "message Outer_Inner generates Go identifier Outer_Inner, already used by message Outer.Inner at check.wb.idl:2:2",
+		},
+		{
+			"enum value and message",
+			"enum Kind { KIND_NONE = 0; }\nmessage Kind_KIND_NONE {}\n",
+			1, 13, "enum Kind value KIND_NONE generates Go identifier Kind_KIND_NONE, already used by message Kind_KIND_NONE at check.wb.idl:2:1",
+		},
+		{
+			"rpc and client method",
+			"message M {}\nservice S {\n\trpc Close (M) returns (M);\n}\n",
+			3, 2, "service S: rpc Close generates Go identifier Close, already used by the Close method of SClient",
+		},
+		{
+			"stream and message",
+			"message M {}\nmessage S_WatchServer {}\nservice S {\n\trpc Watch (M) returns (stream M);\n}\n",
+			4, 2, "service S rpc Watch generates Go identifier S_WatchServer, already used by message S_WatchServer at check.wb.idl:2:1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse("check.wb.idl", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Check(f)
+			var errs ErrorList
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Fatalf("Check error = %v, want exactly one error", err)
+			}
+			want := Pos{Filename: "check.wb.idl", Line: tt.line, Column: tt.col}
+			if errs[0].Pos != want || errs[0].Msg != tt.msg {
+				t.Errorf("Check error = %v, want %v: %s", errs[0], want, tt.msg)
+			}
+		})
+	}
+}
+
+// Files generated into different Go packages may reuse Go names.
+func TestCheckGoNamesPackages(t *testing.T) {
+	a, err := Parse("a.wb.idl", []byte("package a;\noption go_package = \"example.com/a\";\nmessage Outer_Inner {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse("b.wb.idl", []byte("package b;\nimport \"a.wb.idl\";\nmessage Outer {\n\tmessage Inner {}\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Imports[0].File = a
+	if err := Check(b); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Parse("c.wb.idl", []byte("package c;\noption go_package = \"example.com/a;a\";\nimport \"a.wb.idl\";\nmessage Outer_Inner {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Imports[0].File = a
+	if err := Check(c); err == nil || !strings.Contains(err.Error(), "already used by message Outer_Inner at a.wb.idl:3:1") {
+		t.Errorf("Check of a clash in one go_package = %v", err)
+	}
+}
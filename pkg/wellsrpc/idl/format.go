@@ -0,0 +1,566 @@
+package idl
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+// Format returns f in canonical form: two-space indentation, one statement
+// per line and fields written as "type name = N;" with the numbers of
+// adjacent fields aligned. The package comes first, then the imports sorted
+// by path and the file options; messages, enums and services follow in
+// source order, separated by blank lines. Comments stay with the statement
+// they precede, follow or sit inside, and blank lines inside a body are
+// kept, collapsed to one. Block comments leading the file stay at the top.
+func Format(f *File) []byte {
+	return format(f, false)
+}
+
+func format(f *File, proto bool) []byte {
+	p := &printer{comments: f.Comments, proto: proto}
+	p.file(f)
+
+	var out bytes.Buffer
+	tw := tabwriter.NewWriter(&out, 0, 8, 1, ' ', tabwriter.StripEscape)
+	tw.Write(p.buf.Bytes())
+	tw.Flush()
+	return out.Bytes()
+}
+
+// printer writes lines for a tabwriter: a tab separates the cells that are
+// aligned across adjacent lines, and comments are escaped so their contents
+// are never split into cells.
+type printer struct {
+	buf      bytes.Buffer
+	comments []*Comment
+	next     int // index of the first comment not yet printed
+	indent   int
+
+	// last is the source line on which the previous output ended, or 0 at
+	// the start of a body, where no blank line is kept.
+	last  int
+	blank bool // force a blank line before the next output
+
+	// limit is the position of the token after the statement being
+	// printed: the next statement or the '}' closing the body, or the zero
+	// Pos at the end of the file. Comments before it on the line where the
+	// statement ends trail the statement. open saves the limit of the
+	// enclosing statement in limits and close restores it.
+	limit  Pos
+	limits []Pos
+
+	proto   bool           // write proto3 instead of IDL
+	numbers map[*Field]int // field numbers, implicit ones included
+}
+
+func before(a, b Pos) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// sep starts output that begins on source line line, keeping one blank line
+// if the source had any.
+func (p *printer) sep(line int) {
+	if p.blank || (p.last > 0 && line > p.last+1) {
+		p.buf.WriteByte('\n')
+	}
+	p.blank = false
+}
+
+// leading prints the comments that come before pos.
+func (p *printer) leading(pos Pos) {
+	for p.next < len(p.comments) && before(p.comments[p.next].Pos, pos) {
+		p.comment(p.comments[p.next])
+		p.next++
+	}
+}
+
+func (p *printer) comment(c *Comment) {
+	p.sep(c.Pos.Line)
+	p.buf.WriteString(strings.Repeat("  ", p.indent))
+	p.buf.WriteString(escape(c.Text))
+	p.buf.WriteByte('\n')
+	p.last = c.Pos.Line + strings.Count(c.Text, "\n")
+}
+
+// line prints text for a statement from pos to the token at end, followed
+// by the comments inside it and those that trail it.
+func (p *printer) line(pos, end Pos, text string) {
+	p.leading(pos)
+	p.sep(pos.Line)
+	p.buf.WriteString(strings.Repeat("  ", p.indent))
+	p.buf.WriteString(text)
+	p.trailing(end, p.limit)
+}
+
+// trailing ends the line of a statement whose last token is at end. The
+// comments before end, which sit inside the statement, and those after it
+// on the same line and before limit are written after the statement.
+func (p *printer) trailing(end, limit Pos) {
+	var texts []string
+	line := end.Line
+	for ; p.next < len(p.comments); p.next++ {
+		c := p.comments[p.next]
+		if !before(c.Pos, end) && (c.Pos.Line != line || (limit.Line > 0 && !before(c.Pos, limit))) {
+			break
+		}
+		texts = append(texts, escape(c.Text))
+		if !before(c.Pos, end) {
+			line += strings.Count(c.Text, "\n")
+		}
+	}
+	if len(texts) > 0 {
+		p.buf.WriteString("\t" + strings.Join(texts, " "))
+	}
+	p.buf.WriteByte('\n')
+	p.last = line
+}
+
+// open prints the first line of a body ending at end, or the whole body as
+// "text {}" when it holds no statements and no comments. first is the
+// position of the first statement in the body, or the zero Pos for an empty
+// one. It reports whether the body still needs printing, followed by close.
+func (p *printer) open(pos, first, end Pos, text string) bool {
+	p.leading(pos)
+	if first.Line == 0 && (p.next == len(p.comments) || !before(p.comments[p.next].Pos, end)) {
+		p.line(pos, end, text+" {}")
+		return false
+	}
+	// The opening line is not aligned with its neighbours; a form feed ends
+	// the tabwriter's column block.
+	p.breakColumns()
+	p.sep(pos.Line)
+	p.buf.WriteString(strings.Repeat("  ", p.indent) + text + " {")
+	// Comments on the opening line trail it up to the first statement, as
+	// in "service S { // s" but not "service S { rpc A (M) returns (M); // a".
+	limit := end
+	if first.Line > 0 {
+		limit = first
+	}
+	p.trailing(Pos{Line: pos.Line}, limit)
+	p.breakColumns()
+	p.indent++
+	p.last = 0
+	p.limits = append(p.limits, p.limit)
+	return true
+}
+
+func (p *printer) close(end Pos) {
+	p.leading(end)
+	p.indent--
+	p.breakColumns()
+	p.buf.WriteString(strings.Repeat("  ", p.indent) + "}")
+	p.limit = p.limits[len(p.limits)-1]
+	p.limits = p.limits[:len(p.limits)-1]
+	p.trailing(end, p.limit)
+	p.breakColumns()
+}
+
+func (p *printer) breakColumns() {
+	if b := p.buf.Bytes(); len(b) > 0 && b[len(b)-1] == '\n' {
+		b[len(b)-1] = '\f'
+	}
+}
+
+// escape wraps s in tabwriter.Escape bytes so that the tabwriter passes it through unchanged.
+func escape(s string) string {
+	return "\xff" + s + "\xff"
+}
+
+type stmt struct {
+	pos   Pos
+	print func()
+}
+
+// sortStmts sorts list into source order and returns the position of its
+// first statement, or the zero Pos when it is empty.
+func sortStmts(list []stmt) Pos {
+	sort.SliceStable(list, func(i, j int) bool { return before(list[i].pos, list[j].pos) })
+	if len(list) == 0 {
+		return Pos{}
+	}
+	return list[0].pos
+}
+
+// stmts prints the statements of a body ending at end in source order.
+func (p *printer) stmts(list []stmt, end Pos) {
+	sortStmts(list)
+	for i, s := range list {
+		p.limit = end
+		if i+1 < len(list) {
+			p.limit = list[i+1].pos
+		}
+		s.print()
+	}
+}
+
+// headerStmt is a package, import or option statement of a file. These are
+// moved to the top of the file, taking their comments along.
+type headerStmt struct {
+	pos      Pos
+	text     string
+	comments []*Comment // on the lines before the statement
+	trailing []*Comment // inside the statement or after it on its last line
+}
+
+func (p *printer) file(f *File) {
+	// Order every top-level statement by position so each header
+	// statement can claim the comments between it and its predecessor.
+	type top struct {
+		pos, end Pos
+		header   *headerStmt
+	}
+	var tops []top
+	var pkg *headerStmt
+	var imports, options []*headerStmt
+	if f.Package != "" {
+		pkg = &headerStmt{pos: f.PackagePos, text: "package " + f.Package + ";"}
+		tops = append(tops, top{pos: pkg.pos, end: f.PackageEnd, header: pkg})
+	}
+	for _, imp := range f.Imports {
+		importPath := imp.Path
+		if p.proto {
+			importPath = strings.TrimSuffix(importPath, ".wb.idl") + ".proto"
+		}
+		h := &headerStmt{pos: imp.Pos, text: "import " + quote(importPath) + ";"}
+		imports = append(imports, h)
+		tops = append(tops, top{pos: h.pos, end: imp.End, header: h})
+	}
+	for _, opt := range f.Options {
+		h := &headerStmt{pos: opt.Pos, text: optionText(opt)}
+		options = append(options, h)
+		tops = append(tops, top{pos: h.pos, end: opt.End, header: h})
+	}
+	for _, m := range f.Messages {
+		tops = append(tops, top{pos: m.Pos, end: m.End})
+	}
+	for _, e := range f.Enums {
+		tops = append(tops, top{pos: e.Pos, end: e.End})
+	}
+	for _, s := range f.Services {
+		tops = append(tops, top{pos: s.Pos, end: s.End})
+	}
+	sort.SliceStable(tops, func(i, j int) bool { return before(tops[i].pos, tops[j].pos) })
+
+	var rest, fileComments []*Comment
+	fileBlank := false
+	i := 0
+	var prev Pos
+	for n, t := range tops {
+		for ; i < len(p.comments) && before(p.comments[i].Pos, t.pos); i++ {
+			c := p.comments[i]
+			trailsPrev := c.Trailing && c.Pos.Line == prev.Line
+			if t.header != nil && !trailsPrev && (prev.Line == 0 || before(prev, c.Pos)) {
+				t.header.comments = append(t.header.comments, c)
+			} else {
+				rest = append(rest, c)
+			}
+		}
+		if t.header != nil {
+			var limit Pos
+			if n+1 < len(tops) {
+				limit = tops[n+1].pos
+			}
+			for line := t.end.Line; i < len(p.comments); i++ {
+				c := p.comments[i]
+				if !before(c.Pos, t.end) {
+					if c.Pos.Line != line || (limit.Line > 0 && !before(c.Pos, limit)) {
+						break
+					}
+					line += strings.Count(c.Text, "\n")
+				}
+				t.header.trailing = append(t.header.trailing, c)
+			}
+			// Comments separated from the first statement of the file by a
+			// blank line, and block comments leading it, describe the file
+			// and stay at the top when the header statements are sorted.
+			if n == 0 {
+				comments := t.header.comments
+				k := detached(comments, t.pos)
+				fileBlank = k > 0
+				for ; k < len(comments) && strings.HasPrefix(comments[k].Text, "/*"); k++ {
+					fileBlank = false
+				}
+				fileComments, t.header.comments = comments[:k], comments[k:]
+			}
+		}
+		prev = t.end
+	}
+	p.comments = append(rest, p.comments[i:]...)
+
+	for _, c := range fileComments {
+		p.comment(c)
+	}
+	p.blank = fileBlank
+	if p.proto {
+		p.header(&headerStmt{text: `syntax = "proto3";`})
+		p.blank = true
+	}
+	sort.SliceStable(imports, func(i, j int) bool { return imports[i].text < imports[j].text })
+	for _, group := range [][]*headerStmt{{pkg}, imports, options} {
+		printed := false
+		for _, h := range group {
+			if h == nil {
+				continue
+			}
+			p.header(h)
+			printed = true
+		}
+		if printed {
+			p.blank = true
+		}
+	}
+
+	var decls []stmt
+	for _, m := range f.Messages {
+		m := m
+		decls = append(decls, stmt{m.Pos, func() { p.blank = p.buf.Len() > 0; p.message(m) }})
+	}
+	for _, e := range f.Enums {
+		e := e
+		decls = append(decls, stmt{e.Pos, func() { p.blank = p.buf.Len() > 0; p.enum(e) }})
+	}
+	for _, s := range f.Services {
+		s := s
+		decls = append(decls, stmt{s.Pos, func() { p.blank = p.buf.Len() > 0; p.service(s) }})
+	}
+	p.stmts(decls, Pos{})
+	for ; p.next < len(p.comments); p.next++ {
+		p.comment(p.comments[p.next])
+	}
+}
+
+// detached returns how many of comments, which precede pos, are separated
+// from it by a blank line.
+func detached(comments []*Comment, pos Pos) int {
+	k := 0
+	for i, c := range comments {
+		next := pos.Line
+		if i+1 < len(comments) {
+			next = comments[i+1].Pos.Line
+		}
+		if c.Trailing {
+			break
+		}
+		if next > c.Pos.Line+strings.Count(c.Text, "\n")+1 {
+			k = i + 1
+		}
+	}
+	return k
+}
+
+func (p *printer) header(h *headerStmt) {
+	p.sep(0)
+	for _, c := range h.comments {
+		p.buf.WriteString(escape(c.Text) + "\n")
+	}
+	p.buf.WriteString(h.text)
+	if len(h.trailing) > 0 {
+		var texts []string
+		for _, c := range h.trailing {
+			texts = append(texts, escape(c.Text))
+		}
+		p.buf.WriteString("\t" + strings.Join(texts, " "))
+	}
+	p.buf.WriteString("\n")
+	p.last = 0
+}
+
+func (p *printer) message(m *Message) {
+	if p.proto {
+		// Numbers are assigned as Check does, so unchecked files can be
+		// written too.
+		if p.numbers == nil {
+			p.numbers = map[*Field]int{}
+		}
+		next := 1
+		for _, field := range m.Fields {
+			if field.HasNumber {
+				next = field.Number
+			}
+			p.numbers[field] = next
+			next++
+		}
+	}
+	var list []stmt
+	for _, opt := range m.Options {
+		opt := opt
+		list = append(list, stmt{opt.Pos, func() { p.line(opt.Pos, opt.End, optionText(opt)) }})
+	}
+	for _, field := range m.Fields {
+		field := field
+		if field.Oneof == nil {
+			list = append(list, stmt{field.Pos, func() { p.line(field.Pos, field.End, p.fieldText(field)) }})
+		}
+	}
+	for _, oneof := range m.Oneofs {
+		oneof := oneof
+		list = append(list, stmt{oneof.Pos, func() { p.oneof(oneof) }})
+	}
+	for _, res := range m.Reserved {
+		res := res
+		list = append(list, stmt{res.Pos, func() { p.line(res.Pos, res.End, reservedText(res)) }})
+	}
+	for _, nested := range m.Messages {
+		nested := nested
+		list = append(list, stmt{nested.Pos, func() { p.message(nested) }})
+	}
+	for _, enum := range m.Enums {
+		enum := enum
+		list = append(list, stmt{enum.Pos, func() { p.enum(enum) }})
+	}
+	if p.open(m.Pos, sortStmts(list), m.End, "message "+m.Name) {
+		p.stmts(list, m.End)
+		p.close(m.End)
+	}
+}
+
+func (p *printer) oneof(o *Oneof) {
+	var first Pos
+	if len(o.Fields) > 0 {
+		first = o.Fields[0].Pos
+	}
+	if p.open(o.Pos, first, o.End, "oneof "+o.Name) {
+		var list []stmt
+		for _, field := range o.Fields {
+			field := field
+			list = append(list, stmt{field.Pos, func() { p.line(field.Pos, field.End, p.fieldText(field)) }})
+		}
+		p.stmts(list, o.End)
+		p.close(o.End)
+	}
+}
+
+func (p *printer) enum(e *Enum) {
+	var list []stmt
+	for _, opt := range e.Options {
+		opt := opt
+		list = append(list, stmt{opt.Pos, func() { p.line(opt.Pos, opt.End, optionText(opt)) }})
+	}
+	for _, v := range e.Values {
+		v := v
+		list = append(list, stmt{v.Pos, func() { p.line(v.Pos, v.End, v.Name+"\t= "+strconv.Itoa(v.Number)+";") }})
+	}
+	if p.open(e.Pos, sortStmts(list), e.End, "enum "+e.Name) {
+		p.stmts(list, e.End)
+		p.close(e.End)
+	}
+}
+
+func (p *printer) service(s *Service) {
+	var list []stmt
+	for _, opt := range s.Options {
+		opt := opt
+		list = append(list, stmt{opt.Pos, func() { p.line(opt.Pos, opt.End, optionText(opt)) }})
+	}
+	for _, rpc := range s.RPCs {
+		rpc := rpc
+		list = append(list, stmt{rpc.Pos, func() { p.rpc(rpc) }})
+	}
+	if p.open(s.Pos, sortStmts(list), s.End, "service "+s.Name) {
+		p.stmts(list, s.End)
+		p.close(s.End)
+	}
+}
+
+func (p *printer) rpc(r *RPC) {
+	text := "rpc " + r.Name + " (" + rpcType(r.Request, r.ClientStreaming) + ") returns (" + rpcType(r.Response, r.ServerStreaming) + ")"
+	if len(r.Options) == 0 {
+		p.line(r.Pos, r.End, text+";")
+		return
+	}
+	p.open(r.Pos, r.Options[0].Pos, r.End, text)
+	var list []stmt
+	for _, opt := range r.Options {
+		opt := opt
+		list = append(list, stmt{opt.Pos, func() { p.line(opt.Pos, opt.End, optionText(opt)) }})
+	}
+	p.stmts(list, r.End)
+	p.close(r.End)
+}
+
+func rpcType(name string, stream bool) string {
+	if stream {
+		return "stream " + name
+	}
+	return name
+}
+
+func (p *printer) fieldText(f *Field) string {
+	typ, keyType := f.Type, f.KeyType
+	if p.proto {
+		typ, keyType = protoType(typ), protoType(keyType)
+	}
+	var sb strings.Builder
+	if f.Repeated {
+		sb.WriteString("repeated ")
+	}
+	if f.IsMap() {
+		sb.WriteString("map<" + keyType + ", " + typ + ">")
+	} else {
+		sb.WriteString(typ)
+	}
+	sb.WriteString(" " + f.Name)
+	switch {
+	case p.proto:
+		sb.WriteString("\t= " + strconv.Itoa(p.numbers[f]))
+	case f.HasNumber:
+		sb.WriteString("\t= " + strconv.Itoa(f.Number))
+	}
+	sb.WriteString(";")
+	return sb.String()
+}
+
+func optionText(opt *Option) string {
+	value := opt.Value
+	if opt.Quoted {
+		value = quote(value)
+	}
+	return "option " + opt.Name + " = " + value + ";"
+}
+
+func reservedText(r *Reserved) string {
+	var parts []string
+	for _, name := range r.Names {
+		parts = append(parts, quote(name))
+	}
+	for _, rg := range r.Ranges {
+		switch {
+		case rg.Start == rg.End:
+			parts = append(parts, strconv.Itoa(rg.Start))
+		case rg.End == wellsrpc.MaxFieldNumber:
+			parts = append(parts, strconv.Itoa(rg.Start)+" to max")
+		default:
+			parts = append(parts, strconv.Itoa(rg.Start)+" to "+strconv.Itoa(rg.End))
+		}
+	}
+	return "reserved " + strings.Join(parts, ", ") + ";"
+}
+
+// quote writes s as a string literal using only the escapes the lexer
+// understands.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
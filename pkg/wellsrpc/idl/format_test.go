@@ -0,0 +1,60 @@
+package idl
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// TestFormatGolden formats each testdata/format/*.input file and compares
+// the result with the matching .golden file.
+func TestFormatGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "format", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/format/*.input files")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".input")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := formatSource(t, input, src)
+
+			golden := strings.TrimSuffix(input, ".input") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Format(%s) differs from %s:\n%s", input, golden, got)
+			}
+			if again := formatSource(t, golden, want); !bytes.Equal(again, want) {
+				t.Errorf("Format is not idempotent on %s:\n%s", golden, again)
+			}
+		})
+	}
+}
+
+func formatSource(t *testing.T, name string, src []byte) []byte {
+	t.Helper()
+	f, err := Parse(name, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Format(f)
+}
@@ -0,0 +1,267 @@
+package idl
+
+import (
+	gotoken "go/token"
+	"strings"
+	"unicode"
+)
+
+// GoName turns a snake_case IDL name into an exported Go identifier.
+func GoName(s string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// GoName flattens nested message names, so Outer.Inner becomes Outer_Inner.
+func (m *Message) GoName() string {
+	if m.Parent == nil {
+		return m.Name
+	}
+	return m.Parent.GoName() + "_" + m.Name
+}
+
+func (e *Enum) GoName() string {
+	if e.Parent == nil {
+		return e.Name
+	}
+	return e.Parent.GoName() + "_" + e.Name
+}
+
+func (f *Field) GoName() string {
+	return GoName(f.Name)
+}
+
+func (o *Oneof) GoName() string {
+	return GoName(o.Name)
+}
+
+// GoOneofInterface names the interface implemented by the cases of oneof.
+func (m *Message) GoOneofInterface(oneof *Oneof) string {
+	return "is" + m.GoName() + "_" + oneof.GoName()
+}
+
+// GoOneofWrapper names the struct wrapping one oneof case. A trailing
+// underscore avoids clashing with a nested type of the same name.
+func (m *Message) GoOneofWrapper(field *Field) string {
+	name := m.GoName() + "_" + field.GoName()
+	for _, nested := range m.Messages {
+		if nested.GoName() == name {
+			return name + "_"
+		}
+	}
+	for _, e := range m.Enums {
+		if e.GoName() == name {
+			return name + "_"
+		}
+	}
+	return name
+}
+
+// GoClientImpl names the unexported type implementing <Srv>Client.
+func GoClientImpl(service string) string {
+	return strings.ToLower(service[:1]) + service[1:] + "Client"
+}
+
+// GoStreamServer and GoStreamClient name the typed wrappers around
+// *wellsrpc.Stream generated for a streaming rpc.
+func GoStreamServer(service, rpc string) string {
+	return service + "_" + rpc + "Server"
+}
+
+func GoStreamClient(service, rpc string) string {
+	return service + "_" + rpc + "Client"
+}
+
+// goMethods are the methods generated for every message, and goClientMethods
+// those of every <Srv>Client besides its rpcs.
+var (
+	goMethods = []string{
+		"CacheSizeWells",
+		"MarshalWells",
+		"MarshalWellsAppend",
+		"MarshalWellsCached",
+		"SizeWells",
+		"UnmarshalWells",
+		"UnmarshalWellsDepth",
+	}
+	goClientMethods = []string{"Close"}
+)
+
+// GoMessageImports are the package names the generator imports into the file
+// holding the messages and enums of an IDL file, and GoServiceImports those
+// it imports into the server and client files. Declarations may not reuse
+// them.
+var (
+	GoMessageImports = []string{"errors", "fmt", "math", "strconv", "wellib"}
+	GoServiceImports = []string{"context", "errors", "wellib"}
+)
+
+// goPackageKey groups files whose code shares a Go package: those with the
+// same go_package import path, else the same package, else the same name.
+// The generator may still merge groups that resolve to one directory.
+func goPackageKey(f *File) string {
+	if opt := f.Option("go_package"); opt != "" {
+		if i := strings.LastIndexByte(opt, ';'); i >= 0 {
+			opt = opt[:i]
+		}
+		return "go_package " + opt
+	}
+	if f.Package != "" {
+		return "package " + f.Package
+	}
+	return "file " + f.Name
+}
+
+// goIdentProblem explains why name cannot be generated as a Go identifier,
+// or returns "" if it can.
+func goIdentProblem(name string) string {
+	switch {
+	case name == "":
+		return "generates an empty Go identifier"
+	case gotoken.IsKeyword(name):
+		return "generates Go identifier " + name + ", which is a Go keyword"
+	case name == "_" || !gotoken.IsIdentifier(name):
+		return "generates Go identifier " + name + ", which is not valid"
+	}
+	return ""
+}
+
+// checkGoNames reports declarations in files that generate invalid Go
+// identifiers or the same identifier, either at package level or within one
+// generated type. Declarations redeclared under the same qualified name are
+// reported by Check already and skipped here.
+func checkGoNames(files []*File) ErrorList {
+	var errs ErrorList
+	type decl struct {
+		pos  Pos
+		what string
+		id   string // qualified name of the declaration
+	}
+	scopes := map[string]map[string]decl{}
+	scopeOf := func(f *File) map[string]decl {
+		key := goPackageKey(f)
+		if scopes[key] == nil {
+			scopes[key] = map[string]decl{}
+		}
+		return scopes[key]
+	}
+	// The imports of the generated files come first, as every file of a
+	// package sees the package-level names of the others.
+	for _, f := range files {
+		pkg := scopeOf(f)
+		var imports []string
+		if len(f.Messages) > 0 || len(f.Enums) > 0 {
+			imports = append(imports, GoMessageImports...)
+		}
+		if len(f.Services) > 0 {
+			imports = append(imports, GoServiceImports...)
+		}
+		for _, name := range imports {
+			pkg[name] = decl{what: "the import of package " + name + " in the generated code", id: "import " + name}
+		}
+	}
+	declare := func(scope map[string]decl, name string, d decl, prefix string) {
+		if problem := goIdentProblem(name); problem != "" {
+			errs = append(errs, &Error{Pos: d.pos, Msg: prefix + d.what + " " + problem})
+			return
+		}
+		if prev, ok := scope[name]; ok {
+			if prev.id != d.id {
+				at := ""
+				if prev.pos != (Pos{}) {
+					at = " at " + prev.pos.String()
+				}
+				errs = append(errs, &Error{Pos: d.pos, Msg: prefix + d.what + " generates Go identifier " + name + ", already used by " + prev.what + at})
+			}
+			return
+		}
+		scope[name] = d
+	}
+
+	for _, f := range files {
+		pkg := scopeOf(f)
+		global := func(name string, pos Pos, what, id string) {
+			declare(pkg, name, decl{pos: pos, what: what, id: id}, "")
+		}
+
+		for _, msg := range f.AllMessages() {
+			what, id := "message "+msg.FullName(), msg.QualifiedName()
+			global(msg.GoName(), msg.Pos, what, id)
+			for _, oneof := range msg.Oneofs {
+				global(msg.GoOneofInterface(oneof), oneof.Pos, what+" oneof "+oneof.Name, id+"."+oneof.Name)
+				for _, field := range oneof.Fields {
+					global(msg.GoOneofWrapper(field), field.Pos, what+" oneof case "+field.Name, id+"."+field.Name)
+				}
+			}
+
+			members := map[string]decl{}
+			for _, name := range goMethods {
+				members[name] = decl{what: "the " + name + " method"}
+			}
+			member := func(name string, pos Pos, what, id string) {
+				declare(members, name, decl{pos: pos, what: what, id: id}, "message "+msg.FullName()+": ")
+			}
+			for _, field := range msg.Fields {
+				switch {
+				case field.Oneof == nil:
+					member(field.GoName(), field.Pos, "field "+field.Name, field.Name)
+				case field.Oneof.Fields[0] == field:
+					member(field.Oneof.GoName(), field.Oneof.Pos, "oneof "+field.Oneof.Name, field.Oneof.Name)
+				}
+			}
+			for _, oneof := range msg.Oneofs {
+				for _, field := range oneof.Fields {
+					if field.GoName() != "" {
+						member("Get"+field.GoName(), field.Pos, "the getter of oneof case "+field.Name, "Get "+field.Name)
+					}
+				}
+			}
+		}
+
+		for _, enum := range f.AllEnums() {
+			what, id := "enum "+enum.FullName(), enum.QualifiedName()
+			name := enum.GoName()
+			global(name, enum.Pos, what, id)
+			if goIdentProblem(name) != "" {
+				continue
+			}
+			global(name+"_name", enum.Pos, what, id)
+			global(name+"_value", enum.Pos, what, id)
+			for _, v := range enum.Values {
+				global(name+"_"+v.Name, v.Pos, what+" value "+v.Name, id+"."+v.Name)
+			}
+		}
+
+		for _, srv := range f.Services {
+			what, id := "service "+srv.Name, qualify(f, srv.Name)
+			for _, name := range []string{srv.Name + "Server", "Register" + srv.Name + "Server", srv.Name + "Client", GoClientImpl(srv.Name), "New" + srv.Name + "Client", "Dial" + srv.Name + "Client"} {
+				global(name, srv.Pos, what, id)
+			}
+
+			methods := map[string]decl{}
+			for _, name := range goClientMethods {
+				methods[name] = decl{what: "the " + name + " method of " + srv.Name + "Client"}
+			}
+			for _, rpc := range srv.RPCs {
+				declare(methods, rpc.Name, decl{pos: rpc.Pos, what: "rpc " + rpc.Name, id: rpc.Name}, what+": ")
+				if rpc.ClientStreaming || rpc.ServerStreaming {
+					global(GoStreamServer(srv.Name, rpc.Name), rpc.Pos, what+" rpc "+rpc.Name, id+"."+rpc.Name)
+					global(GoStreamClient(srv.Name, rpc.Name), rpc.Pos, what+" rpc "+rpc.Name, id+"."+rpc.Name)
+				}
+			}
+		}
+	}
+	return errs
+}
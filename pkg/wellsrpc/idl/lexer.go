@@ -0,0 +1,218 @@
+package idl
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type lexer struct {
+	filename string
+	src      string
+	off      int
+	line     int
+	col      int
+
+	comments []*Comment
+	lastLine int // line of the last token returned by next
+}
+
+func newLexer(filename string, src []byte) *lexer {
+	return &lexer{filename: filename, src: string(src), line: 1, col: 1}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Filename: l.filename, Line: l.line, Column: l.col}
+}
+
+func (l *lexer) peekByte(ahead int) byte {
+	if l.off+ahead < len(l.src) {
+		return l.src[l.off+ahead]
+	}
+	return 0
+}
+
+func (l *lexer) advance() byte {
+	c := l.src[l.off]
+	l.off++
+	if c == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return c
+}
+
+func (l *lexer) skipSpaceAndComments() error {
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			l.advance()
+		case c == '/' && l.peekByte(1) == '/':
+			start, off := l.pos(), l.off
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+			l.comment(start, strings.TrimRight(l.src[off:l.off], " \t\r"))
+		case c == '/' && l.peekByte(1) == '*':
+			start, off := l.pos(), l.off
+			l.advance()
+			l.advance()
+			for {
+				if l.off >= len(l.src) {
+					return &Error{Pos: start, Msg: "unterminated block comment"}
+				}
+				if l.src[l.off] == '*' && l.peekByte(1) == '/' {
+					l.advance()
+					l.advance()
+					break
+				}
+				l.advance()
+			}
+			l.comment(start, l.src[off:l.off])
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) comment(pos Pos, text string) {
+	l.comments = append(l.comments, &Comment{Pos: pos, Text: text, Trailing: pos.Line == l.lastLine})
+}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpaceAndComments(); err != nil {
+		return token{}, err
+	}
+	pos := l.pos()
+	l.lastLine = pos.Line
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+
+	c := l.src[l.off]
+	switch {
+	case isLetter(c):
+		start := l.off
+		for l.off < len(l.src) && (isLetter(l.src[l.off]) || isDigit(l.src[l.off])) {
+			l.advance()
+		}
+		return token{kind: tokIdent, text: l.src[start:l.off], pos: pos}, nil
+	case isDigit(c):
+		return l.number(pos)
+	case c == '"' || c == '\'':
+		return l.str(pos)
+	case strings.IndexByte("{}()<>[];:,=.-+", c) >= 0:
+		l.advance()
+		return token{kind: tokSymbol, text: string(c), pos: pos}, nil
+	default:
+		return token{}, &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", c)}
+	}
+}
+
+func (l *lexer) number(pos Pos) (token, error) {
+	start := l.off
+	kind := tokInt
+	if l.src[l.off] == '0' && (l.peekByte(1) == 'x' || l.peekByte(1) == 'X') {
+		l.advance()
+		l.advance()
+		for l.off < len(l.src) && isHexDigit(l.src[l.off]) {
+			l.advance()
+		}
+		if l.off-start == 2 {
+			return token{}, &Error{Pos: pos, Msg: "malformed hex literal"}
+		}
+	} else {
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance()
+		}
+		if l.off < len(l.src) && l.src[l.off] == '.' && isDigit(l.peekByte(1)) {
+			kind = tokFloat
+			l.advance()
+			for l.off < len(l.src) && isDigit(l.src[l.off]) {
+				l.advance()
+			}
+		}
+	}
+	if l.off < len(l.src) && isLetter(l.src[l.off]) {
+		return token{}, &Error{Pos: l.pos(), Msg: fmt.Sprintf("unexpected character %q in number", l.src[l.off])}
+	}
+	return token{kind: kind, text: l.src[start:l.off], pos: pos}, nil
+}
+
+func (l *lexer) str(pos Pos) (token, error) {
+	quote := l.advance()
+	var sb strings.Builder
+	for {
+		if l.off >= len(l.src) || l.src[l.off] == '\n' {
+			return token{}, &Error{Pos: pos, Msg: "unterminated string literal"}
+		}
+		c := l.advance()
+		if c == quote {
+			break
+		}
+		if c == '\\' {
+			if l.off >= len(l.src) {
+				return token{}, &Error{Pos: pos, Msg: "unterminated string literal"}
+			}
+			escPos := l.pos()
+			switch e := l.advance(); e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '\\', '"', '\'':
+				sb.WriteByte(e)
+			default:
+				return token{}, &Error{Pos: escPos, Msg: fmt.Sprintf("unknown escape sequence \\%c", e)}
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return token{kind: tokString, text: sb.String(), pos: pos}, nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,101 @@
+package idl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Load parses the named files together with every file they import and checks
+// them as a whole; it returns the named files in the order given. An import
+// path is looked up relative to the directory of the importing file first and
+// then relative to each of importPaths. A file reached through several imports
+// is parsed once, and import cycles are reported as errors. When every file
+// parses but Check fails, the files are returned together with the
+// ErrorList so tools can keep inspecting them.
+func Load(importPaths []string, filenames ...string) ([]*File, error) {
+	l := &loader{importPaths: importPaths, files: map[string]*File{}}
+	var out []*File
+	for _, name := range filenames {
+		f, err := l.load(name, Pos{})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, Check(out...)
+}
+
+type loader struct {
+	importPaths []string
+	files       map[string]*File
+	stack       []string
+}
+
+func (l *loader) load(path string, from Pos) (*File, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range l.stack {
+		if p == key {
+			cycle := append(append([]string(nil), l.stack[i:]...), key)
+			for j := range cycle {
+				cycle[j] = l.display(cycle[j])
+			}
+			return nil, &Error{Pos: from, Msg: "import cycle: " + strings.Join(cycle, " -> ")}
+		}
+	}
+	if f, ok := l.files[key]; ok {
+		return f, nil
+	}
+
+	f, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l.stack = append(l.stack, key)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	seen := map[string]bool{}
+	for _, imp := range f.Imports {
+		if seen[imp.Path] {
+			return nil, &Error{Pos: imp.Pos, Msg: fmt.Sprintf("%q imported more than once", imp.Path)}
+		}
+		seen[imp.Path] = true
+		resolved, ok := l.resolve(filepath.Dir(path), imp.Path)
+		if !ok {
+			return nil, &Error{Pos: imp.Pos, Msg: fmt.Sprintf("import %q not found", imp.Path)}
+		}
+		if imp.File, err = l.load(resolved, imp.Pos); err != nil {
+			return nil, err
+		}
+	}
+	l.files[key] = f
+	return f, nil
+}
+
+func (l *loader) resolve(dir, path string) (string, bool) {
+	if filepath.IsAbs(path) {
+		_, err := os.Stat(path)
+		return path, err == nil
+	}
+	for _, base := range append([]string{dir}, l.importPaths...) {
+		candidate := filepath.Join(base, filepath.FromSlash(path))
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// display shortens an absolute path for error messages.
+func (l *loader) display(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
@@ -0,0 +1,734 @@
+package idl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+func Parse(filename string, src []byte) (*File, error) {
+	p := &parser{lex: newLexer(filename, src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p.parseFile(filename)
+}
+
+type parser struct {
+	lex  *lexer
+	tok  token
+	file *File
+
+	// proto is set by ParseProto, which accepts proto3 syntax and records
+	// what it could not convert in notes.
+	proto     bool
+	sawSyntax bool
+	notes     []*ConvertNote
+	dropped   []*Field // fields left out of the message being parsed
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) is(text string) bool {
+	return (p.tok.kind == tokSymbol || p.tok.kind == tokIdent) && p.tok.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf(p.tok.pos, "expected %q, found %s", text, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) accept(text string) (bool, error) {
+	if !p.is(text) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) ident() (string, Pos, error) {
+	tok := p.tok
+	if tok.kind != tokIdent {
+		return "", tok.pos, p.errorf(tok.pos, "expected identifier, found %s", tok)
+	}
+	return tok.text, tok.pos, p.advance()
+}
+
+func (p *parser) fullIdent() (string, Pos, error) {
+	name, pos, err := p.ident()
+	if err != nil {
+		return "", pos, err
+	}
+	for p.is(".") {
+		if err := p.advance(); err != nil {
+			return "", pos, err
+		}
+		part, _, err := p.ident()
+		if err != nil {
+			return "", pos, err
+		}
+		name += "." + part
+	}
+	return name, pos, nil
+}
+
+// typeName parses a type reference. Proto files may write fully-qualified
+// names with a leading dot, which is dropped.
+func (p *parser) typeName() (string, error) {
+	if p.proto && p.is(".") {
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+	}
+	name, _, err := p.fullIdent()
+	return name, err
+}
+
+func (p *parser) intLit() (int, Pos, error) {
+	pos := p.tok.pos
+	neg, err := p.accept("-")
+	if err != nil {
+		return 0, pos, err
+	}
+	if p.tok.kind != tokInt {
+		return 0, pos, p.errorf(p.tok.pos, "expected integer, found %s", p.tok)
+	}
+	v, err := strconv.ParseInt(p.tok.text, 0, 64)
+	if err != nil {
+		return 0, pos, p.errorf(p.tok.pos, "integer %s out of range", p.tok.text)
+	}
+	if neg {
+		v = -v
+	}
+	return int(v), pos, p.advance()
+}
+
+func (p *parser) parseFile(filename string) (*File, error) {
+	f := &File{Name: filename}
+	p.file = f
+	for p.tok.kind != tokEOF {
+		var err error
+		switch {
+		case p.is(";"):
+			err = p.advance()
+		case p.proto && p.is("syntax"):
+			err = p.parseSyntax()
+		case p.proto && p.is("extend"):
+			err = p.skipStatement("extend")
+		case p.is("package"):
+			err = p.parsePackage(f)
+		case p.is("import"):
+			var imp *Import
+			if imp, err = p.parseImport(); err == nil && imp != nil {
+				f.Imports = append(f.Imports, imp)
+			}
+		case p.is("option"):
+			var opt *Option
+			if opt, err = p.parseOption(); err == nil && opt != nil {
+				f.Options = append(f.Options, opt)
+			}
+		case p.is("message"):
+			var msg *Message
+			if msg, err = p.parseMessage(nil); err == nil {
+				f.Messages = append(f.Messages, msg)
+			}
+		case p.is("enum"):
+			var enum *Enum
+			if enum, err = p.parseEnum(nil); err == nil {
+				f.Enums = append(f.Enums, enum)
+			}
+		case p.is("service"):
+			var srv *Service
+			if srv, err = p.parseService(); err == nil {
+				f.Services = append(f.Services, srv)
+			}
+		default:
+			err = p.errorf(p.tok.pos, "unexpected %s, expected package, import, message, enum, service or option", p.tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.proto && !p.sawSyntax {
+		p.note(Pos{Filename: filename, Line: 1, Column: 1}, false, "no syntax statement; reading the file as proto3")
+	}
+	f.Comments = p.lex.comments
+	return f, nil
+}
+
+func (p *parser) parsePackage(f *File) error {
+	pos := p.tok.pos
+	if err := p.expect("package"); err != nil {
+		return err
+	}
+	if f.Package != "" {
+		return p.errorf(pos, "package declared more than once")
+	}
+	if len(f.Messages) > 0 || len(f.Enums) > 0 || len(f.Services) > 0 {
+		return p.errorf(pos, "package must be declared before any message, enum or service")
+	}
+	name, _, err := p.fullIdent()
+	if err != nil {
+		return err
+	}
+	f.Package, f.PackagePos, f.PackageEnd = name, pos, p.tok.pos
+	return p.expect(";")
+}
+
+func (p *parser) parseImport() (*Import, error) {
+	imp := &Import{Pos: p.tok.pos}
+	if err := p.expect("import"); err != nil {
+		return nil, err
+	}
+	if p.proto && (p.is("public") || p.is("weak")) {
+		p.note(p.tok.pos, false, "import modifier %q dropped", p.tok.text)
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind != tokString {
+		return nil, p.errorf(p.tok.pos, "expected import path, found %s", p.tok)
+	}
+	if p.tok.text == "" {
+		return nil, p.errorf(p.tok.pos, "empty import path")
+	}
+	imp.Path = p.tok.text
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	imp.End = p.tok.pos
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if p.proto {
+		return p.protoImport(imp), nil
+	}
+	return imp, nil
+}
+
+func (p *parser) parseOption() (*Option, error) {
+	opt := &Option{Pos: p.tok.pos}
+	if err := p.expect("option"); err != nil {
+		return nil, err
+	}
+	if p.proto && p.is("(") {
+		return nil, p.skipStatement("custom option")
+	}
+	name, _, err := p.fullIdent()
+	if err != nil {
+		return nil, err
+	}
+	opt.Name = name
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+
+	if p.proto && p.is("{") {
+		return nil, p.skipStatement("option " + opt.Name)
+	}
+	tok := p.tok
+	switch {
+	case tok.kind == tokString:
+		opt.Value, opt.Quoted = tok.text, true
+	case tok.kind == tokIdent || tok.kind == tokInt || tok.kind == tokFloat:
+		opt.Value = tok.text
+	case p.is("-"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokInt && p.tok.kind != tokFloat {
+			return nil, p.errorf(p.tok.pos, "expected number after '-', found %s", p.tok)
+		}
+		tok = p.tok
+		opt.Value = "-" + tok.text
+	default:
+		return nil, p.errorf(tok.pos, "expected option value, found %s", tok)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	opt.End = p.tok.pos
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return opt, nil
+}
+
+func (p *parser) parseMessage(parent *Message) (*Message, error) {
+	msg := &Message{Pos: p.tok.pos, File: p.file, Parent: parent}
+	mark := len(p.dropped)
+	if err := p.expect("message"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	msg.Name = name
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return nil, p.errorf(p.tok.pos, "message %s: missing closing '}'", msg.Name)
+		case p.is(";"):
+			err = p.advance()
+		case p.is("option"):
+			var opt *Option
+			if opt, err = p.parseOption(); err == nil && opt != nil {
+				msg.Options = append(msg.Options, opt)
+			}
+		case p.proto && (p.is("extensions") || p.is("extend")):
+			err = p.skipStatement(p.tok.text)
+		case p.is("reserved"):
+			var res *Reserved
+			if res, err = p.parseReserved(); err == nil {
+				msg.Reserved = append(msg.Reserved, res)
+			}
+		case p.is("oneof"):
+			var oneof *Oneof
+			if oneof, err = p.parseOneof(); err == nil {
+				msg.Oneofs = append(msg.Oneofs, oneof)
+				msg.Fields = append(msg.Fields, oneof.Fields...)
+			}
+		case p.is("message"):
+			var nested *Message
+			if nested, err = p.parseMessage(msg); err == nil {
+				msg.Messages = append(msg.Messages, nested)
+			}
+		case p.is("enum"):
+			var enum *Enum
+			if enum, err = p.parseEnum(msg); err == nil {
+				msg.Enums = append(msg.Enums, enum)
+			}
+		default:
+			var field *Field
+			if field, err = p.parseField(); err == nil && field != nil {
+				msg.Fields = append(msg.Fields, field)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	msg.End = p.tok.pos
+	if len(p.dropped) > mark {
+		reserveDropped(msg, p.dropped[mark:])
+		p.dropped = p.dropped[:mark]
+	}
+	return msg, p.advance()
+}
+
+// parseField accepts both "int64 ts = 1;" and "1: int64 ts;"; the number is
+// optional in either form, "repeated" may precede the type and the type may be
+// "map<K, V>".
+func (p *parser) parseField() (*Field, error) {
+	field := &Field{Pos: p.tok.pos}
+	if p.tok.kind == tokInt || p.is("-") {
+		n, _, err := p.intLit()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		field.Number, field.HasNumber = n, true
+	}
+
+	optional := false
+	if p.proto {
+		if p.is("required") {
+			return nil, p.errorf(p.tok.pos, "required fields are not supported in proto3")
+		}
+		var err error
+		if optional, err = p.accept("optional"); err != nil {
+			return nil, err
+		}
+	}
+	repeated, err := p.accept("repeated")
+	if err != nil {
+		return nil, err
+	}
+	field.Repeated = repeated
+	typ, err := p.typeName()
+	if err != nil {
+		return nil, err
+	}
+	if typ == "map" && p.is("<") {
+		if field.Repeated {
+			return nil, p.errorf(field.Pos, "map fields cannot be repeated")
+		}
+		if field.KeyType, typ, err = p.parseMapTypes(); err != nil {
+			return nil, err
+		}
+	}
+	field.Type = typ
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	field.Name = name
+
+	if p.is("=") {
+		if field.HasNumber {
+			return nil, p.errorf(p.tok.pos, "field %s: number given twice", field.Name)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, _, err := p.intLit()
+		if err != nil {
+			return nil, err
+		}
+		field.Number, field.HasNumber = n, true
+	}
+	if p.proto && p.is("[") {
+		if err := p.parseFieldOptions("field " + field.Name); err != nil {
+			return nil, err
+		}
+	}
+	field.End = p.tok.pos
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if p.proto {
+		if optional {
+			p.note(field.Pos, false, "field %s: optional dropped; the IDL does not track field presence", field.Name)
+		}
+		if !p.protoFieldTypes(field) {
+			p.dropped = append(p.dropped, field)
+			return nil, nil
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) parseOneof() (*Oneof, error) {
+	oneof := &Oneof{Pos: p.tok.pos}
+	if err := p.expect("oneof"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	oneof.Name = name
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return nil, p.errorf(p.tok.pos, "oneof %s: missing closing '}'", oneof.Name)
+		case p.is(";"):
+			err = p.advance()
+		default:
+			var field *Field
+			if field, err = p.parseField(); err == nil && field != nil {
+				if field.Repeated || field.IsMap() {
+					err = p.errorf(field.Pos, "oneof %s: field %s cannot be repeated or a map", oneof.Name, field.Name)
+				}
+				field.Oneof = oneof
+				oneof.Fields = append(oneof.Fields, field)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	oneof.End = p.tok.pos
+	return oneof, p.advance()
+}
+
+func (p *parser) parseMapTypes() (string, string, error) {
+	if err := p.expect("<"); err != nil {
+		return "", "", err
+	}
+	key, _, err := p.ident()
+	if err != nil {
+		return "", "", err
+	}
+	if err := p.expect(","); err != nil {
+		return "", "", err
+	}
+	value, err := p.typeName()
+	if err != nil {
+		return "", "", err
+	}
+	if err := p.expect(">"); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// parseReserved parses "reserved 2, 9 to 11, 40 to max;" or
+// "reserved "foo", "bar";".
+func (p *parser) parseReserved() (*Reserved, error) {
+	res := &Reserved{Pos: p.tok.pos}
+	if err := p.expect("reserved"); err != nil {
+		return nil, err
+	}
+	for {
+		if p.tok.kind == tokString {
+			res.Names = append(res.Names, p.tok.text)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		} else {
+			start, _, err := p.intLit()
+			if err != nil {
+				return nil, err
+			}
+			r := Range{Start: start, End: start}
+			if ok, err := p.accept("to"); err != nil {
+				return nil, err
+			} else if ok {
+				if ok, err := p.accept("max"); err != nil {
+					return nil, err
+				} else if ok {
+					r.End = wellsrpc.MaxFieldNumber
+				} else if r.End, _, err = p.intLit(); err != nil {
+					return nil, err
+				}
+			}
+			res.Ranges = append(res.Ranges, r)
+		}
+		if ok, err := p.accept(","); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+	}
+	if len(res.Names) > 0 && len(res.Ranges) > 0 {
+		return nil, p.errorf(res.Pos, "reserved: cannot mix field names and numbers in one statement")
+	}
+	res.End = p.tok.pos
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *parser) parseEnum(parent *Message) (*Enum, error) {
+	enum := &Enum{Pos: p.tok.pos, File: p.file, Parent: parent}
+	if err := p.expect("enum"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	enum.Name = name
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return nil, p.errorf(p.tok.pos, "enum %s: missing closing '}'", enum.Name)
+		case p.is(";"):
+			err = p.advance()
+		case p.proto && p.is("reserved"):
+			err = p.skipStatement("enum reserved")
+		case p.is("option"):
+			var opt *Option
+			if opt, err = p.parseOption(); err == nil && opt != nil {
+				enum.Options = append(enum.Options, opt)
+			}
+		default:
+			var val *EnumValue
+			if val, err = p.parseEnumValue(); err == nil {
+				enum.Values = append(enum.Values, val)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	enum.End = p.tok.pos
+	return enum, p.advance()
+}
+
+func (p *parser) parseEnumValue() (*EnumValue, error) {
+	val := &EnumValue{Pos: p.tok.pos}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	val.Name = name
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	if val.Number, _, err = p.intLit(); err != nil {
+		return nil, err
+	}
+	if p.proto && p.is("[") {
+		if err := p.parseFieldOptions("enum value " + val.Name); err != nil {
+			return nil, err
+		}
+	}
+	val.End = p.tok.pos
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (p *parser) parseService() (*Service, error) {
+	srv := &Service{Pos: p.tok.pos}
+	if err := p.expect("service"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	srv.Name = name
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return nil, p.errorf(p.tok.pos, "service %s: missing closing '}'", srv.Name)
+		case p.is(";"):
+			err = p.advance()
+		case p.is("option"):
+			var opt *Option
+			if opt, err = p.parseOption(); err == nil && opt != nil {
+				srv.Options = append(srv.Options, opt)
+			}
+		case p.is("rpc"):
+			var rpc *RPC
+			if rpc, err = p.parseRPC(); err == nil {
+				srv.RPCs = append(srv.RPCs, rpc)
+			}
+		default:
+			err = p.errorf(p.tok.pos, "unexpected %s in service %s, expected rpc or option", p.tok, srv.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	srv.End = p.tok.pos
+	return srv, p.advance()
+}
+
+func (p *parser) parseRPC() (*RPC, error) {
+	rpc := &RPC{Pos: p.tok.pos}
+	if err := p.expect("rpc"); err != nil {
+		return nil, err
+	}
+	name, _, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	rpc.Name = name
+
+	if rpc.Request, rpc.ClientStreaming, err = p.parseRPCType(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("returns"); err != nil {
+		return nil, err
+	}
+	if rpc.Response, rpc.ServerStreaming, err = p.parseRPCType(); err != nil {
+		return nil, err
+	}
+
+	rpc.End = p.tok.pos
+	if ok, err := p.accept(";"); ok || err != nil {
+		return rpc, err
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.is("}") {
+		switch {
+		case p.tok.kind == tokEOF:
+			return nil, p.errorf(p.tok.pos, "rpc %s: missing closing '}'", rpc.Name)
+		case p.is(";"):
+			err = p.advance()
+		case p.is("option"):
+			var opt *Option
+			if opt, err = p.parseOption(); err == nil && opt != nil {
+				rpc.Options = append(rpc.Options, opt)
+			}
+		default:
+			err = p.errorf(p.tok.pos, "unexpected %s in rpc %s, expected option", p.tok, rpc.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	rpc.End = p.tok.pos
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	_, err = p.accept(";")
+	return rpc, err
+}
+
+// parseRPCType parses "(Type)" or "(stream Type)". A message may itself be
+// called "stream", so the keyword only counts when a type name follows it.
+// In proto files that name may start with a dot, as in "stream .pkg.Type",
+// while "stream.Type" without a space still names a type.
+func (p *parser) parseRPCType() (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	typ, stream := "", false
+	if p.is("stream") {
+		after := p.tok.pos
+		after.Column += len("stream")
+		if err := p.advance(); err != nil {
+			return "", false, err
+		}
+		stream = p.tok.kind == tokIdent || (p.proto && p.is(".") && p.tok.pos != after)
+		if !stream {
+			typ = "stream"
+			for p.is(".") {
+				if err := p.advance(); err != nil {
+					return "", false, err
+				}
+				part, _, err := p.ident()
+				if err != nil {
+					return "", false, err
+				}
+				typ += "." + part
+			}
+		}
+	}
+	if typ == "" {
+		var err error
+		if typ, err = p.typeName(); err != nil {
+			return "", false, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return "", false, err
+	}
+	return typ, stream, nil
+}
@@ -0,0 +1,222 @@
+package idl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFieldForms(t *testing.T) {
+	src := `package demo;
+
+message Reading {
+	int64 ts = 1;
+	2: float temp;
+	repeated string tags = 3;
+	4: map<string, int32> counts;
+	bytes payload;
+}
+`
+	f, err := Parse("demo.wb.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "demo" || len(f.Messages) != 1 {
+		t.Fatalf("got package %q with %d messages", f.Package, len(f.Messages))
+	}
+	want := []struct {
+		name, typ, key string
+		number         int
+		hasNumber      bool
+		repeated       bool
+		line, col      int
+	}{
+		{"ts", "int64", "", 1, true, false, 4, 2},
+		{"temp", "float", "", 2, true, false, 5, 2},
+		{"tags", "string", "", 3, true, true, 6, 2},
+		{"counts", "int32", "string", 4, true, false, 7, 2},
+		{"payload", "bytes", "", 0, false, false, 8, 2},
+	}
+	fields := f.Messages[0].Fields
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, w := range want {
+		got := fields[i]
+		if got.Name != w.name || got.Type != w.typ || got.KeyType != w.key || got.Number != w.number ||
+			got.HasNumber != w.hasNumber || got.Repeated != w.repeated {
+			t.Errorf("field %d = %+v, want %+v", i, *got, w)
+		}
+		if got.Pos.Line != w.line || got.Pos.Column != w.col {
+			t.Errorf("field %s at %d:%d, want %d:%d", got.Name, got.Pos.Line, got.Pos.Column, w.line, w.col)
+		}
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	src := `/* header
+   spans lines */
+package demo; // trailing after package
+
+// leading
+message Reading {
+	int64 ts = 1; /* trailing block */
+	/* inline */ float temp = 2;
+}
+`
+	f, err := Parse("demo.wb.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text      string
+		line, col int
+		trailing  bool
+	}{
+		{"/* header\n   spans lines */", 1, 1, false},
+		{"// trailing after package", 3, 15, true},
+		{"// leading", 5, 1, false},
+		{"/* trailing block */", 7, 16, true},
+		{"/* inline */", 8, 2, false},
+	}
+	if len(f.Comments) != len(want) {
+		t.Fatalf("got %d comments, want %d", len(f.Comments), len(want))
+	}
+	for i, w := range want {
+		c := f.Comments[i]
+		if c.Text != w.text || c.Pos.Line != w.line || c.Pos.Column != w.col || c.Trailing != w.trailing {
+			t.Errorf("comment %d = %q at %d:%d trailing=%v, want %q at %d:%d trailing=%v",
+				i, c.Text, c.Pos.Line, c.Pos.Column, c.Trailing, w.text, w.line, w.col, w.trailing)
+		}
+	}
+	if fields := f.Messages[0].Fields; len(fields) != 2 || fields[1].Name != "temp" {
+		t.Errorf("comments disturbed the fields: %+v", fields)
+	}
+}
+
+func TestParseMultiLine(t *testing.T) {
+	src := `package
+	demo.sensors
+;
+message
+Reading
+{
+	map <
+		string ,
+		int64
+	>
+	counts
+	=
+	1
+	;
+}
+service SensorService {
+	rpc StreamReadings (
+		stream Reading
+	) returns (
+		Reading
+	);
+}
+`
+	f, err := Parse("demo.wb.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "demo.sensors" || f.PackagePos.Line != 1 {
+		t.Errorf("package = %q at line %d", f.Package, f.PackagePos.Line)
+	}
+	msg := f.Messages[0]
+	if msg.Name != "Reading" || msg.Pos.Line != 4 || msg.End.Line != 15 {
+		t.Errorf("message %s spans lines %d-%d, want Reading 4-15", msg.Name, msg.Pos.Line, msg.End.Line)
+	}
+	if fld := msg.Fields[0]; fld.Name != "counts" || fld.KeyType != "string" || fld.Type != "int64" || fld.Number != 1 {
+		t.Errorf("field = %+v", *fld)
+	}
+	rpc := f.Services[0].RPCs[0]
+	if rpc.Name != "StreamReadings" || !rpc.ClientStreaming || rpc.ServerStreaming ||
+		rpc.Request != "Reading" || rpc.Response != "Reading" || rpc.Pos.Line != 17 || rpc.End.Line != 21 {
+		t.Errorf("rpc = %+v", *rpc)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		line, col int
+		msg       string
+	}{
+		{
+			"unterminated string",
+			"package demo;\nimport \"common.wb.idl;\n",
+			2, 8, "unterminated string literal",
+		},
+		{
+			"unterminated string at end of file",
+			"import 'x",
+			1, 8, "unterminated string literal",
+		},
+		{
+			"unknown escape",
+			`import "a\qb";`,
+			1, 11, `unknown escape sequence \q`,
+		},
+		{
+			"unterminated comment",
+			"package demo;\n\n  /* never\nclosed",
+			3, 3, "unterminated block comment",
+		},
+		{
+			"unexpected character",
+			"message M {\n\tint32 id = 1 #;\n}",
+			2, 15, "unexpected character '#'",
+		},
+		{
+			"unexpected top-level token",
+			"package demo;\n  field x;",
+			2, 3, `unexpected "field", expected package, import, message, enum, service or option`,
+		},
+		{
+			"missing semicolon",
+			"message M {\n\tint32 id = 1\n\tstring name = 2;\n}",
+			3, 2, `expected ";", found "string"`,
+		},
+		{
+			"number given twice",
+			"message M {\n\t1: int32 id = 1;\n}",
+			2, 14, "field id: number given twice",
+		},
+		{
+			"missing closing brace",
+			"message M {\n\tint32 id = 1;\n",
+			3, 1, "message M: missing closing '}'",
+		},
+		{
+			"bad token in service",
+			"service S {\n\tmessage M {}\n}",
+			2, 2, `unexpected "message" in service S, expected rpc or option`,
+		},
+		{
+			"number with letters",
+			"message M {\n\tint32 id = 12ab;\n}",
+			2, 15, `unexpected character 'a' in number`,
+		},
+		{
+			"package after message",
+			"message M {}\npackage demo;",
+			2, 1, "package must be declared before any message, enum or service",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("bad.wb.idl", []byte(tt.src))
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse error = %v, want *Error", err)
+			}
+			want := Pos{Filename: "bad.wb.idl", Line: tt.line, Column: tt.col}
+			if perr.Pos != want || perr.Msg != tt.msg {
+				t.Errorf("Parse error = %v, want %v: %s", perr, want, tt.msg)
+			}
+		})
+	}
+}
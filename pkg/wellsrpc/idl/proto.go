@@ -0,0 +1,282 @@
+package idl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ConvertNote describes something that a conversion between .proto and
+// .wb.idl could not carry over exactly.
+type ConvertNote struct {
+	Pos Pos
+	Msg string
+
+	// Dropped is set when the construct was left out of the output, as
+	// opposed to converted with a change the user should know about.
+	Dropped bool
+}
+
+// ParseProto parses a proto3 file into a File that Format writes as .wb.idl.
+//
+// Scalar types are mapped to the IDL type with the same wire encoding:
+// sint32 and sint64 become int32 and int64, which the IDL zigzag encodes,
+// and int32 and int64 become uint32 and uint64, which are written as the
+// same plain varints. Fields of the fixed-width integer types have no IDL
+// equivalent and are dropped, as are extensions, custom options, field
+// options other than packed and enum reserved ranges; imports of "x.proto"
+// become imports of "x.wb.idl". Every such change is listed in the returned
+// notes.
+func ParseProto(filename string, src []byte) (*File, []*ConvertNote, error) {
+	p := &parser{lex: newLexer(filename, src), proto: true}
+	if err := p.advance(); err != nil {
+		return nil, nil, err
+	}
+	f, err := p.parseFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, p.notes, nil
+}
+
+func (p *parser) note(pos Pos, dropped bool, format string, args ...any) {
+	p.notes = append(p.notes, &ConvertNote{Pos: pos, Msg: fmt.Sprintf(format, args...), Dropped: dropped})
+}
+
+func (p *parser) parseSyntax() error {
+	pos := p.tok.pos
+	if err := p.expect("syntax"); err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	if p.tok.kind != tokString {
+		return p.errorf(p.tok.pos, "expected syntax version, found %s", p.tok)
+	}
+	if p.tok.text != "proto3" {
+		return p.errorf(pos, "syntax %q is not supported, only proto3", p.tok.text)
+	}
+	p.sawSyntax = true
+	if err := p.advance(); err != nil {
+		return err
+	}
+	return p.expect(";")
+}
+
+// skipStatement skips the statement starting at the current token, up to
+// its ';' or the '}' closing its body.
+func (p *parser) skipStatement(what string) error {
+	p.note(p.tok.pos, true, "%s is not supported", what)
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.errorf(p.tok.pos, "unexpected end of file in %s", what)
+		case p.is("{"):
+			depth++
+		case p.is("}"):
+			depth--
+			if depth == 0 {
+				if err := p.advance(); err != nil {
+					return err
+				}
+				_, err := p.accept(";")
+				return err
+			}
+		case p.is(";") && depth == 0:
+			return p.advance()
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseFieldOptions parses a "[packed = true, deprecated = true]" list.
+// packed is accepted since repeated numbers are always packed in the IDL;
+// every other option is dropped.
+func (p *parser) parseFieldOptions(what string) error {
+	if err := p.expect("["); err != nil {
+		return err
+	}
+	for {
+		pos := p.tok.pos
+		name, err := p.optionName()
+		if err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		value, err := p.optionValue(what)
+		if err != nil {
+			return err
+		}
+		switch {
+		case name != "packed":
+			p.note(pos, true, "%s: option %s is not supported", what, name)
+		case value != "true":
+			p.note(pos, false, "%s: packed = %s ignored; repeated numbers are always packed", what, value)
+		}
+		if ok, err := p.accept(","); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+	}
+	return p.expect("]")
+}
+
+// optionName parses an option name such as "deprecated" or
+// "(acme.validate).max".
+func (p *parser) optionName() (string, error) {
+	if !p.is("(") {
+		name, _, err := p.fullIdent()
+		return name, err
+	}
+	if err := p.advance(); err != nil {
+		return "", err
+	}
+	name, _, err := p.fullIdent()
+	if err != nil {
+		return "", err
+	}
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+	name = "(" + name + ")"
+	for p.is(".") {
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+		part, _, err := p.ident()
+		if err != nil {
+			return "", err
+		}
+		name += "." + part
+	}
+	return name, nil
+}
+
+// optionValue parses the value of an option, skipping a "{...}" aggregate,
+// and returns it as written.
+func (p *parser) optionValue(what string) (string, error) {
+	if p.is("{") {
+		depth := 0
+		for {
+			switch {
+			case p.tok.kind == tokEOF:
+				return "", p.errorf(p.tok.pos, "unexpected end of file in %s", what)
+			case p.is("{"):
+				depth++
+			case p.is("}"):
+				depth--
+			}
+			if err := p.advance(); err != nil {
+				return "", err
+			}
+			if depth == 0 {
+				return "{...}", nil
+			}
+		}
+	}
+	sign := ""
+	if p.is("-") {
+		sign = "-"
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+	}
+	tok := p.tok
+	switch {
+	case tok.kind == tokString && sign == "":
+		tok.text = quote(tok.text)
+	case tok.kind == tokIdent || tok.kind == tokInt || tok.kind == tokFloat:
+	default:
+		return "", p.errorf(tok.pos, "expected option value, found %s", tok)
+	}
+	return sign + tok.text, p.advance()
+}
+
+// protoImport rewrites the path of imp, or returns nil to drop it.
+func (p *parser) protoImport(imp *Import) *Import {
+	if strings.HasPrefix(imp.Path, "google/protobuf/") {
+		p.note(imp.Pos, true, "import %q: well-known types have no .wb.idl equivalent", imp.Path)
+		return nil
+	}
+	if ext := path.Ext(imp.Path); ext == ".proto" {
+		imp.Path = strings.TrimSuffix(imp.Path, ext) + ".wb.idl"
+	}
+	return imp
+}
+
+// protoFieldTypes rewrites the proto scalar types of field to IDL types. It
+// reports false when field has to be dropped.
+func (p *parser) protoFieldTypes(field *Field) bool {
+	typ, ok := p.protoScalar(field, field.Type)
+	if !ok {
+		return false
+	}
+	field.Type = typ
+	if field.IsMap() {
+		if field.KeyType, ok = p.protoScalar(field, field.KeyType); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) protoScalar(field *Field, typ string) (string, bool) {
+	switch typ {
+	case "sint32":
+		return "int32", true
+	case "sint64":
+		return "int64", true
+	case "int32", "int64":
+		idlType := "u" + typ
+		p.note(field.Pos, false, "field %s: %s becomes %s, which has the same encoding but another Go type", field.Name, typ, idlType)
+		return idlType, true
+	case "fixed32", "fixed64", "sfixed32", "sfixed64":
+		p.note(field.Pos, true, "field %s: %s has no IDL equivalent; its number is reserved", field.Name, typ)
+		return "", false
+	}
+	return typ, true
+}
+
+// FormatProto returns f as a proto3 file, laid out like Format. IDL int32
+// and int64 are written as sint32 and sint64, which share their zigzag
+// encoding, implicit field numbers are written out, and imports of
+// "x.wb.idl" become imports of "x.proto".
+func FormatProto(f *File) []byte {
+	return format(f, true)
+}
+
+// protoType returns the proto3 name of the IDL type typ.
+func protoType(typ string) string {
+	switch typ {
+	case "int32":
+		return "sint32"
+	case "int64":
+		return "sint64"
+	case "float32":
+		return "float"
+	case "float64":
+		return "double"
+	}
+	return typ
+}
+
+// reserveDropped reserves the numbers of fields dropped from msg, so they
+// cannot be reused by accident.
+func reserveDropped(msg *Message, fields []*Field) {
+	res := &Reserved{Pos: fields[0].Pos, End: fields[0].End}
+	for _, field := range fields {
+		if field.HasNumber {
+			res.Ranges = append(res.Ranges, Range{Start: field.Number, End: field.Number})
+		}
+	}
+	if len(res.Ranges) > 0 {
+		msg.Reserved = append(msg.Reserved, res)
+	}
+}
@@ -0,0 +1,87 @@
+package idl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProtoRoundTrip converts each testdata/proto/*.proto file to .wb.idl
+// and back, comparing every step with a golden file: <name>.wb.idl.golden
+// for the IDL, <name>.notes.golden for the conversion notes and
+// <name>.proto.golden for the proto3 written from the IDL. Converting that
+// proto3 again must give the same IDL without any notes.
+func TestProtoRoundTrip(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "proto", "*.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/proto/*.proto files")
+	}
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, ".proto")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, notes, err := ParseProto(input, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			idlOut := Format(file)
+			checkGolden(t, base+".wb.idl.golden", idlOut)
+			checkGolden(t, base+".notes.golden", formatNotes(notes))
+
+			file, err = Parse(base+".wb.idl", idlOut)
+			if err != nil {
+				t.Fatalf("converted IDL does not parse: %v\n%s", err, idlOut)
+			}
+			protoOut := FormatProto(file)
+			checkGolden(t, base+".proto.golden", protoOut)
+
+			file, notes, err = ParseProto(base+".proto.golden", protoOut)
+			if err != nil {
+				t.Fatalf("converted proto does not parse: %v\n%s", err, protoOut)
+			}
+			if len(notes) > 0 {
+				t.Errorf("converting the proto again gave notes:\n%s", formatNotes(notes))
+			}
+			if again := Format(file); !bytes.Equal(again, idlOut) {
+				t.Errorf("round trip changed the IDL:\n%s", again)
+			}
+		})
+	}
+}
+
+func formatNotes(notes []*ConvertNote) []byte {
+	var buf bytes.Buffer
+	for _, n := range notes {
+		kind := "converted"
+		if n.Dropped {
+			kind = "dropped"
+		}
+		fmt.Fprintf(&buf, "%d:%d: %s: %s\n", n.Pos.Line, n.Pos.Column, kind, n.Msg)
+	}
+	return buf.Bytes()
+}
+
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s", golden, got)
+	}
+}
@@ -0,0 +1,28 @@
+package idl
+
+var scalarTypes = map[string]bool{
+	"int32":   true,
+	"int64":   true,
+	"uint32":  true,
+	"uint64":  true,
+	"bool":    true,
+	"float":   true,
+	"float32": true,
+	"double":  true,
+	"float64": true,
+	"string":  true,
+	"bytes":   true,
+}
+
+func IsScalar(typ string) bool {
+	return scalarTypes[typ]
+}
+
+// IsMapKey reports whether typ may be used as the key of a map field.
+func IsMapKey(typ string) bool {
+	switch typ {
+	case "int32", "int64", "uint32", "uint64", "bool", "string":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package wiretest
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"runtime"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+// Message is implemented by the generated messages of this package.
+type Message interface {
+	MarshalWells() []byte
+	UnmarshalWells([]byte) error
+}
+
+// Name returns the IDL name of m, which is also its Go type name.
+func Name(m Message) string {
+	return reflect.TypeOf(m).Elem().Name()
+}
+
+// New returns an empty message of the type of m.
+func New(m Message) Message {
+	return reflect.New(reflect.TypeOf(m).Elem()).Interface().(Message)
+}
+
+// IDL returns the path of wiretest.wb.idl, for the tests of packages that
+// describe these messages by their IDL.
+func IDL() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "wiretest.wb.idl")
+}
+
+// Nested returns a message holding depth levels of nested messages, each in
+// the field with the given key of the one around it. The keys are used in
+// turn, so messages that refer to each other can be nested too.
+func Nested(depth int, keys ...byte) []byte {
+	sizes := make([]int, depth)
+	size := 0
+	for i := depth - 1; i >= 0; i-- {
+		sizes[i] = size
+		size += 1 + wellsrpc.SizeVarint(uint64(size))
+	}
+	b := make([]byte, 0, size)
+	for i, n := range sizes {
+		b = append(b, keys[i%len(keys)])
+		b = wellsrpc.AppendVarint(b, uint64(n))
+	}
+	return b
+}
+
+// Fixture is a message that every encoding is tested with.
+type Fixture struct {
+	Name string
+	Msg  Message
+	Hex  string // what protobuf writes
+}
+
+// Fixtures are shared by the tests of the wire format and of the other
+// encodings, which each hold the expected output for every fixture. Maps hold
+// a single entry, as protobuf does not fix the order of map entries.
+var Fixtures = []Fixture{
+	{
+		Name: "scalars",
+		Msg: &Scalars{
+			I32:   -3,
+			I64:   1234567890123,
+			U32:   300,
+			U64:   1 << 63,
+			F32:   1.5,
+			F64:   -0.25,
+			Flag:  true,
+			Text:  "hi",
+			Data:  []byte{0x00, 0xff},
+			Level: Level_LEVEL_HIGH,
+		},
+		Hex: "0805" + "109693d89fee47" + "18ac02" + "2080808080808080808001" + "2d0000c03f" +
+			"31000000000000d0bf" + "3801" + "42026869" + "4a0200ff" + "5002",
+	},
+	{
+		Name: "defaults are omitted",
+		Msg:  &Scalars{},
+		Hex:  "",
+	},
+	{
+		Name: "negative zero is written",
+		Msg:  &Scalars{F32: float32(math.Copysign(0, -1)), F64: math.Copysign(0, -1)},
+		Hex:  "2d00000080" + "310000000000000080",
+	},
+	{
+		Name: "multi-byte keys",
+		Msg:  &Numbers{Small: 1, TwoByte: 1, Three: 1, Largest: -1},
+		Hex:  "0802" + "800102" + "80800102" + "f8ffffff0f01",
+	},
+	{
+		Name: "composite",
+		Msg: &Composite{
+			Ids:      []int32{1, -1, 300},
+			Weights:  []float32{0.5},
+			Tags:     []string{"a", ""},
+			Counts:   map[string]int64{"x": -2},
+			Inner:    &Inner{Name: "n"},
+			Children: []*Inner{{}, {Name: "c"}},
+			Choice:   &Composite_Number{Number: 0},
+		},
+		Hex: "0a040201d804" + "12040000003f" + "1a0161" + "1a00" + "22050a01781003" +
+			"2a030a016e" + "3200" + "32030a0163" + "3800",
+	},
+	{
+		Name: "oneof default is written",
+		Msg:  &Composite{Choice: &Composite_Word{Word: ""}},
+		Hex:  "4200",
+	},
+	{
+		Name: "nested messages",
+		Msg: &Node{
+			Name:     "a",
+			Next:     &Node{Name: "b"},
+			Children: []*Node{{Next: &Node{}}},
+			Named:    map[string]*Node{"k": {Name: "v"}},
+		},
+		Hex: "0a0161" + "12030a0162" + "1a021200" + "22080a016b12030a0176",
+	},
+}
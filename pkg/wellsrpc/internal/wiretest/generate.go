@@ -0,0 +1,6 @@
+// Package wiretest checks that generated codecs read and write the
+// protobuf wire format. Its messages and fixtures are shared by the tests of
+// the dynamic, JSON and text encodings.
+package wiretest
+
+//go:generate go run ../../../../cmd/welli-codegen -idl wiretest.wb.idl -out .
@@ -0,0 +1,1455 @@
+package wiretest
+
+import (
+	"errors"
+	"fmt"
+	wellib "github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"math"
+	"strconv"
+)
+
+type Level int32
+
+const (
+	Level_LEVEL_UNSPECIFIED Level = 0
+	Level_LEVEL_LOW         Level = 1
+	Level_LEVEL_HIGH        Level = 2
+)
+
+var Level_name = map[int32]string{
+	0: "LEVEL_UNSPECIFIED",
+	1: "LEVEL_LOW",
+	2: "LEVEL_HIGH",
+}
+
+var Level_value = map[string]int32{
+	"LEVEL_UNSPECIFIED": 0,
+	"LEVEL_LOW":         1,
+	"LEVEL_HIGH":        2,
+}
+
+func (x Level) String() string {
+	if s, ok := Level_name[int32(x)]; ok {
+		return s
+	}
+	return strconv.Itoa(int(x))
+}
+
+type Scalars struct {
+	I32   int32
+	I64   int64
+	U32   uint32
+	U64   uint64
+	F32   float32
+	F64   float64
+	Flag  bool
+	Text  string
+	Data  []byte
+	Level Level
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Scalars) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Scalars) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if m.I32 != 0 {
+		n += 1 + wellib.SizeVarint(wellib.ZigzagEncode(int64(m.I32)))
+	}
+	if m.I64 != 0 {
+		n += 1 + wellib.SizeVarint(wellib.ZigzagEncode(m.I64))
+	}
+	if m.U32 != 0 {
+		n += 1 + wellib.SizeVarint(uint64(m.U32))
+	}
+	if m.U64 != 0 {
+		n += 1 + wellib.SizeVarint(m.U64)
+	}
+	if math.Float32bits(m.F32) != 0 {
+		n += 1 + 4
+	}
+	if math.Float64bits(m.F64) != 0 {
+		n += 1 + 8
+	}
+	if m.Flag {
+		n += 1 + 1
+	}
+	if len(m.Text) > 0 {
+		n += 1 + wellib.SizeBytes(len(m.Text))
+	}
+	if len(m.Data) > 0 {
+		n += 1 + wellib.SizeBytes(len(m.Data))
+	}
+	if m.Level != 0 {
+		n += 1 + wellib.SizeVarint(uint64(m.Level))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Scalars) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Scalars) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	return m.MarshalWellsCached(b, nil)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Scalars) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+
+	if m.I32 != 0 {
+		b = append(b, 0x08)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(m.I32)))
+	}
+
+	if m.I64 != 0 {
+		b = append(b, 0x10)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(m.I64))
+	}
+
+	if m.U32 != 0 {
+		b = append(b, 0x18)
+		b = wellib.AppendVarint(b, uint64(m.U32))
+	}
+
+	if m.U64 != 0 {
+		b = append(b, 0x20)
+		b = wellib.AppendVarint(b, m.U64)
+	}
+
+	if math.Float32bits(m.F32) != 0 {
+		b = append(b, 0x2D)
+		b = wellib.AppendFloat32LE(b, m.F32)
+	}
+
+	if math.Float64bits(m.F64) != 0 {
+		b = append(b, 0x31)
+		b = wellib.AppendFloat64LE(b, m.F64)
+	}
+
+	if m.Flag {
+		b = append(b, 0x38)
+		b = append(b, 1)
+	}
+
+	if len(m.Text) > 0 {
+		b = append(b, 0x42)
+		b = wellib.AppendString(b, m.Text)
+	}
+
+	if len(m.Data) > 0 {
+		b = append(b, 0x4A)
+		b = wellib.AppendBytes(b, m.Data)
+	}
+
+	if m.Level != 0 {
+		b = append(b, 0x50)
+		b = wellib.AppendVarint(b, uint64(m.Level))
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Scalars) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Scalars) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Scalars: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.i32: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.I32 = v
+		case num == 2 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.i64: %w", err)
+			}
+			i += n
+			v := wellib.ZigzagDecode(x)
+			m.I64 = v
+		case num == 3 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.u32: %w", err)
+			}
+			i += n
+			v := uint32(x)
+			m.U32 = v
+		case num == 4 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.u64: %w", err)
+			}
+			i += n
+			v := x
+			m.U64 = v
+		case num == 5 && wireType == wellib.WireFixed32:
+			if i+4 > len(b) {
+				return errors.New("Scalars.f32: truncated")
+			}
+			v := wellib.ReadFloat32LE(b[i : i+4])
+			i += 4
+			m.F32 = v
+		case num == 6 && wireType == wellib.WireFixed64:
+			if i+8 > len(b) {
+				return errors.New("Scalars.f64: truncated")
+			}
+			v := wellib.ReadFloat64LE(b[i : i+8])
+			i += 8
+			m.F64 = v
+		case num == 7 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.flag: %w", err)
+			}
+			i += n
+			v := x != 0
+			m.Flag = v
+		case num == 8 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.text: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Scalars.text: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Text = v
+		case num == 9 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.data: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Scalars.data: truncated")
+			}
+			v := append([]byte(nil), b[i:i+int(l)]...)
+			i += int(l)
+			m.Data = v
+		case num == 10 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Scalars.level: %w", err)
+			}
+			i += n
+			v := Level(int32(x))
+			m.Level = v
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Numbers struct {
+	Small   int32
+	TwoByte int32
+	Three   int32
+	Largest int32
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Numbers) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Numbers) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if m.Small != 0 {
+		n += 1 + wellib.SizeVarint(wellib.ZigzagEncode(int64(m.Small)))
+	}
+	if m.TwoByte != 0 {
+		n += 2 + wellib.SizeVarint(wellib.ZigzagEncode(int64(m.TwoByte)))
+	}
+	if m.Three != 0 {
+		n += 3 + wellib.SizeVarint(wellib.ZigzagEncode(int64(m.Three)))
+	}
+	if m.Largest != 0 {
+		n += 5 + wellib.SizeVarint(wellib.ZigzagEncode(int64(m.Largest)))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Numbers) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Numbers) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	return m.MarshalWellsCached(b, nil)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Numbers) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+
+	if m.Small != 0 {
+		b = append(b, 0x08)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(m.Small)))
+	}
+
+	if m.TwoByte != 0 {
+		b = append(b, 0x80, 0x01)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(m.TwoByte)))
+	}
+
+	if m.Three != 0 {
+		b = append(b, 0x80, 0x80, 0x01)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(m.Three)))
+	}
+
+	if m.Largest != 0 {
+		b = append(b, 0xF8, 0xFF, 0xFF, 0xFF, 0x0F)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(m.Largest)))
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Numbers) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Numbers) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Numbers: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Numbers.small: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.Small = v
+		case num == 16 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Numbers.two_byte: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.TwoByte = v
+		case num == 2048 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Numbers.three: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.Three = v
+		case num == 536870911 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Numbers.largest: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.Largest = v
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Inner struct {
+	Name string
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Inner) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Inner) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if len(m.Name) > 0 {
+		n += 1 + wellib.SizeBytes(len(m.Name))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Inner) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.MarshalWellsCached(make([]byte, 0, m.SizeWells()), nil)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Inner) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	return m.MarshalWellsCached(b, nil)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Inner) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+
+	if len(m.Name) > 0 {
+		b = append(b, 0x0A)
+		b = wellib.AppendString(b, m.Name)
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Inner) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Inner) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Inner: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Inner.name: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Inner.name: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Name = v
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Composite struct {
+	Ids      []int32
+	Weights  []float32
+	Tags     []string
+	Counts   map[string]int64
+	Inner    *Inner
+	Children []*Inner
+	Choice   isComposite_Choice
+
+	unknownFields []byte
+}
+
+type isComposite_Choice interface {
+	isComposite_Choice()
+}
+
+type Composite_Number struct {
+	Number int32
+}
+
+func (*Composite_Number) isComposite_Choice() {}
+
+type Composite_Word struct {
+	Word string
+}
+
+func (*Composite_Word) isComposite_Choice() {}
+
+func (m *Composite) GetNumber() int32 {
+	if m == nil {
+		return 0
+	}
+	if x, ok := m.Choice.(*Composite_Number); ok {
+		return x.Number
+	}
+	return 0
+}
+
+func (m *Composite) GetWord() string {
+	if m == nil {
+		return ""
+	}
+	if x, ok := m.Choice.(*Composite_Word); ok {
+		return x.Word
+	}
+	return ""
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Composite) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Composite) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if len(m.Ids) > 0 {
+		size := 0
+		for _, v := range m.Ids {
+			size += wellib.SizeVarint(wellib.ZigzagEncode(int64(v)))
+		}
+		n += 1 + wellib.SizeBytes(size)
+	}
+	if len(m.Weights) > 0 {
+		size := len(m.Weights) * 4
+		n += 1 + wellib.SizeBytes(size)
+	}
+	for _, v := range m.Tags {
+		n += 1 + wellib.SizeBytes(len(v))
+	}
+	for k, v := range m.Counts {
+		n += 1 + wellib.SizeBytes(1+wellib.SizeBytes(len(k))+1+wellib.SizeVarint(wellib.ZigzagEncode(v)))
+	}
+	if m.Inner != nil {
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), m.Inner.CacheSizeWells(c)))
+	}
+	for _, v := range m.Children {
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), v.CacheSizeWells(c)))
+	}
+	switch x := m.Choice.(type) {
+	case *Composite_Number:
+		n += 1 + wellib.SizeVarint(wellib.ZigzagEncode(int64(x.Number)))
+	case *Composite_Word:
+		n += 1 + wellib.SizeBytes(len(x.Word))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Composite) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	var c wellib.SizeCache
+	return m.MarshalWellsCached(make([]byte, 0, m.CacheSizeWells(&c)), &c)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Composite) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	var c wellib.SizeCache
+	m.CacheSizeWells(&c)
+	return m.MarshalWellsCached(b, &c)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Composite) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+	if c == nil {
+		return m.MarshalWellsAppend(b)
+	}
+
+	if len(m.Ids) > 0 {
+		size := 0
+		for _, v := range m.Ids {
+			size += wellib.SizeVarint(wellib.ZigzagEncode(int64(v)))
+		}
+		b = append(b, 0x0A)
+		b = wellib.AppendVarint(b, uint64(size))
+		for _, v := range m.Ids {
+			b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(v)))
+		}
+	}
+
+	if len(m.Weights) > 0 {
+		size := len(m.Weights) * 4
+		b = append(b, 0x12)
+		b = wellib.AppendVarint(b, uint64(size))
+		for _, v := range m.Weights {
+			b = wellib.AppendFloat32LE(b, v)
+		}
+	}
+
+	for _, v := range m.Tags {
+		b = append(b, 0x1A)
+		b = wellib.AppendString(b, v)
+	}
+
+	for k, v := range m.Counts {
+		size := 1 + wellib.SizeBytes(len(k)) + 1 + wellib.SizeVarint(wellib.ZigzagEncode(v))
+		b = append(b, 0x22)
+		b = wellib.AppendVarint(b, uint64(size))
+		b = append(b, 0x0A)
+		b = wellib.AppendString(b, k)
+		b = append(b, 0x10)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(v))
+	}
+
+	if m.Inner != nil {
+		b = append(b, 0x2A)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = m.Inner.MarshalWellsCached(b, c)
+	}
+
+	for _, v := range m.Children {
+		b = append(b, 0x32)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = v.MarshalWellsCached(b, c)
+	}
+
+	switch x := m.Choice.(type) {
+	case *Composite_Number:
+		b = append(b, 0x38)
+		b = wellib.AppendVarint(b, wellib.ZigzagEncode(int64(x.Number)))
+	case *Composite_Word:
+		b = append(b, 0x42)
+		b = wellib.AppendString(b, x.Word)
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Composite) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Composite) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Composite: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.ids: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.ids: truncated")
+			}
+			end := i + int(l)
+			for i < end {
+				x, n, err := wellib.ReadVarint(b[i:])
+				if err != nil {
+					return fmt.Errorf("Composite.ids: %w", err)
+				}
+				i += n
+				v := int32(wellib.ZigzagDecode(x))
+				m.Ids = append(m.Ids, v)
+			}
+			if i != end {
+				return errors.New("Composite.ids: malformed packed data")
+			}
+		case num == 1 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.ids: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.Ids = append(m.Ids, v)
+		case num == 2 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.weights: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.weights: truncated")
+			}
+			end := i + int(l)
+			for i < end {
+				if i+4 > len(b) {
+					return errors.New("Composite.weights: truncated")
+				}
+				v := wellib.ReadFloat32LE(b[i : i+4])
+				i += 4
+				m.Weights = append(m.Weights, v)
+			}
+			if i != end {
+				return errors.New("Composite.weights: malformed packed data")
+			}
+		case num == 2 && wireType == wellib.WireFixed32:
+			if i+4 > len(b) {
+				return errors.New("Composite.weights: truncated")
+			}
+			v := wellib.ReadFloat32LE(b[i : i+4])
+			i += 4
+			m.Weights = append(m.Weights, v)
+		case num == 3 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.tags: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.tags: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Tags = append(m.Tags, v)
+		case num == 4 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.counts: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.counts: truncated")
+			}
+			if m.Counts == nil {
+				m.Counts = make(map[string]int64)
+			}
+			end := i + int(l)
+			var mk string
+			var mv int64
+			for i < end {
+				en, et, n := wellib.ReadTag(b[i:])
+				if n == 0 {
+					return errors.New("Composite.counts: invalid entry key")
+				}
+				i += n
+				switch {
+				case en == 1 && et == wellib.WireBytes:
+					l, n, err := wellib.ReadVarint(b[i:])
+					if err != nil {
+						return fmt.Errorf("Composite.counts: length: %w", err)
+					}
+					i += n
+					if uint64(len(b)-i) < l {
+						return errors.New("Composite.counts: truncated")
+					}
+					v := string(b[i : i+int(l)])
+					i += int(l)
+					mk = v
+				case en == 2 && et == wellib.WireVarint:
+					x, n, err := wellib.ReadVarint(b[i:])
+					if err != nil {
+						return fmt.Errorf("Composite.counts: %w", err)
+					}
+					i += n
+					v := wellib.ZigzagDecode(x)
+					mv = v
+				default:
+					n, err := wellib.SkipField(b[i:], et)
+					if err != nil {
+						return err
+					}
+					i += n
+				}
+			}
+			if i != end {
+				return errors.New("Composite.counts: malformed map entry")
+			}
+			m.Counts[mk] = mv
+		case num == 5 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.inner: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.inner: truncated")
+			}
+			if m.Inner == nil {
+				m.Inner = &Inner{}
+			}
+			if err := m.Inner.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+		case num == 6 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.children: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.children: truncated")
+			}
+			v := &Inner{}
+			if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+			m.Children = append(m.Children, v)
+		case num == 7 && wireType == wellib.WireVarint:
+			x, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.number: %w", err)
+			}
+			i += n
+			v := int32(wellib.ZigzagDecode(x))
+			m.Choice = &Composite_Number{Number: v}
+		case num == 8 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Composite.word: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Composite.word: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Choice = &Composite_Word{Word: v}
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Node struct {
+	Name     string
+	Next     *Node
+	Children []*Node
+	Named    map[string]*Node
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Node) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Node) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	if len(m.Name) > 0 {
+		n += 1 + wellib.SizeBytes(len(m.Name))
+	}
+	if m.Next != nil {
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), m.Next.CacheSizeWells(c)))
+	}
+	for _, v := range m.Children {
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), v.CacheSizeWells(c)))
+	}
+	for k, v := range m.Named {
+		n += 1 + wellib.SizeBytes(1+wellib.SizeBytes(len(k))+1+wellib.SizeBytes(v.SizeWells()))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Node) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	var c wellib.SizeCache
+	return m.MarshalWellsCached(make([]byte, 0, m.CacheSizeWells(&c)), &c)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Node) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	var c wellib.SizeCache
+	m.CacheSizeWells(&c)
+	return m.MarshalWellsCached(b, &c)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Node) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+	if c == nil {
+		return m.MarshalWellsAppend(b)
+	}
+
+	if len(m.Name) > 0 {
+		b = append(b, 0x0A)
+		b = wellib.AppendString(b, m.Name)
+	}
+
+	if m.Next != nil {
+		b = append(b, 0x12)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = m.Next.MarshalWellsCached(b, c)
+	}
+
+	for _, v := range m.Children {
+		b = append(b, 0x1A)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = v.MarshalWellsCached(b, c)
+	}
+
+	var vc wellib.SizeCache
+	for k, v := range m.Named {
+		vc.Reset()
+		vs := v.CacheSizeWells(&vc)
+		size := 1 + wellib.SizeBytes(len(k)) + 1 + wellib.SizeBytes(vs)
+		b = append(b, 0x22)
+		b = wellib.AppendVarint(b, uint64(size))
+		b = append(b, 0x0A)
+		b = wellib.AppendString(b, k)
+		b = append(b, 0x12)
+		b = wellib.AppendVarint(b, uint64(vs))
+		b = v.MarshalWellsCached(b, &vc)
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Node) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Node) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Node: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Node.name: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Node.name: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Name = v
+		case num == 2 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Node.next: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Node.next: truncated")
+			}
+			if m.Next == nil {
+				m.Next = &Node{}
+			}
+			if err := m.Next.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+		case num == 3 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Node.children: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Node.children: truncated")
+			}
+			v := &Node{}
+			if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+			m.Children = append(m.Children, v)
+		case num == 4 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Node.named: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Node.named: truncated")
+			}
+			if m.Named == nil {
+				m.Named = make(map[string]*Node)
+			}
+			end := i + int(l)
+			var mk string
+			var mv *Node
+			for i < end {
+				en, et, n := wellib.ReadTag(b[i:])
+				if n == 0 {
+					return errors.New("Node.named: invalid entry key")
+				}
+				i += n
+				switch {
+				case en == 1 && et == wellib.WireBytes:
+					l, n, err := wellib.ReadVarint(b[i:])
+					if err != nil {
+						return fmt.Errorf("Node.named: length: %w", err)
+					}
+					i += n
+					if uint64(len(b)-i) < l {
+						return errors.New("Node.named: truncated")
+					}
+					v := string(b[i : i+int(l)])
+					i += int(l)
+					mk = v
+				case en == 2 && et == wellib.WireBytes:
+					l, n, err := wellib.ReadVarint(b[i:])
+					if err != nil {
+						return fmt.Errorf("Node.named: length: %w", err)
+					}
+					i += n
+					if uint64(len(b)-i) < l {
+						return errors.New("Node.named: truncated")
+					}
+					v := &Node{}
+					if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+						return err
+					}
+					i += int(l)
+					mv = v
+				default:
+					n, err := wellib.SkipField(b[i:], et)
+					if err != nil {
+						return err
+					}
+					i += n
+				}
+			}
+			if i != end {
+				return errors.New("Node.named: malformed map entry")
+			}
+			if mv == nil {
+				mv = &Node{}
+			}
+			m.Named[mk] = mv
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Tree struct {
+	Kind isTree_Kind
+
+	unknownFields []byte
+}
+
+type isTree_Kind interface {
+	isTree_Kind()
+}
+
+type Tree_Leaf struct {
+	Leaf string
+}
+
+func (*Tree_Leaf) isTree_Kind() {}
+
+type Tree_Branch struct {
+	Branch *Branch
+}
+
+func (*Tree_Branch) isTree_Kind() {}
+
+func (m *Tree) GetLeaf() string {
+	if m == nil {
+		return ""
+	}
+	if x, ok := m.Kind.(*Tree_Leaf); ok {
+		return x.Leaf
+	}
+	return ""
+}
+
+func (m *Tree) GetBranch() *Branch {
+	if m == nil {
+		return nil
+	}
+	if x, ok := m.Kind.(*Tree_Branch); ok {
+		return x.Branch
+	}
+	return nil
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Tree) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Tree) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	switch x := m.Kind.(type) {
+	case *Tree_Leaf:
+		n += 1 + wellib.SizeBytes(len(x.Leaf))
+	case *Tree_Branch:
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), x.Branch.CacheSizeWells(c)))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Tree) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	var c wellib.SizeCache
+	return m.MarshalWellsCached(make([]byte, 0, m.CacheSizeWells(&c)), &c)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Tree) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	var c wellib.SizeCache
+	m.CacheSizeWells(&c)
+	return m.MarshalWellsCached(b, &c)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Tree) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+	if c == nil {
+		return m.MarshalWellsAppend(b)
+	}
+
+	switch x := m.Kind.(type) {
+	case *Tree_Leaf:
+		b = append(b, 0x0A)
+		b = wellib.AppendString(b, x.Leaf)
+	case *Tree_Branch:
+		b = append(b, 0x12)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = x.Branch.MarshalWellsCached(b, c)
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Tree) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Tree) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Tree: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Tree.leaf: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Tree.leaf: truncated")
+			}
+			v := string(b[i : i+int(l)])
+			i += int(l)
+			m.Kind = &Tree_Leaf{Leaf: v}
+		case num == 2 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Tree.branch: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Tree.branch: truncated")
+			}
+			v := &Branch{}
+			if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+			m.Kind = &Tree_Branch{Branch: v}
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
+
+type Branch struct {
+	Trees []*Tree
+
+	unknownFields []byte
+}
+
+// SizeWells returns the size of the encoding of m.
+func (m *Branch) SizeWells() int {
+	return m.CacheSizeWells(nil)
+}
+
+// CacheSizeWells is SizeWells that also records the sizes of the nested
+// messages in c for MarshalWellsCached.
+func (m *Branch) CacheSizeWells(c *wellib.SizeCache) int {
+	if m == nil {
+		return 0
+	}
+	n := 0
+	for _, v := range m.Trees {
+		n += 1 + wellib.SizeBytes(c.Set(c.Reserve(), v.CacheSizeWells(c)))
+	}
+	n += len(m.unknownFields)
+	return n
+}
+
+func (m *Branch) MarshalWells() []byte {
+	if m == nil {
+		return nil
+	}
+	var c wellib.SizeCache
+	return m.MarshalWellsCached(make([]byte, 0, m.CacheSizeWells(&c)), &c)
+}
+
+// MarshalWellsAppend appends the encoding of m to b and returns the
+// extended buffer.
+func (m *Branch) MarshalWellsAppend(b []byte) []byte {
+	if m == nil {
+		return b
+	}
+	var c wellib.SizeCache
+	m.CacheSizeWells(&c)
+	return m.MarshalWellsCached(b, &c)
+}
+
+// MarshalWellsCached is MarshalWellsAppend with the sizes of the nested
+// messages taken from c, which CacheSizeWells filled. A nil c is the same as
+// MarshalWellsAppend.
+func (m *Branch) MarshalWellsCached(b []byte, c *wellib.SizeCache) []byte {
+	if m == nil {
+		return b
+	}
+	if c == nil {
+		return m.MarshalWellsAppend(b)
+	}
+
+	for _, v := range m.Trees {
+		b = append(b, 0x0A)
+		b = wellib.AppendVarint(b, uint64(c.Next()))
+		b = v.MarshalWellsCached(b, c)
+	}
+
+	b = append(b, m.unknownFields...)
+
+	return b
+}
+
+func (m *Branch) UnmarshalWells(b []byte) error {
+	return m.UnmarshalWellsDepth(b, wellib.MaxDepth)
+}
+
+// UnmarshalWellsDepth is UnmarshalWells for a message that may hold depth
+// more levels of nested messages.
+func (m *Branch) UnmarshalWellsDepth(b []byte, depth int) error {
+	if depth < 0 {
+		return wellib.ErrMaxDepth
+	}
+	var i int
+	for i < len(b) {
+		start := i
+		num, wireType, n := wellib.ReadTag(b[i:])
+		if n == 0 {
+			return errors.New("Branch: invalid field key")
+		}
+		i += n
+		switch {
+		case num == 1 && wireType == wellib.WireBytes:
+			l, n, err := wellib.ReadVarint(b[i:])
+			if err != nil {
+				return fmt.Errorf("Branch.trees: length: %w", err)
+			}
+			i += n
+			if uint64(len(b)-i) < l {
+				return errors.New("Branch.trees: truncated")
+			}
+			v := &Tree{}
+			if err := v.UnmarshalWellsDepth(b[i:i+int(l)], depth-1); err != nil {
+				return err
+			}
+			i += int(l)
+			m.Trees = append(m.Trees, v)
+		default:
+			n, err := wellib.SkipField(b[i:], wireType)
+			if err != nil {
+				return err
+			}
+			i += n
+			m.unknownFields = append(m.unknownFields, b[start:i]...)
+		}
+	}
+	return nil
+}
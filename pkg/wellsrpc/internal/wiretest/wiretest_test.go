@@ -0,0 +1,420 @@
+package wiretest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+)
+
+type sizer interface {
+	SizeWells() int
+	MarshalWellsAppend([]byte) []byte
+	MarshalWellsCached([]byte, *wellsrpc.SizeCache) []byte
+}
+
+func TestGoldenMarshal(t *testing.T) {
+	for _, f := range Fixtures {
+		if got := hex.EncodeToString(f.Msg.MarshalWells()); got != f.Hex {
+			t.Errorf("%s: MarshalWells() = %s, want %s", f.Name, got, f.Hex)
+		}
+	}
+}
+
+func TestGoldenUnmarshal(t *testing.T) {
+	for _, f := range Fixtures {
+		b, _ := hex.DecodeString(f.Hex)
+		got := New(f.Msg)
+		if err := got.UnmarshalWells(b); err != nil {
+			t.Errorf("%s: UnmarshalWells: %v", f.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, f.Msg) {
+			t.Errorf("%s: UnmarshalWells() = %+v, want %+v", f.Name, got, f.Msg)
+		}
+	}
+
+	var s Scalars
+	b, _ := hex.DecodeString("2d00000080310000000000000080")
+	if err := s.UnmarshalWells(b); err != nil {
+		t.Fatal(err)
+	}
+	if !math.Signbit(float64(s.F32)) || !math.Signbit(s.F64) {
+		t.Errorf("negative zero decoded as %v, %v", s.F32, s.F64)
+	}
+}
+
+func TestUnpackedRepeated(t *testing.T) {
+	// Writers may send packable fields one value per key.
+	b, _ := hex.DecodeString("0802" + "0801" + "15ffffff7f" + "15000080ff")
+	var got Composite
+	if err := got.UnmarshalWells(b); err != nil {
+		t.Fatal(err)
+	}
+	want := []int32{1, -1}
+	if !reflect.DeepEqual(got.Ids, want) {
+		t.Errorf("Ids = %v, want %v", got.Ids, want)
+	}
+	if len(got.Weights) != 2 || !math.IsNaN(float64(got.Weights[0])) || !math.IsInf(float64(got.Weights[1]), -1) {
+		t.Errorf("Weights = %v, want [NaN -Inf]", got.Weights)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	unknown := "a0069601" + // 100: varint
+		"a9060102030405060708" + // 101: fixed64
+		"b206026162" + // 102: bytes
+		"bd0601020304" + // 103: fixed32
+		"837d" + "0801" + "1b" + "1001" + "1c" + "847d" // 2000: group holding a nested group
+	b, _ := hex.DecodeString(unknown[:8] + "0a016e" + unknown[8:])
+	var got Inner
+	if err := got.UnmarshalWells(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "n" {
+		t.Errorf("Name = %q, want %q", got.Name, "n")
+	}
+
+	// Unknown fields are kept in the order they came in and written after
+	// the known ones.
+	want := "0a016e" + unknown
+	if enc := hex.EncodeToString(got.MarshalWells()); enc != want {
+		t.Errorf("MarshalWells() = %s, want %s", enc, want)
+	}
+	if size := got.SizeWells(); size != len(want)/2 {
+		t.Errorf("SizeWells() = %d, want %d", size, len(want)/2)
+	}
+
+	// A message nested in one that is re-encoded keeps its unknown fields.
+	outer := append([]byte{0x2a, byte(len(b))}, b...)
+	var c Composite
+	if err := c.UnmarshalWells(outer); err != nil {
+		t.Fatal(err)
+	}
+	if enc := hex.EncodeToString(c.MarshalWells()); enc != "2a"+hex.EncodeToString([]byte{byte(len(b))})+want {
+		t.Errorf("Composite.MarshalWells() = %s, want inner %s", enc, want)
+	}
+}
+
+func TestSkipMalformedGroup(t *testing.T) {
+	for name, in := range map[string]string{
+		"missing end":         "837d0801",
+		"end without start":   "847d",
+		"mismatched nested":   "837d1b24847d",
+		"truncated in group":  "837d08",
+		"invalid wire type 7": "837d0f",
+	} {
+		b, _ := hex.DecodeString(in)
+		var got Inner
+		if err := got.UnmarshalWells(b); err == nil {
+			t.Errorf("%s: UnmarshalWells(%s) succeeded, want an error", name, in)
+		}
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	for name, in := range map[string]string{
+		"field zero":    "0001",
+		"field too big": "8080808010" + "01",
+		"truncated key": "80",
+	} {
+		b, _ := hex.DecodeString(in)
+		var got Numbers
+		if err := got.UnmarshalWells(b); err == nil {
+			t.Errorf("%s: UnmarshalWells(%s) succeeded, want an error", name, in)
+		}
+	}
+}
+
+func TestVarintErrors(t *testing.T) {
+	for _, tt := range []struct {
+		msg Message
+		in  string
+		err error
+	}{
+		{&Scalars{}, "08", wellsrpc.ErrTruncated},
+		{&Scalars{}, "08ffffffffffffffffff02", wellsrpc.ErrOverflow},
+		{&Scalars{}, "4aff", wellsrpc.ErrTruncated},
+		{&Scalars{}, "4affffffffffffffffffff01", wellsrpc.ErrOverflow},
+		{&Composite{}, "0a0180", wellsrpc.ErrTruncated},
+		{&Composite{}, "2202" + "1080", wellsrpc.ErrTruncated},
+		{&Composite{}, "2a02" + "0a80", wellsrpc.ErrTruncated},
+	} {
+		b, _ := hex.DecodeString(tt.in)
+		if err := New(tt.msg).UnmarshalWells(b); !errors.Is(err, tt.err) {
+			t.Errorf("%s: UnmarshalWells(%s) = %v, want %v", Name(tt.msg), tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := &Composite{
+		Counts: map[string]int64{"a": 1, "b": 0, "": -1},
+		Choice: &Composite_Word{Word: "w"},
+	}
+	var got Composite
+	if err := got.UnmarshalWells(want.MarshalWells()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, want) {
+		t.Errorf("round trip = %+v, want %+v", &got, want)
+	}
+	if b := (&Inner{}).MarshalWells(); len(b) != 0 {
+		t.Errorf("empty message marshals to %x", b)
+	}
+}
+
+// TestDeepNesting marshals a chain of messages deeper than the sizes a
+// SizeCache holds inline, with messages in repeated fields and map values at
+// every level.
+func TestDeepNesting(t *testing.T) {
+	var root *Node
+	for i := 0; i < 40; i++ {
+		root = &Node{
+			Name:     strconv.Itoa(i),
+			Next:     root,
+			Children: []*Node{{Name: "c", Next: &Node{}}, {}},
+			Named:    map[string]*Node{"m": {Next: &Node{Name: "v"}}},
+		}
+	}
+	b := root.MarshalWells()
+	if len(b) != root.SizeWells() {
+		t.Fatalf("MarshalWells() wrote %d bytes, SizeWells() = %d", len(b), root.SizeWells())
+	}
+	if got := root.MarshalWellsAppend([]byte("prefix")); string(got) != "prefix"+string(b) {
+		t.Errorf("MarshalWellsAppend() = %x, want %x", got, b)
+	}
+	var got Node
+	if err := got.UnmarshalWells(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, root) {
+		t.Error("deeply nested message did not survive a round trip")
+	}
+}
+
+// TestMaxDepth decodes Node.next chains up to and past wellsrpc.MaxDepth.
+// Deeper input is rejected instead of overflowing the stack, however deep it
+// is.
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		err   error
+	}{
+		{wellsrpc.MaxDepth, nil},
+		{wellsrpc.MaxDepth + 1, wellsrpc.ErrMaxDepth},
+		{3000000, wellsrpc.ErrMaxDepth},
+	}
+	for _, tt := range tests {
+		var m Node
+		if err := m.UnmarshalWells(Nested(tt.depth, 0x12)); !errors.Is(err, tt.err) {
+			t.Errorf("depth %d: UnmarshalWells() = %v, want %v", tt.depth, err, tt.err)
+		}
+	}
+}
+
+// TestMaxDepthRecursive checks the limit for a message that refers to itself
+// through each kind of field, and for messages that refer to each other.
+func TestMaxDepthRecursive(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   Message
+		keys  []byte
+		depth int // levels of keys holding wellsrpc.MaxDepth messages
+	}{
+		{"Node.next", &Node{}, []byte{0x12}, wellsrpc.MaxDepth},
+		{"Node.children", &Node{}, []byte{0x1a}, wellsrpc.MaxDepth},
+		// Map entries are not messages of their own, so each level of
+		// nesting is an entry and the Node in its value.
+		{"Node.named", &Node{}, []byte{0x22, 0x12}, 2 * wellsrpc.MaxDepth},
+		{"Tree.branch and Branch.trees", &Tree{}, []byte{0x12, 0x0a}, wellsrpc.MaxDepth},
+	}
+	for _, tt := range tests {
+		if err := New(tt.msg).UnmarshalWells(Nested(tt.depth, tt.keys...)); err != nil {
+			t.Errorf("%s: %d levels: %v", tt.name, wellsrpc.MaxDepth, err)
+		}
+		deeper := Nested(tt.depth+len(tt.keys), tt.keys...)
+		if err := New(tt.msg).UnmarshalWells(deeper); !errors.Is(err, wellsrpc.ErrMaxDepth) {
+			t.Errorf("%s: %d levels: %v, want ErrMaxDepth", tt.name, wellsrpc.MaxDepth+1, err)
+		}
+	}
+}
+
+// FuzzUnmarshal feeds arbitrary bytes to the generated decoders, which must
+// reject them or decode to a message that survives another round trip. The
+// seeds include messages nested past wellsrpc.MaxDepth.
+func FuzzUnmarshal(f *testing.F) {
+	for _, fx := range Fixtures {
+		b, _ := hex.DecodeString(fx.Hex)
+		f.Add(b)
+	}
+	f.Add([]byte{0x83, 0x7d, 0x08, 0x01, 0x1b, 0x10, 0x01, 0x1c, 0x84, 0x7d})
+	f.Add([]byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Add(Nested(wellsrpc.MaxDepth+1, 0x12))
+	f.Add(Nested(2*wellsrpc.MaxDepth, 0x1a, 0x22, 0x12))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, m := range []Message{&Scalars{}, &Numbers{}, &Composite{}, &Node{}} {
+			if err := m.UnmarshalWells(b); err != nil {
+				continue
+			}
+			enc := m.MarshalWells()
+			again := New(m)
+			if err := again.UnmarshalWells(enc); err != nil {
+				t.Fatalf("%T: decoding %x failed after re-encoding %x: %v", m, enc, b, err)
+			}
+			// Map entries are written in random order, so only messages
+			// without maps must re-encode to the same bytes.
+			switch again := again.(type) {
+			case *Composite:
+				if len(again.Counts) > 1 {
+					continue
+				}
+			case *Node:
+				continue
+			}
+			if enc2 := again.MarshalWells(); !bytes.Equal(enc, enc2) {
+				t.Fatalf("%T: re-encoding %x gave %x, then %x", m, b, enc, enc2)
+			}
+		}
+	})
+}
+
+func TestSizeWells(t *testing.T) {
+	for _, f := range Fixtures {
+		if got, want := f.Msg.(sizer).SizeWells(), len(f.Hex)/2; got != want {
+			t.Errorf("%s: SizeWells() = %d, want %d", f.Name, got, want)
+		}
+	}
+}
+
+func TestMarshalWellsAppend(t *testing.T) {
+	for _, f := range Fixtures {
+		m := f.Msg.(sizer)
+		got := m.MarshalWellsAppend([]byte("prefix"))
+		if want := "prefix" + string(f.Msg.MarshalWells()); string(got) != want {
+			t.Errorf("%s: MarshalWellsAppend() = %x, want %x", f.Name, got, want)
+		}
+		buf := make([]byte, 0, m.SizeWells())
+		if allocs := testing.AllocsPerRun(100, func() { buf = m.MarshalWellsAppend(buf[:0]) }); allocs != 0 {
+			t.Errorf("%s: MarshalWellsAppend made %v allocations, want 0", f.Name, allocs)
+		}
+	}
+}
+
+func TestEnumString(t *testing.T) {
+	values := []Level{Level_LEVEL_UNSPECIFIED, Level_LEVEL_LOW, Level_LEVEL_HIGH}
+	if len(Level_name) != len(values) || len(Level_value) != len(values) {
+		t.Fatalf("Level_name has %d entries and Level_value %d, want %d", len(Level_name), len(Level_value), len(values))
+	}
+	for _, v := range values {
+		name := v.String()
+		if Level_name[int32(v)] != name || Level_value[name] != int32(v) {
+			t.Errorf("%d: String() = %q, Level_name = %q, Level_value[%q] = %d", v, name, Level_name[int32(v)], name, Level_value[name])
+		}
+	}
+	if got := Level_LEVEL_HIGH.String(); got != "LEVEL_HIGH" {
+		t.Errorf("String() = %q, want LEVEL_HIGH", got)
+	}
+	if got := Level(-7).String(); got != "-7" {
+		t.Errorf("String() of an unknown value = %q, want -7", got)
+	}
+}
+
+// MarshalWellsCached without a cache sizes the nested messages itself.
+func TestMarshalWellsCachedNil(t *testing.T) {
+	for _, f := range Fixtures {
+		got := f.Msg.(sizer).MarshalWellsCached(nil, nil)
+		if want := f.Msg.MarshalWells(); !bytes.Equal(got, want) {
+			t.Errorf("%s: MarshalWellsCached(nil, nil) = %x, want %x", f.Name, got, want)
+		}
+	}
+}
+
+// Tagged structs mirroring the generated messages, for the reflection codec.
+// Signed integers need no option to match the zigzag encoding of IDL int32
+// and int64; an enum needs varint.
+type taggedScalars struct {
+	I32   int32   `wells:"1"`
+	I64   int64   `wells:"2"`
+	U32   uint32  `wells:"3"`
+	U64   uint64  `wells:"4"`
+	F32   float32 `wells:"5"`
+	F64   float64 `wells:"6"`
+	Flag  bool    `wells:"7"`
+	Text  string  `wells:"8"`
+	Data  []byte  `wells:"9"`
+	Level Level   `wells:"10,varint"`
+}
+
+type taggedInner struct {
+	Name string `wells:"1"`
+}
+
+type taggedComposite struct {
+	Ids      []int32          `wells:"1"`
+	Weights  []float32        `wells:"2"`
+	Tags     []string         `wells:"3"`
+	Counts   map[string]int64 `wells:"4"`
+	Inner    *Inner           `wells:"5"`
+	Children []taggedInner    `wells:"6"`
+}
+
+// wellsrpc.MarshalStruct writes the golden bytes for tagged structs, and the
+// generated messages read them.
+func TestTaggedStruct(t *testing.T) {
+	for _, tt := range []struct {
+		tagged any
+		msg    Message
+		hex    string
+	}{
+		{
+			&taggedScalars{I32: -3, I64: 1234567890123, U32: 300, U64: 1 << 63, F32: 1.5, F64: -0.25,
+				Flag: true, Text: "hi", Data: []byte{0x00, 0xff}, Level: Level_LEVEL_HIGH},
+			Fixtures[0].Msg,
+			Fixtures[0].Hex,
+		},
+		{
+			&taggedComposite{
+				Ids:      []int32{1, -1, 300},
+				Weights:  []float32{0.5},
+				Tags:     []string{"a", ""},
+				Counts:   map[string]int64{"x": -2},
+				Inner:    &Inner{Name: "n"},
+				Children: []taggedInner{{}, {Name: "c"}},
+			},
+			&Composite{
+				Ids:      []int32{1, -1, 300},
+				Weights:  []float32{0.5},
+				Tags:     []string{"a", ""},
+				Counts:   map[string]int64{"x": -2},
+				Inner:    &Inner{Name: "n"},
+				Children: []*Inner{{}, {Name: "c"}},
+			},
+			// The composite golden without its oneof.
+			"0a040201d804" + "12040000003f" + "1a0161" + "1a00" + "22050a01781003" +
+				"2a030a016e" + "3200" + "32030a0163",
+		},
+	} {
+		b, err := wellsrpc.MarshalStruct(tt.tagged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(b); got != tt.hex {
+			t.Errorf("MarshalStruct(%T) = %s, want %s", tt.tagged, got, tt.hex)
+		}
+
+		got := New(tt.msg)
+		if err := got.UnmarshalWells(b); err != nil || !reflect.DeepEqual(got, tt.msg) {
+			t.Errorf("UnmarshalWells(%s) = %+v, %v, want %+v", tt.hex, got, err, tt.msg)
+		}
+		back := reflect.New(reflect.TypeOf(tt.tagged).Elem()).Interface()
+		if err := wellsrpc.UnmarshalStruct(back, tt.msg.MarshalWells()); err != nil || !reflect.DeepEqual(back, tt.tagged) {
+			t.Errorf("UnmarshalStruct(%T) = %+v, %v, want %+v", back, back, err, tt.tagged)
+		}
+	}
+}
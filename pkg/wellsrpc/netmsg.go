@@ -15,13 +15,22 @@ const (
 )
 
 func SendMessage(conn net.Conn, msg WelliMarshaller) error {
-	data := msg.MarshalWells()
-	header := [4]byte{}
-	binary.LittleEndian.PutUint32(header[:], uint32(len(data)))
-
 	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
 		return err
 	}
+	if am, ok := msg.(AppendMarshaller); ok {
+		bufp := GetBuffer()
+		defer PutBuffer(bufp)
+		buf := am.MarshalWellsAppend(append(*bufp, 0, 0, 0, 0))
+		*bufp = buf
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(buf)-4))
+		_, err := conn.Write(buf)
+		return err
+	}
+
+	data := msg.MarshalWells()
+	header := [4]byte{}
+	binary.LittleEndian.PutUint32(header[:], uint32(len(data)))
 	if _, err := conn.Write(header[:]); err != nil {
 		return err
 	}
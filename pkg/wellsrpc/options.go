@@ -0,0 +1,41 @@
+package wellsrpc
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// defaultCallTimeout bounds a Call whose context has no deadline.
+const defaultCallTimeout = 10 * time.Second
+
+// DialOption configures a client created by DialWithOptions.
+type DialOption func(*dialOptions)
+
+type dialOptions struct {
+	tlsConfig         *tls.Config
+	dialTimeout       time.Duration
+	callTimeout       time.Duration
+	unaryInterceptors []UnaryClientInterceptor
+}
+
+// WithTLSConfig makes the client connect over TLS; a nil cfg means plaintext.
+func WithTLSConfig(cfg *tls.Config) DialOption {
+	return func(o *dialOptions) { o.tlsConfig = cfg }
+}
+
+// WithDialTimeout limits how long establishing the connection may take.
+func WithDialTimeout(d time.Duration) DialOption {
+	return func(o *dialOptions) { o.dialTimeout = d }
+}
+
+// WithCallTimeout sets the timeout applied to calls whose context has no
+// deadline. The default is 10 seconds; zero or less disables it.
+func WithCallTimeout(d time.Duration) DialOption {
+	return func(o *dialOptions) { o.callTimeout = d }
+}
+
+// WithUnaryInterceptor adds an interceptor to every unary call, as
+// UseUnaryInterceptor does.
+func WithUnaryInterceptor(i UnaryClientInterceptor) DialOption {
+	return func(o *dialOptions) { o.unaryInterceptors = append(o.unaryInterceptors, i) }
+}
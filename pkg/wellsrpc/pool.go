@@ -2,6 +2,11 @@ package wellsrpc
 
 import "sync"
 
+// maxPooledBuffer is the capacity above which a buffer is left to the
+// garbage collector instead of being pooled, so that one large message does
+// not pin its buffer for good.
+const maxPooledBuffer = 1 << 20
+
 var bufPool = sync.Pool{
 	New: func() any {
 		b := make([]byte, 0, 4096)
@@ -13,7 +18,13 @@ func GetBuffer() *[]byte {
 	return bufPool.Get().(*[]byte)
 }
 
+// PutBuffer returns b to the pool. A caller that grew the buffer should
+// store the grown slice in *b first, so the next user starts with its
+// capacity.
 func PutBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBuffer {
+		return
+	}
 	*b = (*b)[:0]
 	bufPool.Put(b)
 }
@@ -3,6 +3,7 @@ package wellsrpc
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -108,8 +109,11 @@ func (s *RPCServer) serveConn(conn net.Conn) {
 			go func() {
 				ctx, cancel := context.WithCancel(context.Background())
 				defer cancel()
-				_ = sh(ctx, stream)
-				_ = WriteFrame(conn, &Frame{Type: FrameTypeStreamClose, StreamID: frame.StreamID})
+				if err := sh(ctx, stream); err != nil && !errors.Is(err, ErrStreamClosed) {
+					_ = send(&Frame{Type: FrameTypeError, StreamID: frame.StreamID, Payload: []byte(err.Error())})
+				} else {
+					_ = send(&Frame{Type: FrameTypeStreamClose, StreamID: frame.StreamID})
+				}
 				smu.Lock()
 				if st, ok := streamMap[frame.StreamID]; ok {
 					st.Close()
@@ -118,14 +122,28 @@ func (s *RPCServer) serveConn(conn net.Conn) {
 				smu.Unlock()
 			}()
 		case FrameTypeStreamData:
+			smu.Lock()
+			st, ok := streamMap[frame.StreamID]
+			smu.Unlock()
+			if ok && !st.deliver(frame.Payload) {
+				// The handler is not keeping up. Fail its stream rather
+				// than block the reader every other call shares.
+				st.abort(ErrStreamOverflow)
+				_ = send(&Frame{Type: FrameTypeError, StreamID: frame.StreamID, Payload: []byte(ErrStreamOverflow.Error())})
+			}
+		case FrameTypeStreamClose:
 			smu.Lock()
 			st, ok := streamMap[frame.StreamID]
 			smu.Unlock()
 			if ok {
-				select {
-				case st.recvCh <- frame.Payload:
-				default:
-				}
+				st.closeRecv(nil)
+			}
+		case FrameTypeError:
+			smu.Lock()
+			st, ok := streamMap[frame.StreamID]
+			smu.Unlock()
+			if ok {
+				st.abort(errors.New(string(frame.Payload)))
 			}
 		}
 	}
@@ -6,48 +6,137 @@ import (
 	"sync"
 )
 
+// ErrStreamClosed is returned by Recv once the peer has closed its side of
+// the stream and every buffered message has been read, and by Send after the
+// local side has been closed.
+var ErrStreamClosed = errors.New("stream closed")
+
+// ErrStreamOverflow ends a stream whose reader fell more than streamBuffer
+// messages behind the peer.
+var ErrStreamOverflow = errors.New("stream receive buffer full")
+
+// streamBuffer is how many received messages a stream holds before it fails
+// with ErrStreamOverflow.
+const streamBuffer = 128
+
 type Stream struct {
-	ID     uint32
-	send   func([]byte) error
-	recvCh chan []byte
-	closed bool
-	mu     sync.Mutex
+	ID         uint32
+	send       func([]byte) error
+	closeSend  func() error
+	recvCh     chan []byte
+	recvDone   chan struct{} // closed when the receive side ends
+	closed     bool
+	sendClosed bool
+	err        error
+	mu         sync.Mutex
 }
 
 func newStream(id uint32, send func([]byte) error) *Stream {
 	return &Stream{
-		ID:     id,
-		send:   send,
-		recvCh: make(chan []byte, 128), // increased buffer
+		ID:       id,
+		send:     send,
+		recvCh:   make(chan []byte, streamBuffer),
+		recvDone: make(chan struct{}),
 	}
 }
 
 func (s *Stream) Send(b []byte) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.closed {
-		return errors.New("stream closed")
+	if s.sendClosed {
+		if s.err != nil {
+			return s.err
+		}
+		return ErrStreamClosed
 	}
 	return s.send(b)
 }
 
 func (s *Stream) Recv(ctx context.Context) ([]byte, error) {
 	select {
-	case b, ok := <-s.recvCh:
-		if !ok {
-			return nil, errors.New("stream closed")
-		}
+	case b := <-s.recvCh:
 		return b, nil
+	case <-s.recvDone:
+		// Messages delivered before the receive side ended are still
+		// returned first.
+		select {
+		case b := <-s.recvCh:
+			return b, nil
+		default:
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, ErrStreamClosed
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// CloseSend tells the peer that no more messages follow. Messages from the
+// peer can still be received until it closes its side.
+func (s *Stream) CloseSend() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sendClosed {
+		return nil
+	}
+	s.sendClosed = true
+	if s.closeSend != nil {
+		return s.closeSend()
+	}
+	return nil
+}
+
 func (s *Stream) Close() {
 	s.mu.Lock()
-	if !s.closed {
-		s.closed = true
-		close(s.recvCh)
+	s.sendClosed = true
+	s.closeRecvLocked(nil)
+	s.mu.Unlock()
+}
+
+// deliver queues a message from the peer and reports whether there was room
+// for it. It never blocks, as the connection's reader, which is shared by
+// every call and stream on the connection, is the only caller; a stream that
+// is not read fast enough is failed with abort instead. Messages arriving
+// after the receive side was closed are dropped. Only the reader calls
+// deliver and closeRecv, so everything delivered before the peer closed its
+// side is buffered by the time Recv sees the end.
+func (s *Stream) deliver(b []byte) bool {
+	select {
+	case s.recvCh <- b:
+		return true
+	case <-s.recvDone:
+		return true
+	default:
+		return false
 	}
+}
+
+// abort ends both sides of the stream with err, which Recv returns once the
+// buffered messages are drained and Send returns from then on.
+func (s *Stream) abort(err error) {
+	s.mu.Lock()
+	s.sendClosed = true
+	s.closeRecvLocked(err)
+	s.mu.Unlock()
+}
+
+// closeRecv ends the receive side; Recv returns err, or ErrStreamClosed when
+// err is nil, once buffered messages are drained.
+func (s *Stream) closeRecv(err error) {
+	s.mu.Lock()
+	s.closeRecvLocked(err)
 	s.mu.Unlock()
 }
+
+func (s *Stream) closeRecvLocked(err error) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	close(s.recvDone)
+}
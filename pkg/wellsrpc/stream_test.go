@@ -0,0 +1,147 @@
+package wellsrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStreamDeliverFull(t *testing.T) {
+	s := newStream(1, nil)
+	for i := 0; i < streamBuffer; i++ {
+		if !s.deliver([]byte{byte(i)}) {
+			t.Fatalf("deliver %d into a stream with room failed", i)
+		}
+	}
+	if s.deliver([]byte{0xff}) {
+		t.Fatal("deliver into a full stream succeeded")
+	}
+	s.abort(ErrStreamOverflow)
+	if err := s.Send(nil); !errors.Is(err, ErrStreamOverflow) {
+		t.Errorf("Send after overflow: %v, want ErrStreamOverflow", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < streamBuffer; i++ {
+		b, err := s.Recv(ctx)
+		if err != nil || b[0] != byte(i) {
+			t.Fatalf("Recv %d = %v, %v", i, b, err)
+		}
+	}
+	if _, err := s.Recv(ctx); !errors.Is(err, ErrStreamOverflow) {
+		t.Errorf("Recv after the buffered messages: %v, want ErrStreamOverflow", err)
+	}
+}
+
+// A stream handler that stops reading fails its own stream; unary calls on
+// the same connection are still answered.
+func TestServerStreamOverflow(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	srv := NewRPCServer()
+	srv.Register("Test.Echo", func(ctx context.Context, b []byte) ([]byte, error) { return b, nil })
+	handlerErr := make(chan error, 1)
+	srv.RegisterStream("Test.Upload", func(ctx context.Context, s *Stream) error {
+		time.Sleep(50 * time.Millisecond) // the client overflows the buffer meanwhile
+		var err error
+		for err == nil {
+			_, err = s.Recv(ctx)
+		}
+		handlerErr <- err
+		return err
+	})
+	go srv.serveConn(serverConn)
+	client := NewRPCClient(clientConn)
+	defer client.Close()
+
+	s, err := client.OpenStream(context.Background(), "Test.Upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= streamBuffer; i++ {
+		if err := s.Send([]byte{byte(i)}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := s.Recv(ctx); err == nil || err.Error() != ErrStreamOverflow.Error() {
+		t.Errorf("client Recv: %v, want %v", err, ErrStreamOverflow)
+	}
+
+	req, resp := rawMessage("ping"), rawMessage(nil)
+	if err := client.Call(ctx, "Test.Echo", &req, &resp); err != nil || string(resp) != "ping" {
+		t.Errorf("Call after the overflow = %q, %v", resp, err)
+	}
+	select {
+	case err := <-handlerErr:
+		if !errors.Is(err, ErrStreamOverflow) {
+			t.Errorf("handler Recv: %v, want ErrStreamOverflow", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not see the overflow")
+	}
+}
+
+// A client stream that is not read fails on its own and the server handler
+// is told; unary calls on the same connection are still answered.
+func TestClientStreamOverflow(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	srv := NewRPCServer()
+	srv.Register("Test.Echo", func(ctx context.Context, b []byte) ([]byte, error) { return b, nil })
+	handlerErr := make(chan error, 1)
+	srv.RegisterStream("Test.Download", func(ctx context.Context, s *Stream) error {
+		for {
+			if err := s.Send([]byte{1}); err != nil {
+				handlerErr <- err
+				return err
+			}
+		}
+	})
+	go srv.serveConn(serverConn)
+	client := NewRPCClient(clientConn)
+	defer client.Close()
+
+	s, err := client.OpenStream(context.Background(), "Test.Download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-handlerErr:
+		if err == nil || err.Error() != ErrStreamOverflow.Error() {
+			t.Errorf("handler Send: %v, want %v", err, ErrStreamOverflow)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not told about the overflow")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, resp := rawMessage("ping"), rawMessage(nil)
+	if err := client.Call(ctx, "Test.Echo", &req, &resp); err != nil || string(resp) != "ping" {
+		t.Errorf("Call after the overflow = %q, %v", resp, err)
+	}
+	n := 0
+	for ; ; n++ {
+		if _, err := s.Recv(ctx); err != nil {
+			if !errors.Is(err, ErrStreamOverflow) {
+				t.Errorf("client Recv: %v, want ErrStreamOverflow", err)
+			}
+			break
+		}
+	}
+	if n != streamBuffer {
+		t.Errorf("client received %d messages before the overflow, want %d", n, streamBuffer)
+	}
+}
+
+// rawMessage marshals to its own bytes.
+type rawMessage []byte
+
+func (m *rawMessage) MarshalWells() []byte { return *m }
+
+func (m *rawMessage) UnmarshalWells(b []byte) error {
+	*m = append((*m)[:0], b...)
+	return nil
+}
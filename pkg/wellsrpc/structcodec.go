@@ -0,0 +1,755 @@
+package wellsrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The reflection codec behind MarshalStruct and UnmarshalStruct for structs
+// with wells field tags. Each struct type gets a structPlan, built on first
+// use and cached, that lists its fields in number order with the codec of
+// each.
+
+type encoding int
+
+const (
+	encVarint     encoding = iota // bool, unsigned integers, and signed integers with the varint option
+	encZigzag                     // signed integers
+	encFixed32                    // float32, and 32-bit integers with the fixed option
+	encFixed64                    // float64, and 64-bit integers with the fixed option
+	encBytes                      // string and []byte
+	encStruct                     // struct or pointer to struct
+	encMarshaller                 // pointer to a type with MarshalWells
+)
+
+// valueCodec encodes the values of one Go type.
+type valueCodec struct {
+	enc  encoding
+	typ  reflect.Type
+	plan *structPlan // for encStruct
+}
+
+func (c *valueCodec) wireType() int {
+	switch c.enc {
+	case encVarint, encZigzag:
+		return WireVarint
+	case encFixed32:
+		return WireFixed32
+	case encFixed64:
+		return WireFixed64
+	}
+	return WireBytes
+}
+
+// packable reports whether a repeated field of c is written packed.
+func (c *valueCodec) packable() bool {
+	return c.enc <= encFixed64
+}
+
+type fieldKind int
+
+const (
+	fieldSingular fieldKind = iota
+	fieldRepeated
+	fieldMap
+)
+
+type fieldPlan struct {
+	name  string // Type.Field, for errors
+	num   int
+	index int
+	kind  fieldKind
+	key   valueCodec // for fieldMap
+	val   valueCodec // the value, slice element or map value
+	tag   []byte     // the key written before each value
+}
+
+// structPlan is how one struct type is encoded, worked out once per type.
+type structPlan struct {
+	fields []*fieldPlan // in field number order
+	byNum  map[int]*fieldPlan
+}
+
+type planResult struct {
+	plan *structPlan
+	err  error
+}
+
+var (
+	marshallerType = reflect.TypeOf((*WelliMarshaller)(nil)).Elem()
+
+	planCache sync.Map // reflect.Type -> planResult
+	planMu    sync.Mutex
+)
+
+// planFor returns the plan of struct type t, building and caching it on
+// first use.
+func planFor(t reflect.Type) (*structPlan, error) {
+	if reflect.PointerTo(t).Implements(marshallerType) {
+		return nil, fmt.Errorf("wellsrpc: %s has its own MarshalWells; pass *%s", t, t)
+	}
+	if r, ok := planCache.Load(t); ok {
+		return r.(planResult).result()
+	}
+	planMu.Lock()
+	defer planMu.Unlock()
+	if r, ok := planCache.Load(t); ok {
+		return r.(planResult).result()
+	}
+	// building holds the plans started in this call, so a type that refers
+	// to itself, like a tree node, gets the plan being filled in.
+	building := map[reflect.Type]*structPlan{}
+	p, err := buildPlan(t, building)
+	if err != nil {
+		r := planResult{err: err}
+		planCache.Store(t, r)
+		return r.result()
+	}
+	for bt, bp := range building {
+		planCache.Store(bt, planResult{plan: bp})
+	}
+	return p, nil
+}
+
+func (r planResult) result() (*structPlan, error) {
+	if r.err != nil {
+		return nil, fmt.Errorf("wellsrpc: %w", r.err)
+	}
+	return r.plan, nil
+}
+
+func buildPlan(t reflect.Type, building map[reflect.Type]*structPlan) (*structPlan, error) {
+	if p := building[t]; p != nil {
+		return p, nil
+	}
+	if r, ok := planCache.Load(t); ok {
+		return r.(planResult).plan, r.(planResult).err
+	}
+	p := &structPlan{byNum: map[int]*fieldPlan{}}
+	building[t] = p
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("wells")
+		if !ok || tag == "-" {
+			continue
+		}
+		name := t.Name() + "." + sf.Name
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("%s: tagged field is not exported", name)
+		}
+		f, err := buildField(sf.Type, tag, building)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if other := p.byNum[f.num]; other != nil {
+			return nil, fmt.Errorf("%s: field number %d is also used by %s", name, f.num, other.name)
+		}
+		f.name, f.index = name, i
+		p.byNum[f.num] = f
+		p.fields = append(p.fields, f)
+	}
+	sort.Slice(p.fields, func(i, j int) bool { return p.fields[i].num < p.fields[j].num })
+	return p, nil
+}
+
+// buildField parses a wells tag, "N" followed by options, for a field of
+// type t.
+func buildField(t reflect.Type, tag string, building map[reflect.Type]*structPlan) (*fieldPlan, error) {
+	opts := strings.Split(tag, ",")
+	num, err := strconv.Atoi(opts[0])
+	if err != nil || num < 1 || num > MaxFieldNumber {
+		return nil, fmt.Errorf("invalid field number %q", opts[0])
+	}
+	// intOpt is "zigzag", "varint" or "fixed". zigzag is the default for
+	// signed integers and only spells it out.
+	var intOpt string
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "zigzag", "varint", "fixed":
+			if intOpt != "" {
+				return nil, fmt.Errorf("%s and %s cannot be combined", intOpt, opt)
+			}
+			intOpt = opt
+		default:
+			return nil, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+
+	f := &fieldPlan{num: num}
+	switch {
+	case t.Kind() == reflect.Map:
+		f.kind = fieldMap
+		switch t.Key().Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Slice, reflect.Struct, reflect.Ptr:
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		if f.key, err = codecFor(t.Key(), intOpt, building); err != nil {
+			return nil, err
+		}
+		f.val, err = codecFor(t.Elem(), intOpt, building)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		f.kind = fieldRepeated
+		f.val, err = codecFor(t.Elem(), intOpt, building)
+	default:
+		f.val, err = codecFor(t, intOpt, building)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uses := func(enc encoding) bool {
+		return f.val.enc == enc || f.kind == fieldMap && f.key.enc == enc
+	}
+	signed := isSigned(f.val.typ) || f.kind == fieldMap && isSigned(f.key.typ)
+	switch {
+	case (intOpt == "zigzag" || intOpt == "varint") && !signed:
+		return nil, fmt.Errorf("%s needs a signed integer, not %s", intOpt, t)
+	case intOpt == "fixed" && !uses(encFixed32) && !uses(encFixed64):
+		return nil, fmt.Errorf("fixed needs an integer, not %s", t)
+	}
+
+	switch {
+	case f.kind == fieldMap, f.kind == fieldRepeated && f.val.packable():
+		f.tag = AppendTag(nil, num, WireBytes)
+	default:
+		f.tag = AppendTag(nil, num, f.val.wireType())
+	}
+	return f, nil
+}
+
+// codecFor returns the codec of a single value of type t. Signed integers,
+// defined types such as time.Duration included, are zigzag encoded like an
+// IDL int32 or int64 unless intOpt says otherwise. The option is ignored by
+// types it does not apply to; buildField checks that it was used.
+func codecFor(t reflect.Type, intOpt string, building map[reflect.Type]*structPlan) (valueCodec, error) {
+	c := valueCodec{typ: t}
+	switch t.Kind() {
+	case reflect.Bool:
+		c.enc = encVarint
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		c.enc = intEncoding(intOpt, encFixed32)
+	case reflect.Int, reflect.Int64:
+		c.enc = intEncoding(intOpt, encFixed64)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		c.enc = unsignedEncoding(intOpt, encFixed32)
+	case reflect.Uint, reflect.Uint64:
+		c.enc = unsignedEncoding(intOpt, encFixed64)
+	case reflect.Float32:
+		c.enc = encFixed32
+	case reflect.Float64:
+		c.enc = encFixed64
+	case reflect.String:
+		c.enc = encBytes
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return c, fmt.Errorf("unsupported type %s", t)
+		}
+		c.enc = encBytes
+	case reflect.Struct:
+		if reflect.PointerTo(t).Implements(marshallerType) {
+			return c, fmt.Errorf("%s has its own MarshalWells; use *%s", t, t)
+		}
+		c.enc = encStruct
+		p, err := buildPlan(t, building)
+		if err != nil {
+			return c, err
+		}
+		c.plan = p
+	case reflect.Ptr:
+		if t.Implements(marshallerType) {
+			c.enc = encMarshaller
+			break
+		}
+		if t.Elem().Kind() != reflect.Struct {
+			return c, fmt.Errorf("unsupported type %s", t)
+		}
+		c.enc = encStruct
+		p, err := buildPlan(t.Elem(), building)
+		if err != nil {
+			return c, err
+		}
+		c.plan = p
+	default:
+		return c, fmt.Errorf("unsupported type %s", t)
+	}
+	return c, nil
+}
+
+func intEncoding(intOpt string, fixedEnc encoding) encoding {
+	switch intOpt {
+	case "fixed":
+		return fixedEnc
+	case "varint":
+		return encVarint
+	}
+	return encZigzag
+}
+
+func unsignedEncoding(intOpt string, fixedEnc encoding) encoding {
+	if intOpt == "fixed" {
+		return fixedEnc
+	}
+	return encVarint
+}
+
+func isSigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// marshalStruct encodes msg, a struct or pointer to struct.
+func marshalStruct(msg any) ([]byte, error) {
+	v := reflect.ValueOf(msg)
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			return nil, fmt.Errorf("wellsrpc: MarshalStruct of nil %T", msg)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("wellsrpc: cannot marshal %T", msg)
+	}
+	p, err := planFor(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	return p.append(nil, v), nil
+}
+
+// unmarshalStruct decodes b into msg, a pointer to a struct.
+func unmarshalStruct(msg any, b []byte) error {
+	v := reflect.ValueOf(msg)
+	if v.Kind() != reflect.Ptr || v.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("wellsrpc: UnmarshalStruct needs a pointer to a struct, not %T", msg)
+	}
+	if v.IsNil() {
+		return fmt.Errorf("wellsrpc: UnmarshalStruct into nil %T", msg)
+	}
+	p, err := planFor(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	return p.unmarshal(b, v.Elem(), v.Type().Elem().Name(), MaxDepth)
+}
+
+func (p *structPlan) append(b []byte, v reflect.Value) []byte {
+	for _, f := range p.fields {
+		fv := v.Field(f.index)
+		switch f.kind {
+		case fieldSingular:
+			b = f.appendSingular(b, fv)
+		case fieldRepeated:
+			b = f.appendRepeated(b, fv)
+		case fieldMap:
+			b = f.appendMap(b, fv)
+		}
+	}
+	return b
+}
+
+func (f *fieldPlan) appendSingular(b []byte, v reflect.Value) []byte {
+	switch f.val.enc {
+	case encStruct:
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return b
+		}
+	case encMarshaller:
+		if v.IsNil() {
+			return b
+		}
+	default:
+		if isZero(v) {
+			return b
+		}
+	}
+	start := len(b)
+	b = append(b, f.tag...)
+	value := len(b)
+	b = f.val.append(b, v)
+	// A struct held by value has no nil to mark it absent, so it is left
+	// out when its encoding is empty: a single zero length.
+	if v.Kind() == reflect.Struct && len(b)-value == 1 {
+		b = b[:start]
+	}
+	return b
+}
+
+func (f *fieldPlan) appendRepeated(b []byte, v reflect.Value) []byte {
+	n := v.Len()
+	if n == 0 {
+		return b
+	}
+	if f.val.packable() {
+		b = append(b, f.tag...)
+		b = append(b, 0)
+		start := len(b)
+		for i := 0; i < n; i++ {
+			b = f.val.append(b, v.Index(i))
+		}
+		return finishLength(b, start)
+	}
+	for i := 0; i < n; i++ {
+		b = append(b, f.tag...)
+		b = f.val.append(b, v.Index(i))
+	}
+	return b
+}
+
+func (f *fieldPlan) appendMap(b []byte, v reflect.Value) []byte {
+	if v.Len() == 0 {
+		return b
+	}
+	keys := v.MapKeys()
+	sortValues(keys)
+	for _, k := range keys {
+		b = append(b, f.tag...)
+		b = append(b, 0)
+		start := len(b)
+		b = AppendTag(b, 1, f.key.wireType())
+		b = f.key.append(b, k)
+		b = AppendTag(b, 2, f.val.wireType())
+		b = f.val.append(b, v.MapIndex(k))
+		b = finishLength(b, start)
+	}
+	return b
+}
+
+// append writes v, without a key. Nil messages are written empty.
+func (c *valueCodec) append(b []byte, v reflect.Value) []byte {
+	switch c.enc {
+	case encVarint:
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				return append(b, 1)
+			}
+			return append(b, 0)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return AppendVarint(b, uint64(v.Int()))
+		}
+		return AppendVarint(b, v.Uint())
+	case encZigzag:
+		return AppendVarint(b, ZigzagEncode(v.Int()))
+	case encFixed32:
+		var x uint32
+		switch v.Kind() {
+		case reflect.Float32:
+			x = math.Float32bits(float32(v.Float()))
+		case reflect.Int8, reflect.Int16, reflect.Int32:
+			x = uint32(v.Int())
+		default:
+			x = uint32(v.Uint())
+		}
+		return append(b, byte(x), byte(x>>8), byte(x>>16), byte(x>>24))
+	case encFixed64:
+		var x uint64
+		switch v.Kind() {
+		case reflect.Float64:
+			x = math.Float64bits(v.Float())
+		case reflect.Int, reflect.Int64:
+			x = uint64(v.Int())
+		default:
+			x = v.Uint()
+		}
+		return append(b, byte(x), byte(x>>8), byte(x>>16), byte(x>>24),
+			byte(x>>32), byte(x>>40), byte(x>>48), byte(x>>56))
+	case encBytes:
+		if v.Kind() == reflect.String {
+			return AppendString(b, v.String())
+		}
+		return AppendBytes(b, v.Bytes())
+	case encStruct:
+		b = append(b, 0)
+		start := len(b)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return b
+			}
+			v = v.Elem()
+		}
+		b = c.plan.append(b, v)
+		return finishLength(b, start)
+	default:
+		if v.IsNil() {
+			return append(b, 0)
+		}
+		if m, ok := v.Interface().(AppendMarshaller); ok {
+			b = append(b, 0)
+			start := len(b)
+			b = m.MarshalWellsAppend(b)
+			return finishLength(b, start)
+		}
+		return AppendBytes(b, v.Interface().(WelliMarshaller).MarshalWells())
+	}
+}
+
+// finishLength fills in the length of the bytes appended from start on. The
+// caller reserved one byte for it at start-1; a longer length moves the
+// bytes up to make room.
+func finishLength(b []byte, start int) []byte {
+	n := len(b) - start
+	if n < 0x80 {
+		b[start-1] = byte(n)
+		return b
+	}
+	size := SizeVarint(uint64(n))
+	for i := 1; i < size; i++ {
+		b = append(b, 0)
+	}
+	copy(b[start-1+size:], b[start:start+n])
+	AppendVarint(b[:start-1], uint64(n))
+	return b
+}
+
+// isZero reports whether a scalar field holds its default. Floats compare by
+// their bits, so -0 is written.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return math.Float64bits(v.Float()) == 0
+	}
+	return v.Len() == 0
+}
+
+// sortValues sorts map keys of a single bool, integer or string kind.
+func sortValues(keys []reflect.Value) {
+	if len(keys) < 2 {
+		return
+	}
+	var less func(a, b reflect.Value) bool
+	switch keys[0].Kind() {
+	case reflect.Bool:
+		less = func(a, b reflect.Value) bool { return !a.Bool() && b.Bool() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	default:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+}
+
+// unmarshal merges b into the struct v, which may hold depth more levels of
+// nested messages. name is the struct's type name, for errors.
+func (p *structPlan) unmarshal(b []byte, v reflect.Value, name string, depth int) error {
+	if depth < 0 {
+		return ErrMaxDepth
+	}
+	for i := 0; i < len(b); {
+		num, wireType, n := ReadTag(b[i:])
+		if n == 0 {
+			return fmt.Errorf("%s: invalid field key", name)
+		}
+		i += n
+		if f := p.byNum[num]; f != nil {
+			n, err := f.decode(b[i:], wireType, v.Field(f.index), depth)
+			if errors.Is(err, ErrMaxDepth) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+			if n >= 0 {
+				i += n
+				continue
+			}
+		}
+		n, err := SkipField(b[i:], wireType)
+		if err != nil {
+			return err
+		}
+		i += n
+	}
+	return nil
+}
+
+// decode reads the value of f at the start of b into v and returns its
+// size, or -1 when the wire type does not match f and the value is to be
+// skipped.
+func (f *fieldPlan) decode(b []byte, wireType int, v reflect.Value, depth int) (int, error) {
+	switch {
+	case f.kind == fieldMap:
+		if wireType != WireBytes {
+			return -1, nil
+		}
+		return f.decodeEntry(b, v, depth)
+	case f.kind == fieldRepeated && f.val.packable() && wireType == WireBytes:
+		data, n, err := readLength(b)
+		if err != nil {
+			return 0, err
+		}
+		for len(data) > 0 {
+			e := reflect.New(f.val.typ).Elem()
+			m, err := f.val.decode(data, e, depth)
+			if err != nil {
+				return 0, err
+			}
+			data = data[m:]
+			v.Set(reflect.Append(v, e))
+		}
+		return n, nil
+	case wireType != f.val.wireType():
+		return -1, nil
+	case f.kind == fieldRepeated:
+		e := reflect.New(f.val.typ).Elem()
+		n, err := f.val.decode(b, e, depth)
+		if err != nil {
+			return 0, err
+		}
+		v.Set(reflect.Append(v, e))
+		return n, nil
+	}
+	return f.val.decode(b, v, depth)
+}
+
+// decodeEntry reads a map entry. A missing key or value is the zero value,
+// and a missing message value an empty one.
+func (f *fieldPlan) decodeEntry(b []byte, v reflect.Value, depth int) (int, error) {
+	data, n, err := readLength(b)
+	if err != nil {
+		return 0, err
+	}
+	k := reflect.New(f.key.typ).Elem()
+	val := reflect.New(f.val.typ).Elem()
+	for len(data) > 0 {
+		num, wireType, m := ReadTag(data)
+		if m == 0 {
+			return 0, errors.New("invalid entry key")
+		}
+		data = data[m:]
+		switch {
+		case num == 1 && wireType == f.key.wireType():
+			m, err = f.key.decode(data, k, depth)
+		case num == 2 && wireType == f.val.wireType():
+			m, err = f.val.decode(data, val, depth)
+		default:
+			m, err = SkipField(data, wireType)
+		}
+		if err != nil {
+			return 0, err
+		}
+		data = data[m:]
+	}
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		val.Set(reflect.New(f.val.typ.Elem()))
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	v.SetMapIndex(k, val)
+	return n, nil
+}
+
+// decode reads one value from the start of b into v and returns its size.
+// Messages are merged into what v holds, one level deeper than depth.
+func (c *valueCodec) decode(b []byte, v reflect.Value, depth int) (int, error) {
+	switch c.enc {
+	case encVarint, encZigzag:
+		x, n, err := ReadVarint(b)
+		if err != nil {
+			return 0, errors.New("invalid varint")
+		}
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(x != 0)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if c.enc == encZigzag {
+				v.SetInt(ZigzagDecode(x))
+			} else {
+				v.SetInt(int64(x))
+			}
+		default:
+			v.SetUint(x)
+		}
+		return n, nil
+	case encFixed32:
+		if len(b) < 4 {
+			return 0, errors.New("truncated")
+		}
+		x := binary.LittleEndian.Uint32(b)
+		switch v.Kind() {
+		case reflect.Float32:
+			v.SetFloat(float64(math.Float32frombits(x)))
+		case reflect.Int8, reflect.Int16, reflect.Int32:
+			v.SetInt(int64(int32(x)))
+		default:
+			v.SetUint(uint64(x))
+		}
+		return 4, nil
+	case encFixed64:
+		if len(b) < 8 {
+			return 0, errors.New("truncated")
+		}
+		x := binary.LittleEndian.Uint64(b)
+		switch v.Kind() {
+		case reflect.Float64:
+			v.SetFloat(math.Float64frombits(x))
+		case reflect.Int, reflect.Int64:
+			v.SetInt(int64(x))
+		default:
+			v.SetUint(x)
+		}
+		return 8, nil
+	}
+
+	data, n, err := readLength(b)
+	if err != nil {
+		return 0, err
+	}
+	switch c.enc {
+	case encBytes:
+		if v.Kind() == reflect.String {
+			v.SetString(string(data))
+		} else {
+			v.SetBytes(append([]byte(nil), data...))
+		}
+	case encStruct:
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(c.typ.Elem()))
+			}
+			v = v.Elem()
+		}
+		err = c.plan.unmarshal(data, v, v.Type().Name(), depth-1)
+	default:
+		if v.IsNil() {
+			v.Set(reflect.New(c.typ.Elem()))
+		}
+		if m, ok := v.Interface().(DepthUnmarshaller); ok {
+			err = m.UnmarshalWellsDepth(data, depth-1)
+		} else {
+			err = v.Interface().(WelliMarshaller).UnmarshalWells(data)
+		}
+	}
+	return n, err
+}
+
+// readLength returns the length-delimited value at the start of b and the
+// size of b it takes.
+func readLength(b []byte) ([]byte, int, error) {
+	l, n, err := ReadVarint(b)
+	if err != nil {
+		return nil, 0, errors.New("invalid length")
+	}
+	if uint64(len(b)-n) < l {
+		return nil, 0, errors.New("truncated")
+	}
+	return b[n : n+int(l)], n + int(l), nil
+}
@@ -0,0 +1,312 @@
+package wellsrpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type point struct {
+	X int64 `wells:"1"`
+	Y int64 `wells:"2,zigzag"`
+}
+
+type tree struct {
+	Name     string  `wells:"1"`
+	Children []*tree `wells:"2"`
+	Parent   *tree   `wells:"3"`
+}
+
+type options struct {
+	Small   int8              `wells:"1,varint"`
+	Fixed   int32             `wells:"2,fixed"`
+	Big     uint64            `wells:"3,fixed"`
+	Counts  []uint            `wells:"4"`
+	Scores  map[int32]float64 `wells:"5"`
+	Enabled map[bool]string   `wells:"6"`
+	Origin  point             `wells:"7"`
+	Raw     []byte            `wells:"-"`
+	Note    string
+	hidden  int
+}
+
+type level int32
+
+// signedInts shows how signed integers are written: zigzag by default, like
+// the IDL int32 and int64, defined types included, and in two's complement
+// with varint, like an IDL enum.
+type signedInts struct {
+	Plain    int32         `wells:"1"`
+	Varint   int32         `wells:"2,varint"`
+	Defined  level         `wells:"3"`
+	Enum     level         `wells:"4,varint"`
+	Fixed    int64         `wells:"5,fixed"`
+	Duration time.Duration `wells:"6"`
+}
+
+func TestStructMarshal(t *testing.T) {
+	tests := []struct {
+		msg any
+		hex string
+	}{
+		{point{}, ""},
+		// Signed integers are zigzag encoded without an option, like an
+		// IDL int64; varint writes them in two's complement.
+		{&point{X: -1, Y: 1}, "0801" + "1002"},
+		{&signedInts{Plain: -1, Varint: -1, Defined: -1, Enum: -1, Fixed: -1, Duration: -time.Second},
+			"0801" + "10ffffffffffffffffff01" + "1801" + "20ffffffffffffffffff01" + "29ffffffffffffffff" + "30ffa7d6b907"},
+		{&options{Small: -1}, "08ffffffffffffffffff01"},
+		{&options{Fixed: -2, Big: 1}, "15feffffff" + "190100000000000000"},
+		{&options{Counts: []uint{1, 300}}, "2203" + "01ac02"},
+		// Map entries are written in key order, with both key and value.
+		{&options{Scores: map[int32]float64{1: 0, -1: 0.5}}, "2a0b" + "0801" + "11000000000000e03f" + "2a0b" + "0802" + "110000000000000000"},
+		{&options{Enabled: map[bool]string{true: "y", false: ""}}, "32040800" + "1200" + "32050801" + "120179"},
+		{&options{Origin: point{X: 1}}, "3a020802"},
+		{&options{Raw: []byte{1}, Note: "n", hidden: 1}, ""},
+		{&tree{Name: "a", Children: []*tree{{Name: "b"}, nil}, Parent: &tree{}}, "0a0161" + "1203" + "0a0162" + "1200" + "1a00"},
+	}
+	for _, tt := range tests {
+		b, err := MarshalStruct(tt.msg)
+		if err != nil {
+			t.Errorf("MarshalStruct(%+v): %v", tt.msg, err)
+			continue
+		}
+		if got := hex.EncodeToString(b); got != tt.hex {
+			t.Errorf("MarshalStruct(%+v) = %s, want %s", tt.msg, got, tt.hex)
+		}
+	}
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	tests := []any{
+		&point{X: math.MinInt64, Y: math.MaxInt64},
+		&signedInts{Plain: math.MinInt32, Varint: math.MinInt32, Defined: math.MinInt32, Enum: math.MaxInt32, Fixed: math.MinInt64, Duration: math.MinInt64},
+		&options{
+			Small:   -128,
+			Fixed:   math.MinInt32,
+			Big:     math.MaxUint64,
+			Counts:  []uint{0, 1, math.MaxUint64},
+			Scores:  map[int32]float64{-5: math.Inf(1), 0: math.Copysign(0, -1), 7: 0},
+			Enabled: map[bool]string{true: "on"},
+			Origin:  point{Y: -3},
+		},
+		&tree{Name: "root", Children: []*tree{{Name: "a", Children: []*tree{{}}}, {Name: strings.Repeat("x", 200)}}},
+	}
+	for _, want := range tests {
+		b, err := MarshalStruct(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := reflect.New(reflect.TypeOf(want).Elem()).Interface()
+		if err := UnmarshalStruct(got, b); err != nil {
+			t.Errorf("UnmarshalStruct(%x): %v", b, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UnmarshalStruct(MarshalStruct(%+v)) = %+v", want, got)
+		}
+	}
+}
+
+// Unmarshal takes tagged structs as well as WelliMarshallers.
+func TestUnmarshalStruct(t *testing.T) {
+	var p point
+	if err := Unmarshal(&p, []byte{0x08, 0x01, 0x10, 0x02}); err != nil || p != (point{X: -1, Y: 1}) {
+		t.Errorf("Unmarshal into *point = %+v, %v", p, err)
+	}
+	var e embedded
+	if err := Unmarshal(&e, []byte{0x0a, 0x01, 'A'}); err != nil || e.Name != "a" {
+		t.Errorf("Unmarshal into *embedded = %+v, %v", e, err)
+	}
+	if err := Unmarshal(p, nil); err == nil {
+		t.Error("Unmarshal into a struct value succeeded")
+	}
+}
+
+func TestStructUnmarshal(t *testing.T) {
+	// Packed numbers may also come one per key, and repeated and nested
+	// fields merge into what the struct already holds. Unknown fields and
+	// fields with another wire type are skipped.
+	b, _ := hex.DecodeString("2001" + "220102" + "2003" + "3a020802" + "3a021004" + "0802" + "a00601" + "0a00")
+	got := options{Counts: []uint{9}, Origin: point{Y: 5}}
+	if err := UnmarshalStruct(&got, b); err != nil {
+		t.Fatal(err)
+	}
+	want := options{Small: 2, Counts: []uint{9, 1, 2, 3}, Origin: point{X: 1, Y: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", got, want)
+	}
+
+	// A map entry with a missing key or message value holds zero values.
+	type withMap struct {
+		Points map[string]*point `wells:"1"`
+	}
+	b, _ = hex.DecodeString("0a00" + "0a030a0161")
+	var m withMap
+	if err := UnmarshalStruct(&m, b); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]*point{"": {}, "a": {}}; !reflect.DeepEqual(m.Points, want) {
+		t.Errorf("Points = %+v, want %+v", m.Points, want)
+	}
+
+	for _, in := range []string{"08", "15ffff", "0a05", "00", "0a", "120208"} {
+		b, _ := hex.DecodeString(in)
+		if err := UnmarshalStruct(&tree{}, b); err == nil {
+			t.Errorf("UnmarshalStruct(%s) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestStructLongLength(t *testing.T) {
+	// Nested encodings of 128 bytes and more move to fit their length.
+	want := &tree{Children: []*tree{{Name: strings.Repeat("y", 1<<14)}}, Parent: &tree{Name: "p"}}
+	b, err := MarshalStruct(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := 1 + 3 + 1 + 3 + 1<<14 + 5; len(b) != n {
+		t.Errorf("Marshal took %d bytes, want %d", len(b), n)
+	}
+	var got tree
+	if err := UnmarshalStruct(&got, b); err != nil || !reflect.DeepEqual(&got, want) {
+		t.Errorf("Unmarshal = %v", err)
+	}
+}
+
+// TestStructMaxDepth decodes tree.Parent chains up to and past MaxDepth.
+func TestStructMaxDepth(t *testing.T) {
+	for _, tt := range []struct {
+		depth int
+		err   error
+	}{
+		{MaxDepth, nil},
+		{MaxDepth + 1, ErrMaxDepth},
+		{3000000, ErrMaxDepth},
+	} {
+		// Each level is the Parent, field 3, of the one around it.
+		sizes := make([]int, tt.depth)
+		size := 0
+		for i := tt.depth - 1; i >= 0; i-- {
+			sizes[i] = size
+			size += 1 + SizeVarint(uint64(size))
+		}
+		b := make([]byte, 0, size)
+		for _, n := range sizes {
+			b = AppendVarint(append(b, 0x1a), uint64(n))
+		}
+		if err := UnmarshalStruct(&tree{}, b); !errors.Is(err, tt.err) {
+			t.Errorf("depth %d: UnmarshalStruct() = %v, want %v", tt.depth, err, tt.err)
+		}
+	}
+}
+
+type embedded struct {
+	Name string `wells:"1"`
+}
+
+func (e *embedded) MarshalWells() []byte {
+	return AppendString([]byte{0x0a}, strings.ToUpper(e.Name))
+}
+
+func (e *embedded) UnmarshalWells(b []byte) error {
+	e.Name = strings.ToLower(string(b[2:]))
+	return nil
+}
+
+func TestStructMarshaller(t *testing.T) {
+	type outer struct {
+		E    *embedded   `wells:"1"`
+		List []*embedded `wells:"2"`
+	}
+	want := &outer{E: &embedded{Name: "a"}, List: []*embedded{{Name: "b"}}}
+	b, err := MarshalStruct(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(b); got != "0a030a0141"+"12030a0142" {
+		t.Errorf("Marshal = %s", got)
+	}
+	var got outer
+	if err := UnmarshalStruct(&got, b); err != nil || !reflect.DeepEqual(&got, want) {
+		t.Errorf("Unmarshal = %+v, %v", got, err)
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	type nested struct {
+		Bad *struct {
+			F float32 `wells:"1,zigzag"`
+		} `wells:"1"`
+	}
+	tests := []struct {
+		msg any
+		err string
+	}{
+		{struct {
+			A int `wells:"0"`
+		}{}, `invalid field number "0"`},
+		{struct {
+			A int `wells:"536870912"`
+		}{}, `invalid field number "536870912"`},
+		{struct {
+			A int `wells:"x"`
+		}{}, `invalid field number "x"`},
+		{struct {
+			A int `wells:"1,packed"`
+		}{}, `unknown option "packed"`},
+		{struct {
+			A int `wells:"1,zigzag,fixed"`
+		}{}, "zigzag and fixed cannot be combined"},
+		{struct {
+			A int `wells:"1,varint,zigzag"`
+		}{}, "varint and zigzag cannot be combined"},
+		{struct {
+			A uint32 `wells:"1,zigzag"`
+		}{}, "zigzag needs a signed integer, not uint32"},
+		{struct {
+			A []bool `wells:"1,varint"`
+		}{}, "varint needs a signed integer, not []bool"},
+		{struct {
+			A string `wells:"1,fixed"`
+		}{}, "fixed needs an integer, not string"},
+		{struct {
+			A int `wells:"1"`
+			B int `wells:"1"`
+		}{}, "B: field number 1 is also used by .A"},
+		{struct {
+			a int `wells:"1"`
+		}{}, "a: tagged field is not exported"},
+		{struct {
+			A any `wells:"1"`
+		}{}, "unsupported type interface {}"},
+		{struct {
+			A map[float64]int `wells:"1"`
+		}{}, "unsupported map key type float64"},
+		{struct {
+			A [][]int `wells:"1"`
+		}{}, "unsupported type []int"},
+		{nested{}, "nested.Bad: .F: zigzag needs a signed integer"},
+		{embedded{}, "embedded has its own MarshalWells; pass *wellsrpc.embedded"},
+		{(*point)(nil), "MarshalStruct of nil *wellsrpc.point"},
+		{1, "cannot marshal int"},
+	}
+	for _, tt := range tests {
+		_, err := MarshalStruct(tt.msg)
+		if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), "wellsrpc: ") {
+			t.Errorf("MarshalStruct(%T) error = %v, want %q", tt.msg, err, tt.err)
+		}
+	}
+
+	// The error is cached with the plan.
+	if _, err := MarshalStruct(nested{}); err == nil || strings.Count(err.Error(), "wellsrpc:") != 1 {
+		t.Errorf("second Marshal error = %v", err)
+	}
+	if err := UnmarshalStruct(point{}, nil); err == nil || !strings.Contains(err.Error(), "needs a pointer to a struct") {
+		t.Errorf("Unmarshal of a struct value error = %v", err)
+	}
+}
@@ -1,5 +1,18 @@
 package wellsrpc
 
+import "errors"
+
+// AppendVarint appends the varint encoding of x to dst.
+func AppendVarint(dst []byte, x uint64) []byte {
+	for x >= 0x80 {
+		dst = append(dst, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(dst, byte(x))
+}
+
+// EncodeVarint returns the varint encoding of x in a new slice. Use
+// AppendVarint on hot paths.
 func EncodeVarint(x uint64) []byte {
 	var buf [10]byte
 	i := 0
@@ -12,15 +25,70 @@ func EncodeVarint(x uint64) []byte {
 	return buf[:i+1]
 }
 
-func DecodeVarint(b []byte) (uint64, int) {
+// MaxVarintLen is the largest number of bytes a 64-bit varint takes.
+const MaxVarintLen = 10
+
+var (
+	// ErrTruncated is returned when the input ends inside a varint.
+	ErrTruncated = errors.New("truncated varint")
+
+	// ErrOverflow is returned for a varint that does not fit in 64 bits:
+	// one longer than MaxVarintLen bytes, or whose last byte sets bits
+	// beyond the 64th.
+	ErrOverflow = errors.New("varint overflows 64 bits")
+
+	// ErrNonMinimal is returned by ReadMinimalVarint for a varint padded
+	// with trailing zero groups, such as 0x80 0x00 for 0.
+	ErrNonMinimal = errors.New("non-minimal varint")
+)
+
+// ReadVarint decodes the varint at the start of b and returns it with its
+// size. Like protobuf, it accepts non-minimal encodings.
+func ReadVarint(b []byte) (uint64, int, error) {
 	var x uint64
 	var s uint
 	for i, c := range b {
+		if i == MaxVarintLen-1 && c > 1 {
+			return 0, 0, ErrOverflow
+		}
 		if c < 0x80 {
-			return x | uint64(c)<<s, i + 1
+			return x | uint64(c)<<s, i + 1, nil
 		}
 		x |= uint64(c&0x7F) << s
 		s += 7
 	}
-	return 0, 0
+	return 0, 0, ErrTruncated
+}
+
+// ReadMinimalVarint is ReadVarint for input that must be in the shortest
+// form, such as bytes that are compared or hashed after decoding.
+func ReadMinimalVarint(b []byte) (uint64, int, error) {
+	x, n, err := ReadVarint(b)
+	if err == nil && n > 1 && b[n-1] == 0 {
+		return 0, 0, ErrNonMinimal
+	}
+	return x, n, err
+}
+
+// DecodeVarint is ReadVarint without the reason for a failure: n is 0 when
+// b does not start with a valid varint.
+func DecodeVarint(b []byte) (uint64, int) {
+	x, n, err := ReadVarint(b)
+	if err != nil {
+		return 0, 0
+	}
+	return x, n
+}
+
+func SizeVarint(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
+
+func SizeBytes(n int) int {
+	return SizeVarint(uint64(n)) + n
 }
@@ -0,0 +1,99 @@
+package wellsrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestReadVarint(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want uint64
+		n    int
+		err  error
+	}{
+		{[]byte{0x00}, 0, 1, nil},
+		{[]byte{0x7f, 0xff}, 127, 1, nil},
+		{[]byte{0xac, 0x02}, 300, 2, nil},
+		{[]byte{0x80, 0x00}, 0, 2, nil},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, math.MaxUint64, 10, nil},
+		{nil, 0, 0, ErrTruncated},
+		{[]byte{0x80}, 0, 0, ErrTruncated},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0, 0, ErrTruncated},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, 0, 0, ErrOverflow},
+		{[]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, 0, 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, n, err := ReadVarint(tt.in)
+		if got != tt.want || n != tt.n || !errors.Is(err, tt.err) {
+			t.Errorf("ReadVarint(%x) = %d, %d, %v, want %d, %d, %v", tt.in, got, n, err, tt.want, tt.n, tt.err)
+		}
+	}
+}
+
+func TestReadMinimalVarint(t *testing.T) {
+	if _, _, err := ReadMinimalVarint([]byte{0x80, 0x00}); !errors.Is(err, ErrNonMinimal) {
+		t.Errorf("ReadMinimalVarint(8000) error = %v, want ErrNonMinimal", err)
+	}
+	if _, _, err := ReadMinimalVarint([]byte{0x80}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ReadMinimalVarint(80) error = %v, want ErrTruncated", err)
+	}
+	for _, x := range []uint64{0, 1, 127, 128, 300, math.MaxUint32, math.MaxUint64} {
+		got, n, err := ReadMinimalVarint(EncodeVarint(x))
+		if got != x || n != SizeVarint(x) || err != nil {
+			t.Errorf("ReadMinimalVarint(EncodeVarint(%d)) = %d, %d, %v", x, got, n, err)
+		}
+	}
+}
+
+func FuzzReadVarint(f *testing.F) {
+	f.Add([]byte{0x00})
+	f.Add([]byte{0xac, 0x02})
+	f.Add([]byte{0x80, 0x00})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02})
+	f.Add([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		x, n, err := ReadVarint(b)
+
+		// encoding/binary uses the same format: n == 0 when truncated and
+		// n < 0 on overflow. It waits for an eleventh byte before reporting
+		// an unterminated ten-byte varint as overflow; ReadVarint does not.
+		want, wantN := binary.Uvarint(b)
+		switch {
+		case wantN == 0 && len(b) >= MaxVarintLen:
+			if !errors.Is(err, ErrOverflow) {
+				t.Fatalf("ReadVarint(%x) = %d, %d, %v, want ErrOverflow", b, x, n, err)
+			}
+		case wantN == 0:
+			if !errors.Is(err, ErrTruncated) {
+				t.Fatalf("ReadVarint(%x) = %d, %d, %v, want ErrTruncated", b, x, n, err)
+			}
+		case wantN < 0:
+			if !errors.Is(err, ErrOverflow) {
+				t.Fatalf("ReadVarint(%x) = %d, %d, %v, want ErrOverflow", b, x, n, err)
+			}
+		case x != want || n != wantN || err != nil:
+			t.Fatalf("ReadVarint(%x) = %d, %d, %v, want %d, %d", b, x, n, err, want, wantN)
+		}
+
+		if dx, dn := DecodeVarint(b); dx != x || dn != n {
+			t.Fatalf("DecodeVarint(%x) = %d, %d, want %d, %d", b, dx, dn, x, n)
+		}
+		if err != nil {
+			return
+		}
+		if n > MaxVarintLen {
+			t.Fatalf("ReadVarint(%x) read %d bytes", b, n)
+		}
+		_, _, minErr := ReadMinimalVarint(b)
+		if minimal := n == SizeVarint(x); minimal != (minErr == nil) {
+			t.Fatalf("ReadMinimalVarint(%x) error = %v, size %d of %d", b, minErr, n, SizeVarint(x))
+		}
+		if enc := EncodeVarint(x); minErr == nil && string(enc) != string(b[:n]) {
+			t.Fatalf("EncodeVarint(%d) = %x, want %x", x, enc, b[:n])
+		}
+	})
+}
@@ -0,0 +1,391 @@
+package wellsjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// UnmarshalOptions configures Unmarshal. The zero value rejects unknown
+// members.
+type UnmarshalOptions struct {
+	// DiscardUnknown ignores object members that the schema does not
+	// declare instead of failing.
+	DiscardUnknown bool
+}
+
+// Unmarshal sets the fields of m from the JSON object b. Fields present in
+// b replace the values in m, and the others are left alone.
+func Unmarshal(b []byte, m *dynamic.Message) error {
+	return UnmarshalOptions{}.Unmarshal(b, m)
+}
+
+// UnmarshalMessage decodes the JSON object b into msg, a generated message
+// of type desc. Like UnmarshalWells it merges into msg.
+func UnmarshalMessage(b []byte, desc *idl.Message, msg wellsrpc.WelliMarshaller) error {
+	return UnmarshalOptions{}.UnmarshalMessage(b, desc, msg)
+}
+
+// Unmarshal sets the fields of m from the JSON object b. Fields present in
+// b replace the values in m, and the others are left alone.
+func (o UnmarshalOptions) Unmarshal(b []byte, m *dynamic.Message) error {
+	var raw json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("wellsjson: %w", err)
+	}
+	if err := o.unmarshalMessage(raw, m); err != nil {
+		return fmt.Errorf("wellsjson: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalMessage decodes the JSON object b into msg, a generated message
+// of type desc. Like UnmarshalWells it merges into msg.
+func (o UnmarshalOptions) UnmarshalMessage(b []byte, desc *idl.Message, msg wellsrpc.WelliMarshaller) error {
+	m := dynamic.New(desc)
+	if err := o.Unmarshal(b, m); err != nil {
+		return err
+	}
+	return msg.UnmarshalWells(m.MarshalWells())
+}
+
+func (o UnmarshalOptions) unmarshalMessage(data json.RawMessage, m *dynamic.Message) error {
+	desc := m.Descriptor()
+	members, err := readObject(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", desc.FullName(), err)
+	}
+	seen := map[*idl.Field]bool{}
+	oneofs := map[*idl.Oneof]*idl.Field{}
+	for _, mem := range members {
+		f := fieldByName(desc, mem.name)
+		if f == nil {
+			if o.DiscardUnknown {
+				continue
+			}
+			return fmt.Errorf("%s: unknown field %q", desc.FullName(), mem.name)
+		}
+		if seen[f] {
+			return fmt.Errorf("%s: duplicate field %q", desc.FullName(), mem.name)
+		}
+		seen[f] = true
+		if isNull(mem.value) {
+			m.Clear(f.Name)
+			continue
+		}
+		if f.Oneof != nil {
+			if other := oneofs[f.Oneof]; other != nil {
+				return fmt.Errorf("%s: fields %q and %q of oneof %s are both set", desc.FullName(), other.Name, f.Name, f.Oneof.Name)
+			}
+			oneofs[f.Oneof] = f
+		}
+		v, err := o.parseField(f, mem.value)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", desc.FullName(), f.Name, err)
+		}
+		if err := m.Set(f.Name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByName returns the field of desc with the JSON or IDL name, or nil.
+func fieldByName(desc *idl.Message, name string) *idl.Field {
+	for _, f := range desc.Fields {
+		if jsonName(f.Name) == name || f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (o UnmarshalOptions) parseField(f *idl.Field, data json.RawMessage) (any, error) {
+	switch {
+	case f.IsMap():
+		members, err := readObject(data)
+		if err != nil {
+			return nil, err
+		}
+		entries := make(map[any]any, len(members))
+		for _, mem := range members {
+			k, err := parseKey(f.KeyType, mem.name)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := entries[k]; ok {
+				return nil, fmt.Errorf("duplicate key %q", mem.name)
+			}
+			if entries[k], err = o.parseValue(f, mem.value); err != nil {
+				return nil, fmt.Errorf("key %q: %w", mem.name, err)
+			}
+		}
+		return entries, nil
+	case f.Repeated:
+		if data[0] != '[' {
+			return nil, fmt.Errorf("needs an array, not %s", kind(data))
+		}
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		elems := make([]any, len(raw))
+		for i, e := range raw {
+			var err error
+			if elems[i], err = o.parseValue(f, e); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return elems, nil
+	default:
+		return o.parseValue(f, data)
+	}
+}
+
+func (o UnmarshalOptions) parseValue(f *idl.Field, data json.RawMessage) (any, error) {
+	switch {
+	case isNull(data):
+		return nil, errors.New("null is only allowed for a field")
+	case f.Message != nil:
+		sub := dynamic.New(f.Message)
+		if err := o.unmarshalMessage(data, sub); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	case f.Enum != nil:
+		return parseEnum(f.Enum, data)
+	}
+
+	switch f.Type {
+	case "int32":
+		x, err := parseInt(data, 32)
+		return int32(x), err
+	case "int64":
+		return parseInt(data, 64)
+	case "uint32":
+		x, err := parseUint(data, 32)
+		return uint32(x), err
+	case "uint64":
+		return parseUint(data, 64)
+	case "float", "float32":
+		x, err := parseFloat(data, 32)
+		return float32(x), err
+	case "double", "float64":
+		return parseFloat(data, 64)
+	case "bool":
+		switch string(data) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("needs a boolean, not %s", kind(data))
+	case "string":
+		return parseString(data)
+	default:
+		s, err := parseString(data)
+		if err != nil {
+			return nil, err
+		}
+		return decodeBase64(s)
+	}
+}
+
+func parseEnum(enum *idl.Enum, data json.RawMessage) (any, error) {
+	if data[0] != '"' {
+		x, err := parseInt(data, 32)
+		return int32(x), err
+	}
+	name, err := parseString(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range enum.Values {
+		if v.Name == name {
+			return int32(v.Number), nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a value of %s", name, enum.FullName())
+}
+
+// parseKey parses a map key, which JSON always holds as a string.
+func parseKey(typ, s string) (any, error) {
+	switch typ {
+	case "string":
+		return s, nil
+	case "bool":
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	case "int32":
+		if x, err := strconv.ParseInt(s, 10, 32); err == nil {
+			return int32(x), nil
+		}
+	case "int64":
+		if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return x, nil
+		}
+	case "uint32":
+		if x, err := strconv.ParseUint(s, 10, 32); err == nil {
+			return uint32(x), nil
+		}
+	case "uint64":
+		if x, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s key %q", typ, s)
+}
+
+// numberText returns the text of a JSON number, which may be quoted.
+func numberText(data json.RawMessage) (string, error) {
+	if data[0] != '"' {
+		if kind(data) != "a number" {
+			return "", fmt.Errorf("needs a number, not %s", kind(data))
+		}
+		return string(data), nil
+	}
+	s, err := parseString(data)
+	if err != nil {
+		return "", err
+	}
+	if s == "" || kind(json.RawMessage(s)) != "a number" || !json.Valid([]byte(s)) {
+		return "", fmt.Errorf("invalid number %s", data)
+	}
+	return s, nil
+}
+
+// parseInt parses a signed integer. Fractions and exponents are accepted
+// when the value is whole, as in 1e3.
+func parseInt(data json.RawMessage, bits int) (int64, error) {
+	s, err := numberText(data)
+	if err != nil {
+		return 0, err
+	}
+	if x, err := strconv.ParseInt(s, 10, bits); err == nil {
+		return x, nil
+	}
+	limit := math.Ldexp(1, bits-1)
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && -limit <= f && f < limit {
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("invalid int%d %s", bits, data)
+}
+
+// parseUint parses an unsigned integer like parseInt.
+func parseUint(data json.RawMessage, bits int) (uint64, error) {
+	s, err := numberText(data)
+	if err != nil {
+		return 0, err
+	}
+	if x, err := strconv.ParseUint(s, 10, bits); err == nil {
+		return x, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && 0 <= f && f < math.Ldexp(1, bits) {
+		return uint64(f), nil
+	}
+	return 0, fmt.Errorf("invalid uint%d %s", bits, data)
+}
+
+func parseFloat(data json.RawMessage, bits int) (float64, error) {
+	switch string(data) {
+	case `"NaN"`:
+		return math.NaN(), nil
+	case `"Infinity"`:
+		return math.Inf(1), nil
+	case `"-Infinity"`:
+		return math.Inf(-1), nil
+	}
+	s, err := numberText(data)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(s, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float%d %s", bits, data)
+	}
+	return f, nil
+}
+
+func parseString(data json.RawMessage) (string, error) {
+	if data[0] != '"' {
+		return "", fmt.Errorf("needs a string, not %s", kind(data))
+	}
+	var s string
+	err := json.Unmarshal(data, &s)
+	return s, err
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if len(s)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return enc.DecodeString(s)
+}
+
+type member struct {
+	name  string
+	value json.RawMessage
+}
+
+// readObject returns the members of the JSON object data in order,
+// including duplicates.
+func readObject(data json.RawMessage) ([]member, error) {
+	if data[0] != '{' {
+		return nil, fmt.Errorf("needs an object, not %s", kind(data))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var members []member
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		mem := member{name: tok.(string)}
+		if err := dec.Decode(&mem.value); err != nil {
+			return nil, err
+		}
+		members = append(members, mem)
+	}
+	return members, nil
+}
+
+func isNull(data json.RawMessage) bool {
+	return string(data) == "null"
+}
+
+// kind describes the JSON value data for error messages.
+func kind(data json.RawMessage) string {
+	switch data[0] {
+	case '{':
+		return "an object"
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	}
+	return "a number"
+}
@@ -0,0 +1,309 @@
+// Package wellsjson converts Wells messages to and from a canonical JSON
+// form, for logs, HTTP gateways and debugging. It follows the proto3 JSON
+// mapping, so a converted schema reads and writes the same JSON as protobuf:
+//
+//   - Fields are named in lowerCamelCase, so sensor_id becomes "sensorId".
+//     Unmarshal also accepts the IDL name.
+//   - int32 and uint32 are numbers. int64 and uint64 are strings, since many
+//     JSON readers lose precision above 2^53. Unmarshal accepts numbers and
+//     strings for all four.
+//   - float and double are numbers, or the strings "NaN", "Infinity" and
+//     "-Infinity".
+//   - bytes are standard base64 with padding. Unmarshal also accepts the
+//     URL-safe alphabet and missing padding.
+//   - Enums are value names. A number without a name is written as a number.
+//   - Messages are objects, repeated fields arrays, and maps objects keyed by
+//     the map key as a string.
+//   - Fields holding their default are left out, as on the wire, unless
+//     MarshalOptions.EmitDefaults is set. A set oneof case is always written.
+//     Unmarshal reads null as the default.
+//
+// Fields are written in declaration order and map entries in key order, so
+// equal messages give identical JSON.
+//
+// The unknown fields a message keeps from the wire have no JSON form and are
+// not written. Unmarshal rejects members the schema does not declare, so a
+// misspelled field name fails instead of being ignored, unless
+// UnmarshalOptions.DiscardUnknown is set.
+package wellsjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// MarshalOptions configures Marshal. The zero value writes compact JSON
+// without defaults.
+type MarshalOptions struct {
+	// Indent, when set, puts each member and element on its own line,
+	// indented by Indent per level.
+	Indent string
+
+	// EmitDefaults also writes the fields that hold their default: unset
+	// messages as null, empty repeated fields as [] and empty maps as {}.
+	// Unset oneof cases are still left out.
+	EmitDefaults bool
+}
+
+// Marshal returns the JSON form of m.
+func Marshal(m *dynamic.Message) ([]byte, error) {
+	return MarshalOptions{}.Marshal(m)
+}
+
+// MarshalMessage returns the JSON form of msg, a generated message of type
+// desc.
+func MarshalMessage(desc *idl.Message, msg wellsrpc.WelliMarshaller) ([]byte, error) {
+	return MarshalOptions{}.MarshalMessage(desc, msg)
+}
+
+// Marshal returns the JSON form of m.
+func (o MarshalOptions) Marshal(m *dynamic.Message) ([]byte, error) {
+	b, err := o.appendMessage(nil, m)
+	if err != nil {
+		return nil, fmt.Errorf("wellsjson: %w", err)
+	}
+	if o.Indent == "" {
+		return b, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", o.Indent); err != nil {
+		return nil, fmt.Errorf("wellsjson: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalMessage returns the JSON form of msg, a generated message of type
+// desc.
+func (o MarshalOptions) MarshalMessage(desc *idl.Message, msg wellsrpc.WelliMarshaller) ([]byte, error) {
+	m := dynamic.New(desc)
+	if err := m.UnmarshalWells(msg.MarshalWells()); err != nil {
+		return nil, fmt.Errorf("wellsjson: %w", err)
+	}
+	return o.Marshal(m)
+}
+
+func (o MarshalOptions) appendMessage(b []byte, m *dynamic.Message) ([]byte, error) {
+	desc := m.Descriptor()
+	b = append(b, '{')
+	first := true
+	for _, f := range desc.Fields {
+		v := m.Get(f.Name)
+		if f.Oneof != nil && !m.Has(f.Name) || f.Oneof == nil && isDefault(v) && !o.EmitDefaults {
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendString(b, jsonName(f.Name))
+		b = append(b, ':')
+		var err error
+		if b, err = o.appendField(b, f, v); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", desc.FullName(), f.Name, err)
+		}
+	}
+	return append(b, '}'), nil
+}
+
+func (o MarshalOptions) appendField(b []byte, f *idl.Field, v any) ([]byte, error) {
+	var err error
+	switch {
+	case f.IsMap():
+		entries, _ := v.(map[any]any)
+		b = append(b, '{')
+		for i, k := range dynamic.SortedKeys(entries) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			key := fmt.Sprint(k)
+			if !utf8.ValidString(key) {
+				return nil, errors.New("invalid UTF-8 in key")
+			}
+			b = appendString(b, key)
+			b = append(b, ':')
+			if b, err = o.appendValue(b, f, entries[k]); err != nil {
+				return nil, fmt.Errorf("key %q: %w", key, err)
+			}
+		}
+		return append(b, '}'), nil
+	case f.Repeated:
+		elems, _ := v.([]any)
+		b = append(b, '[')
+		for i, e := range elems {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if b, err = o.appendValue(b, f, e); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return append(b, ']'), nil
+	default:
+		return o.appendValue(b, f, v)
+	}
+}
+
+func (o MarshalOptions) appendValue(b []byte, f *idl.Field, v any) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case *dynamic.Message:
+		return o.appendMessage(b, x)
+	case int32:
+		if f.Enum != nil {
+			if name := enumName(f.Enum, x); name != "" {
+				return appendString(b, name), nil
+			}
+		}
+		return strconv.AppendInt(b, int64(x), 10), nil
+	case int64:
+		b = append(b, '"')
+		b = strconv.AppendInt(b, x, 10)
+		return append(b, '"'), nil
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10), nil
+	case uint64:
+		b = append(b, '"')
+		b = strconv.AppendUint(b, x, 10)
+		return append(b, '"'), nil
+	case float32:
+		return appendFloat(b, float64(x), 32), nil
+	case float64:
+		return appendFloat(b, x, 64), nil
+	case bool:
+		return strconv.AppendBool(b, x), nil
+	case string:
+		if !utf8.ValidString(x) {
+			return nil, errors.New("invalid UTF-8")
+		}
+		return appendString(b, x), nil
+	case []byte:
+		return appendString(b, base64.StdEncoding.EncodeToString(x)), nil
+	}
+	return nil, fmt.Errorf("unexpected value %T", v)
+}
+
+// isDefault reports whether v is left out of the JSON of a field that is
+// not in a oneof.
+func isDefault(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case int32:
+		return x == 0
+	case int64:
+		return x == 0
+	case uint32:
+		return x == 0
+	case uint64:
+		return x == 0
+	case float32:
+		return math.Float32bits(x) == 0
+	case float64:
+		return math.Float64bits(x) == 0
+	case bool:
+		return !x
+	case string:
+		return x == ""
+	case []byte:
+		return len(x) == 0
+	case []any:
+		return len(x) == 0
+	case map[any]any:
+		return len(x) == 0
+	}
+	return false
+}
+
+// jsonName returns the lowerCamelCase JSON name of a field: underscores are
+// dropped and the letter after each one is upper-cased.
+func jsonName(name string) string {
+	b := make([]byte, 0, len(name))
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			b = append(b, c-'a'+'A')
+			upper = false
+		default:
+			b = append(b, c)
+			upper = false
+		}
+	}
+	return string(b)
+}
+
+func enumName(enum *idl.Enum, num int32) string {
+	for _, v := range enum.Values {
+		if v.Number == int(num) {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// appendFloat writes f like encoding/json does, switching to exponent
+// notation for very small and very large values.
+func appendFloat(b []byte, f float64, bits int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(b, `"Infinity"`...)
+	case math.IsInf(f, -1):
+		return append(b, `"-Infinity"`...)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) ||
+			bits == 64 && (abs < 1e-6 || abs >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// Shorten e-09 to e-9.
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// appendString writes s, which must be valid UTF-8, as a JSON string.
+func appendString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,246 @@
+package wellsjson
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/internal/wiretest"
+)
+
+func loadSchema(t *testing.T) *dynamic.Schema {
+	t.Helper()
+	s, err := dynamic.Load(nil, wiretest.IDL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// The golden JSON is what protobuf's protojson writes for the same messages,
+// with its random spacing removed.
+var golden = []struct {
+	name string
+	msg  wellsrpc.WelliMarshaller
+	json string
+	full string // with EmitDefaults
+}{
+	{
+		"Scalars", &wiretest.Scalars{},
+		`{}`,
+		`{"i32":0,"i64":"0","u32":0,"u64":"0","f32":0,"f64":0,"flag":false,"text":"","data":"","level":"LEVEL_UNSPECIFIED"}`,
+	},
+	{
+		"Scalars", &wiretest.Scalars{I32: -1, I64: math.MinInt64, U32: math.MaxUint32, U64: math.MaxUint64, F32: 1e-6, F64: 1e21},
+		`{"i32":-1,"i64":"-9223372036854775808","u32":4294967295,"u64":"18446744073709551615","f32":0.000001,"f64":1e+21}`,
+		"",
+	},
+	{
+		"Scalars", &wiretest.Scalars{F32: float32(math.Inf(-1)), F64: math.NaN()},
+		`{"f32":"-Infinity","f64":"NaN"}`,
+		"",
+	},
+	{
+		"Scalars", &wiretest.Scalars{F32: float32(math.Copysign(0, -1)), F64: 5e-324},
+		`{"f32":-0,"f64":5e-324}`,
+		"",
+	},
+	{
+		"Scalars", &wiretest.Scalars{Flag: true, Text: "a\"b\\c\n\x01<>&é", Data: []byte{0xfb, 0xff}, Level: 7},
+		`{"flag":true,"text":"a\"b\\c\n\u0001<>&é","data":"+/8=","level":7}`,
+		"",
+	},
+	{
+		"Scalars", &wiretest.Scalars{Level: wiretest.Level_LEVEL_LOW},
+		`{"level":"LEVEL_LOW"}`,
+		"",
+	},
+	{
+		"Numbers", &wiretest.Numbers{TwoByte: 5},
+		`{"twoByte":5}`,
+		`{"small":0,"twoByte":5,"three":0,"largest":0}`,
+	},
+	{
+		"Composite", &wiretest.Composite{
+			Ids:      []int32{1, -1},
+			Weights:  []float32{0.5, float32(math.Inf(1))},
+			Tags:     []string{"a", ""},
+			Counts:   map[string]int64{"b": 2, "a": -1, "": 0},
+			Children: []*wiretest.Inner{{Name: "n"}, {}},
+			Choice:   &wiretest.Composite_Number{Number: 0},
+		},
+		`{"ids":[1,-1],"weights":[0.5,"Infinity"],"tags":["a",""],"counts":{"":"0","a":"-1","b":"2"},"children":[{"name":"n"},{}],"number":0}`,
+		`{"ids":[1,-1],"weights":[0.5,"Infinity"],"tags":["a",""],"counts":{"":"0","a":"-1","b":"2"},"inner":null,"children":[{"name":"n"},{"name":""}],"number":0}`,
+	},
+	{
+		"Composite", &wiretest.Composite{Inner: &wiretest.Inner{}, Choice: &wiretest.Composite_Word{}},
+		`{"inner":{},"word":""}`,
+		`{"ids":[],"weights":[],"tags":[],"counts":{},"inner":{"name":""},"children":[],"word":""}`,
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range golden {
+		desc := s.Message(tt.name)
+		got, err := MarshalMessage(desc, tt.msg)
+		if err != nil || string(got) != tt.json {
+			t.Errorf("MarshalMessage(%+v) = %s, %v, want %s", tt.msg, got, err, tt.json)
+		}
+		if tt.full == "" {
+			continue
+		}
+		got, err = MarshalOptions{EmitDefaults: true}.MarshalMessage(desc, tt.msg)
+		if err != nil || string(got) != tt.full {
+			t.Errorf("MarshalMessage(%+v) with EmitDefaults = %s, %v, want %s", tt.msg, got, err, tt.full)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range golden {
+		for _, in := range []string{tt.json, tt.full} {
+			if in == "" {
+				continue
+			}
+			got := reflect.New(reflect.TypeOf(tt.msg).Elem()).Interface().(wellsrpc.WelliMarshaller)
+			if err := UnmarshalMessage([]byte(in), s.Message(tt.name), got); err != nil {
+				t.Errorf("UnmarshalMessage(%s): %v", in, err)
+				continue
+			}
+			if out, err := MarshalMessage(s.Message(tt.name), got); err != nil || string(out) != tt.json {
+				t.Errorf("UnmarshalMessage(%s) then MarshalMessage = %s, %v, want %s", in, out, err, tt.json)
+			}
+		}
+	}
+}
+
+// fixtureJSON is what protojson writes for each of wiretest.Fixtures.
+var fixtureJSON = map[string]string{
+	"scalars":                  `{"i32":-3,"i64":"1234567890123","u32":300,"u64":"9223372036854775808","f32":1.5,"f64":-0.25,"flag":true,"text":"hi","data":"AP8=","level":"LEVEL_HIGH"}`,
+	"defaults are omitted":     `{}`,
+	"negative zero is written": `{"f32":-0,"f64":-0}`,
+	"multi-byte keys":          `{"small":1,"twoByte":1,"three":1,"largest":-1}`,
+	"composite":                `{"ids":[1,-1,300],"weights":[0.5],"tags":["a",""],"counts":{"x":"-2"},"inner":{"name":"n"},"children":[{},{"name":"c"}],"number":0}`,
+	"oneof default is written": `{"word":""}`,
+	"nested messages":          `{"name":"a","next":{"name":"b"},"children":[{"next":{}}],"named":{"k":{"name":"v"}}}`,
+}
+
+func TestFixtures(t *testing.T) {
+	s := loadSchema(t)
+	if len(fixtureJSON) != len(wiretest.Fixtures) {
+		t.Errorf("fixtureJSON has %d entries for %d fixtures", len(fixtureJSON), len(wiretest.Fixtures))
+	}
+	for _, f := range wiretest.Fixtures {
+		want, ok := fixtureJSON[f.Name]
+		if !ok {
+			t.Errorf("no JSON for fixture %q", f.Name)
+			continue
+		}
+		desc := s.Message(wiretest.Name(f.Msg))
+		got, err := MarshalMessage(desc, f.Msg)
+		if err != nil || string(got) != want {
+			t.Errorf("%s: MarshalMessage = %s, %v, want %s", f.Name, got, err, want)
+		}
+		m := wiretest.New(f.Msg)
+		if err := UnmarshalMessage([]byte(want), desc, m); err != nil {
+			t.Errorf("%s: UnmarshalMessage(%s): %v", f.Name, want, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, f.Msg) {
+			t.Errorf("%s: UnmarshalMessage(%s) = %+v, want %+v", f.Name, want, m, f.Msg)
+		}
+	}
+}
+
+func TestIndent(t *testing.T) {
+	s := loadSchema(t)
+	got, err := MarshalOptions{Indent: "  "}.MarshalMessage(s.Message("Composite"), &wiretest.Composite{Ids: []int32{1}})
+	want := "{\n  \"ids\": [\n    1\n  ]\n}"
+	if err != nil || string(got) != want {
+		t.Errorf("Marshal with Indent = %q, %v, want %q", got, err, want)
+	}
+}
+
+// Unmarshal accepts the alternative forms of the JSON mapping.
+func TestUnmarshalForms(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range []struct {
+		name string
+		json string
+		want wellsrpc.WelliMarshaller
+	}{
+		{"Scalars", `{"i32":"-3","i64":12,"u32":"7","u64":1e3,"f32":"1.5","f64":"-Infinity"}`,
+			&wiretest.Scalars{I32: -3, I64: 12, U32: 7, U64: 1000, F32: 1.5, F64: math.Inf(-1)}},
+		{"Scalars", `{"data":"-_8","level":2}`, &wiretest.Scalars{Data: []byte{0xfb, 0xff}, Level: wiretest.Level_LEVEL_HIGH}},
+		{"Scalars", `{"level":"LEVEL_HIGH","text":null,"flag":null}`, &wiretest.Scalars{Level: wiretest.Level_LEVEL_HIGH}},
+		{"Numbers", `{"two_byte":1,"largest":2.0}`, &wiretest.Numbers{TwoByte: 1, Largest: 2}},
+		{"Composite", ` {"counts":{"k":"9"},"inner":null,"children":[{}],"word":"w"} `,
+			&wiretest.Composite{Counts: map[string]int64{"k": 9}, Children: []*wiretest.Inner{{}}, Choice: &wiretest.Composite_Word{Word: "w"}}},
+	} {
+		got := reflect.New(reflect.TypeOf(tt.want).Elem()).Interface().(wellsrpc.WelliMarshaller)
+		if err := UnmarshalMessage([]byte(tt.json), s.Message(tt.name), got); err != nil {
+			t.Errorf("UnmarshalMessage(%s): %v", tt.json, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UnmarshalMessage(%s) = %+v, want %+v", tt.json, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"Scalars", `{"i32":1`, "unexpected end"},
+		{"Scalars", `{} {}`, "invalid character"},
+		{"Scalars", `[]`, "needs an object, not an array"},
+		{"Scalars", `{"missing":1}`, `unknown field "missing"`},
+		{"Scalars", `{"i32":1,"i32":2}`, `duplicate field "i32"`},
+		{"Scalars", `{"i32":2147483648}`, "invalid int32 2147483648"},
+		{"Scalars", `{"i32":1.5}`, "invalid int32 1.5"},
+		{"Scalars", `{"u32":-1}`, "invalid uint32 -1"},
+		{"Scalars", `{"i64":" 1"}`, `invalid int64 " 1"`},
+		{"Scalars", `{"f32":1e39}`, "invalid float32 1e39"},
+		{"Scalars", `{"f64":"Inf"}`, `invalid number "Inf"`},
+		{"Scalars", `{"f64":"0x1p4"}`, `invalid number "0x1p4"`},
+		{"Scalars", `{"flag":"true"}`, "needs a boolean, not a string"},
+		{"Scalars", `{"text":1}`, "needs a string, not a number"},
+		{"Scalars", `{"data":"!"}`, "illegal base64"},
+		{"Scalars", `{"level":"LEVEL_MAX"}`, `"LEVEL_MAX" is not a value of Level`},
+		{"Composite", `{"ids":1}`, "Composite.ids: needs an array, not a number"},
+		{"Composite", `{"ids":[null]}`, "element 0: null is only allowed for a field"},
+		{"Composite", `{"counts":{"a":"1","a":"2"}}`, `duplicate key "a"`},
+		{"Composite", `{"number":1,"word":"w"}`, `fields "number" and "word" of oneof choice are both set`},
+		{"Composite", `{"children":[{"name":1}]}`, "Composite.children: element 0: Inner.name: needs a string"},
+	} {
+		err := Unmarshal([]byte(tt.json), dynamic.New(s.Message(tt.name)))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Unmarshal(%s) error = %v, want %q", tt.json, err, tt.err)
+		}
+	}
+
+	m := dynamic.New(s.Message("Inner"))
+	if err := (UnmarshalOptions{DiscardUnknown: true}).Unmarshal([]byte(`{"other":{"x":[1]},"name":"n"}`), m); err != nil {
+		t.Fatalf("Unmarshal with DiscardUnknown: %v", err)
+	}
+	if got := m.Get("name"); got != "n" {
+		t.Errorf("name = %q, want n", got)
+	}
+}
+
+func TestMarshalInvalidUTF8(t *testing.T) {
+	s := loadSchema(t)
+	_, err := MarshalMessage(s.Message("Inner"), &wiretest.Inner{Name: "\xff"})
+	if err == nil || !strings.Contains(err.Error(), "Inner.name: invalid UTF-8") {
+		t.Errorf("Marshal of invalid UTF-8 error = %v", err)
+	}
+}
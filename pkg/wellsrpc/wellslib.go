@@ -5,10 +5,47 @@ type WelliMarshaller interface {
 	UnmarshalWells([]byte) error
 }
 
+// AppendMarshaller is implemented by generated messages. SizeWells is the
+// exact size of the encoding that MarshalWellsAppend appends to dst, so a
+// caller can marshal into a buffer it reuses, such as a frame being built,
+// without allocating.
+type AppendMarshaller interface {
+	SizeWells() int
+	MarshalWellsAppend(dst []byte) []byte
+}
+
+// DepthUnmarshaller is implemented by generated messages. UnmarshalWellsDepth
+// is UnmarshalWells for a message that may hold depth more levels of nested
+// messages; UnmarshalWells starts at MaxDepth.
+type DepthUnmarshaller interface {
+	UnmarshalWellsDepth(b []byte, depth int) error
+}
+
 func Marshal(msg WelliMarshaller) []byte {
 	return msg.MarshalWells()
 }
 
-func Unmarshal(msg WelliMarshaller, b []byte) error {
-	return msg.UnmarshalWells(b)
+// Unmarshal decodes b into msg, a WelliMarshaller or a pointer to a struct
+// with wells field tags, which is decoded like UnmarshalStruct does.
+func Unmarshal(msg any, b []byte) error {
+	return UnmarshalStruct(msg, b)
+}
+
+// MarshalStruct encodes msg, a struct with wells field tags or a pointer to
+// one, by reflection; a WelliMarshaller encodes itself. The README lists how
+// each Go type is written.
+func MarshalStruct(msg any) ([]byte, error) {
+	if m, ok := msg.(WelliMarshaller); ok {
+		return m.MarshalWells(), nil
+	}
+	return marshalStruct(msg)
+}
+
+// UnmarshalStruct decodes b into msg, a pointer to a struct with wells field
+// tags or a WelliMarshaller, merging like UnmarshalWells.
+func UnmarshalStruct(msg any, b []byte) error {
+	if m, ok := msg.(WelliMarshaller); ok {
+		return m.UnmarshalWells(b)
+	}
+	return unmarshalStruct(msg, b)
 }
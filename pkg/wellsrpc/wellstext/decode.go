@@ -0,0 +1,666 @@
+package wellstext
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// UnmarshalOptions configures Unmarshal. The zero value rejects unknown
+// fields.
+type UnmarshalOptions struct {
+	// DiscardUnknown skips fields that the schema does not declare instead
+	// of failing.
+	DiscardUnknown bool
+}
+
+// Unmarshal sets the fields of m from the text b. Fields present in b
+// replace the values in m, and the others are left alone.
+func Unmarshal(b []byte, m *dynamic.Message) error {
+	return UnmarshalOptions{}.Unmarshal(b, m)
+}
+
+// UnmarshalMessage decodes the text b into msg, a generated message of type
+// desc. Like UnmarshalWells it merges into msg.
+func UnmarshalMessage(b []byte, desc *idl.Message, msg wellsrpc.WelliMarshaller) error {
+	return UnmarshalOptions{}.UnmarshalMessage(b, desc, msg)
+}
+
+// Unmarshal sets the fields of m from the text b. Fields present in b
+// replace the values in m, and the others are left alone.
+func (o UnmarshalOptions) Unmarshal(b []byte, m *dynamic.Message) error {
+	p := &parser{src: b, opts: o}
+	if err := p.next(); err != nil {
+		return err
+	}
+	return p.message(m, "")
+}
+
+// UnmarshalMessage decodes the text b into msg, a generated message of type
+// desc. Like UnmarshalWells it merges into msg.
+func (o UnmarshalOptions) UnmarshalMessage(b []byte, desc *idl.Message, msg wellsrpc.WelliMarshaller) error {
+	m := dynamic.New(desc)
+	if err := o.Unmarshal(b, m); err != nil {
+		return err
+	}
+	return msg.UnmarshalWells(m.MarshalWells())
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string // the unquoted value of a string
+	off  int
+}
+
+type parser struct {
+	src  []byte
+	off  int
+	tok  token
+	opts UnmarshalOptions
+}
+
+// errorf returns an error at the line and column of the byte offset off.
+func (p *parser) errorf(off int, format string, args ...any) error {
+	line := 1 + bytes.Count(p.src[:off], []byte("\n"))
+	col := 1 + off - (bytes.LastIndexByte(p.src[:off], '\n') + 1)
+	return fmt.Errorf("wellstext: %d:%d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// is reports whether the current token is the punctuation c.
+func (p *parser) is(c string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == c
+}
+
+// accept skips the punctuation c if it is the current token.
+func (p *parser) accept(c string) (bool, error) {
+	if !p.is(c) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) expect(c string) error {
+	if !p.is(c) {
+		return p.errorf(p.tok.off, "expected %q, found %s", c, p.describe())
+	}
+	return p.next()
+}
+
+func (p *parser) describe() string {
+	switch p.tok.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return "string"
+	}
+	return strconv.Quote(p.tok.text)
+}
+
+// next reads the next token into p.tok.
+func (p *parser) next() error {
+	for p.off < len(p.src) {
+		switch c := p.src[p.off]; {
+		case c == '#':
+			for p.off < len(p.src) && p.src[p.off] != '\n' {
+				p.off++
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.off++
+			continue
+		}
+		break
+	}
+	start := p.off
+	if start == len(p.src) {
+		p.tok = token{kind: tokEOF, off: start}
+		return nil
+	}
+	switch c := p.src[start]; {
+	case isLetter(c):
+		for p.off < len(p.src) && (isLetter(p.src[p.off]) || isDigit(p.src[p.off])) {
+			p.off++
+		}
+		p.tok = token{kind: tokIdent, text: string(p.src[start:p.off]), off: start}
+	case isDigit(c) || c == '-' || c == '.':
+		p.off++
+		for p.off < len(p.src) {
+			c := p.src[p.off]
+			prev := p.src[p.off-1]
+			hex := bytes.HasPrefix(bytes.TrimPrefix(p.src[start:p.off], []byte("-")), []byte("0x"))
+			if isLetter(c) || isDigit(c) || c == '.' || (c == '+' || c == '-') && (prev == 'e' || prev == 'E') && !hex {
+				p.off++
+				continue
+			}
+			break
+		}
+		p.tok = token{kind: tokNumber, text: string(p.src[start:p.off]), off: start}
+	case c == '"' || c == '\'':
+		s, err := p.quoted()
+		if err != nil {
+			return err
+		}
+		p.tok = token{kind: tokString, text: s, off: start}
+	case strings.IndexByte(":{}<>[],;", c) >= 0:
+		p.off++
+		p.tok = token{kind: tokPunct, text: string(c), off: start}
+	default:
+		return p.errorf(start, "unexpected character %q", c)
+	}
+	return nil
+}
+
+// quoted reads a quoted string and returns its value.
+func (p *parser) quoted() (string, error) {
+	start := p.off
+	q := p.src[p.off]
+	p.off++
+	var b []byte
+	for {
+		if p.off == len(p.src) || p.src[p.off] == '\n' {
+			return "", p.errorf(start, "unterminated string")
+		}
+		c := p.src[p.off]
+		p.off++
+		if c == q {
+			return string(b), nil
+		}
+		if c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		if p.off == len(p.src) {
+			return "", p.errorf(start, "unterminated string")
+		}
+		esc := p.off - 1
+		c = p.src[p.off]
+		p.off++
+		switch c {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'a':
+			b = append(b, '\a')
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'v':
+			b = append(b, '\v')
+		case '\\', '\'', '"', '?':
+			b = append(b, c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			v := int(c - '0')
+			for i := 0; i < 2 && p.off < len(p.src) && '0' <= p.src[p.off] && p.src[p.off] <= '7'; i++ {
+				v = v*8 + int(p.src[p.off]-'0')
+				p.off++
+			}
+			if v > 0xff {
+				return "", p.errorf(esc, "octal escape out of range")
+			}
+			b = append(b, byte(v))
+		case 'x', 'X':
+			v, ok := p.hexDigits(2, false)
+			if !ok {
+				return "", p.errorf(esc, "invalid escape")
+			}
+			b = append(b, byte(v))
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			v, ok := p.hexDigits(n, true)
+			if !ok || v > utf8.MaxRune || 0xd800 <= v && v < 0xe000 {
+				return "", p.errorf(esc, "invalid escape")
+			}
+			b = utf8.AppendRune(b, rune(v))
+		default:
+			return "", p.errorf(esc, "invalid escape \\%c", c)
+		}
+	}
+}
+
+// hexDigits reads up to n hex digits, or exactly n when exact is set.
+func (p *parser) hexDigits(n int, exact bool) (uint32, bool) {
+	var v uint32
+	i := 0
+	for ; i < n && p.off < len(p.src); i++ {
+		d, err := strconv.ParseUint(string(p.src[p.off]), 16, 8)
+		if err != nil {
+			break
+		}
+		v = v<<4 | uint32(d)
+		p.off++
+	}
+	return v, i == n || i > 0 && !exact
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// fields collects the fields of one message as they are parsed. Repeated
+// fields and maps are set on the message at its end.
+type fields struct {
+	m      *dynamic.Message
+	seen   map[*idl.Field]bool
+	oneofs map[*idl.Oneof]*idl.Field
+	lists  map[*idl.Field][]any
+	maps   map[*idl.Field]map[any]any
+}
+
+// message parses fields into m up to end, which is "}", ">" or "" for the
+// end of the input.
+func (p *parser) message(m *dynamic.Message, end string) error {
+	fs := &fields{
+		m:      m,
+		seen:   map[*idl.Field]bool{},
+		oneofs: map[*idl.Oneof]*idl.Field{},
+		lists:  map[*idl.Field][]any{},
+		maps:   map[*idl.Field]map[any]any{},
+	}
+	for {
+		switch {
+		case end == "" && p.tok.kind == tokEOF:
+			return fs.flush()
+		case p.tok.kind == tokEOF:
+			return p.errorf(p.tok.off, "expected %q, found end of input", end)
+		case p.is(end):
+			if err := fs.flush(); err != nil {
+				return err
+			}
+			return p.next()
+		case p.tok.kind != tokIdent:
+			return p.errorf(p.tok.off, "expected a field name, found %s", p.describe())
+		}
+
+		nameTok := p.tok
+		if err := p.next(); err != nil {
+			return err
+		}
+		f := m.Field(nameTok.text)
+		var err error
+		switch {
+		case f != nil:
+			err = p.field(fs, f, nameTok)
+		case p.opts.DiscardUnknown:
+			err = p.skipField()
+		default:
+			err = p.errorf(nameTok.off, "unknown field %q in %s", nameTok.text, m.Descriptor().FullName())
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.separator(); err != nil {
+			return err
+		}
+	}
+}
+
+// separator skips the comma or semicolon that may follow a field.
+func (p *parser) separator() error {
+	if p.is(",") || p.is(";") {
+		return p.next()
+	}
+	return nil
+}
+
+func (fs *fields) flush() error {
+	for f, elems := range fs.lists {
+		if err := fs.m.Set(f.Name, elems); err != nil {
+			return err
+		}
+	}
+	for f, entries := range fs.maps {
+		if err := fs.m.Set(f.Name, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field parses the value or values of f after its name.
+func (p *parser) field(fs *fields, f *idl.Field, name token) error {
+	colon, err := p.accept(":")
+	if err != nil {
+		return err
+	}
+	if !f.Repeated && !f.IsMap() {
+		if fs.seen[f] {
+			return p.errorf(name.off, "field %q appears twice", f.Name)
+		}
+		fs.seen[f] = true
+		if f.Oneof != nil {
+			if other := fs.oneofs[f.Oneof]; other != nil {
+				return p.errorf(name.off, "fields %q and %q of oneof %s are both set", other.Name, f.Name, f.Oneof.Name)
+			}
+			fs.oneofs[f.Oneof] = f
+		}
+		return p.element(fs, f, colon)
+	}
+	if !p.is("[") {
+		return p.element(fs, f, colon)
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	if f.Repeated && fs.lists[f] == nil {
+		fs.lists[f] = []any{}
+	}
+	for !p.is("]") {
+		if err := p.element(fs, f, colon); err != nil {
+			return err
+		}
+		if !p.is("]") {
+			if err := p.expect(","); err != nil {
+				return err
+			}
+		}
+	}
+	return p.next()
+}
+
+// element parses one value of f: the whole value of a singular field, an
+// element of a repeated field or an entry of a map.
+func (p *parser) element(fs *fields, f *idl.Field, colon bool) error {
+	if f.IsMap() {
+		k, v, err := p.entry(f)
+		if err != nil {
+			return err
+		}
+		if fs.maps[f] == nil {
+			fs.maps[f] = map[any]any{}
+		}
+		fs.maps[f][k] = v
+		return nil
+	}
+
+	var v any
+	if f.Message != nil {
+		sub := dynamic.New(f.Message)
+		if err := p.open(func(end string) error { return p.message(sub, end) }); err != nil {
+			return err
+		}
+		v = sub
+	} else {
+		if !colon {
+			return p.errorf(p.tok.off, "expected \":\", found %s", p.describe())
+		}
+		var err error
+		if v, err = p.scalar(f.Type, f.Enum); err != nil {
+			return err
+		}
+	}
+	if f.Repeated {
+		fs.lists[f] = append(fs.lists[f], v)
+		return nil
+	}
+	return fs.m.Set(f.Name, v)
+}
+
+// open parses a message body between braces or angle brackets with body.
+func (p *parser) open(body func(end string) error) error {
+	end := "}"
+	switch {
+	case p.is("<"):
+		end = ">"
+	case !p.is("{"):
+		return p.errorf(p.tok.off, "expected \"{\", found %s", p.describe())
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	return body(end)
+}
+
+// entry parses a map entry block with key and value fields, which default
+// when missing.
+func (p *parser) entry(f *idl.Field) (key, value any, err error) {
+	err = p.open(func(end string) error {
+		for !p.is(end) {
+			name := p.tok
+			if name.kind != tokIdent || name.text != "key" && name.text != "value" {
+				return p.errorf(name.off, "expected key or value, found %s", p.describe())
+			}
+			if err := p.next(); err != nil {
+				return err
+			}
+			colon, err := p.accept(":")
+			if err != nil {
+				return err
+			}
+			switch {
+			case name.text == "key" && key != nil, name.text == "value" && value != nil:
+				return p.errorf(name.off, "%s appears twice", name.text)
+			case name.text == "key" && !colon, name.text == "value" && f.Message == nil && !colon:
+				return p.errorf(p.tok.off, "expected \":\", found %s", p.describe())
+			case name.text == "key":
+				key, err = p.scalar(f.KeyType, nil)
+			case f.Message != nil:
+				sub := dynamic.New(f.Message)
+				err = p.open(func(end string) error { return p.message(sub, end) })
+				value = sub
+			default:
+				value, err = p.scalar(f.Type, f.Enum)
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.separator(); err != nil {
+				return err
+			}
+		}
+		return p.next()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		key = zero(f.KeyType)
+	}
+	if value == nil && f.Message != nil {
+		value = dynamic.New(f.Message)
+	} else if value == nil {
+		value = zero(f.Type)
+	}
+	return key, value, nil
+}
+
+func zero(typ string) any {
+	switch typ {
+	case "int64":
+		return int64(0)
+	case "uint32":
+		return uint32(0)
+	case "uint64":
+		return uint64(0)
+	case "float", "float32":
+		return float32(0)
+	case "double", "float64":
+		return float64(0)
+	case "bool":
+		return false
+	case "string":
+		return ""
+	case "bytes":
+		return []byte(nil)
+	}
+	return int32(0) // int32 and enums
+}
+
+// scalar parses a value of a scalar type or of enum, when not nil.
+func (p *parser) scalar(typ string, enum *idl.Enum) (any, error) {
+	tok := p.tok
+	switch {
+	case enum != nil && tok.kind == tokIdent:
+		for _, v := range enum.Values {
+			if v.Name == tok.text {
+				return int32(v.Number), p.next()
+			}
+		}
+		return nil, p.errorf(tok.off, "%q is not a value of %s", tok.text, enum.FullName())
+	case enum != nil:
+		typ = "int32"
+	case typ == "string" || typ == "bytes":
+		if tok.kind != tokString {
+			return nil, p.errorf(tok.off, "expected a string, found %s", p.describe())
+		}
+		var s strings.Builder
+		for p.tok.kind == tokString {
+			s.WriteString(p.tok.text)
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if typ == "bytes" {
+			return []byte(s.String()), nil
+		}
+		if !utf8.ValidString(s.String()) {
+			return nil, p.errorf(tok.off, "invalid UTF-8 in string")
+		}
+		return s.String(), nil
+	case typ == "bool":
+		switch tok.text {
+		case "true", "True", "t", "1":
+			return true, p.next()
+		case "false", "False", "f", "0":
+			return false, p.next()
+		}
+		return nil, p.errorf(tok.off, "expected true or false, found %s", p.describe())
+	}
+
+	if tok.kind != tokNumber && tok.kind != tokIdent {
+		return nil, p.errorf(tok.off, "expected a number, found %s", p.describe())
+	}
+	v, err := parseNumber(typ, tok.text)
+	if err != nil {
+		return nil, p.errorf(tok.off, "invalid %s %s", typ, tok.text)
+	}
+	return v, p.next()
+}
+
+func parseNumber(typ, s string) (any, error) {
+	if strings.Contains(s, "_") {
+		return nil, strconv.ErrSyntax
+	}
+	switch typ {
+	case "int32":
+		x, err := strconv.ParseInt(s, 0, 32)
+		return int32(x), err
+	case "int64":
+		return strconv.ParseInt(s, 0, 64)
+	case "uint32":
+		x, err := strconv.ParseUint(s, 0, 32)
+		return uint32(x), err
+	case "uint64":
+		return strconv.ParseUint(s, 0, 64)
+	case "float", "float32":
+		x, err := parseFloat(s, 32)
+		return float32(x), err
+	default:
+		return parseFloat(s, 64)
+	}
+}
+
+// parseFloat parses a float, which may end in f and be inf, infinity or nan
+// in any case.
+func parseFloat(s string, bits int) (float64, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, "-")) {
+	case "inf", "infinity":
+		if s[0] == '-' {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	if t := strings.TrimPrefix(s, "-"); !strings.HasPrefix(t, "0x") && !strings.HasPrefix(t, "0X") {
+		s = strings.TrimSuffix(strings.TrimSuffix(s, "f"), "F")
+	}
+	return strconv.ParseFloat(s, bits)
+}
+
+// skipField skips the value or values of an unknown field after its name.
+func (p *parser) skipField() error {
+	colon, err := p.accept(":")
+	if err != nil {
+		return err
+	}
+	if !p.is("[") {
+		return p.skipValue(colon)
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	for !p.is("]") {
+		if err := p.skipValue(colon); err != nil {
+			return err
+		}
+		if !p.is("]") {
+			if err := p.expect(","); err != nil {
+				return err
+			}
+		}
+	}
+	return p.next()
+}
+
+func (p *parser) skipValue(colon bool) error {
+	switch {
+	case p.is("{") || p.is("<"):
+		return p.open(func(end string) error {
+			for !p.is(end) {
+				if p.tok.kind != tokIdent {
+					return p.errorf(p.tok.off, "expected a field name, found %s", p.describe())
+				}
+				if err := p.next(); err != nil {
+					return err
+				}
+				if err := p.skipField(); err != nil {
+					return err
+				}
+				if err := p.separator(); err != nil {
+					return err
+				}
+			}
+			return p.next()
+		})
+	case !colon:
+		return p.errorf(p.tok.off, "expected \":\", found %s", p.describe())
+	case p.tok.kind == tokString:
+		for p.tok.kind == tokString {
+			if err := p.next(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case p.tok.kind == tokNumber || p.tok.kind == tokIdent:
+		return p.next()
+	}
+	return p.errorf(p.tok.off, "expected a value, found %s", p.describe())
+}
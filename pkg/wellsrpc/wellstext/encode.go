@@ -0,0 +1,258 @@
+// Package wellstext reads and writes Wells messages in a human-readable text
+// format, for golden test files, command-line tools and configuration. It is
+// the protobuf text format, driven by a schema:
+//
+//	timestamp: 1700000000000
+//	temperature: 21.5
+//	payload: "\x01\x02"
+//	level: LEVEL_HIGH
+//	inner {
+//	  name: "probe"
+//	}
+//	ids: 1
+//	ids: 2
+//	counts {
+//	  key: "a"
+//	  value: 1
+//	}
+//
+// Fields use their IDL names. A repeated field takes one line per element,
+// and a map one entry block per key, in key order. Strings and bytes are
+// double-quoted with C escapes, floats may be inf, -inf or nan, and enums
+// are value names, or numbers for values the schema does not name. Like on
+// the wire, fields holding their default are left out, except a set oneof
+// case. The unknown fields a message keeps from the wire are not written.
+//
+// Unmarshal also accepts what protobuf's parsers do: # comments, single
+// quotes, adjacent strings that are joined, hex and octal integers, the
+// list form ids: [1, 2], < > around messages, an optional colon before a
+// message, and commas or semicolons after fields.
+package wellstext
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/idl"
+)
+
+// MarshalOptions configures Marshal. The zero value writes one field per
+// line, indented by two spaces per level.
+type MarshalOptions struct {
+	// Compact writes the message on a single line.
+	Compact bool
+}
+
+// Marshal returns the text form of m.
+func Marshal(m *dynamic.Message) []byte {
+	return MarshalOptions{}.Marshal(m)
+}
+
+// MarshalMessage returns the text form of msg, a generated message of type
+// desc.
+func MarshalMessage(desc *idl.Message, msg wellsrpc.WelliMarshaller) ([]byte, error) {
+	return MarshalOptions{}.MarshalMessage(desc, msg)
+}
+
+// Marshal returns the text form of m.
+func (o MarshalOptions) Marshal(m *dynamic.Message) []byte {
+	p := printer{compact: o.Compact}
+	p.message(m)
+	if !o.Compact && len(p.b) > 0 {
+		p.b = append(p.b, '\n')
+	}
+	return p.b
+}
+
+// MarshalMessage returns the text form of msg, a generated message of type
+// desc.
+func (o MarshalOptions) MarshalMessage(desc *idl.Message, msg wellsrpc.WelliMarshaller) ([]byte, error) {
+	m := dynamic.New(desc)
+	if err := m.UnmarshalWells(msg.MarshalWells()); err != nil {
+		return nil, fmt.Errorf("wellstext: %w", err)
+	}
+	return o.Marshal(m), nil
+}
+
+type printer struct {
+	b       []byte
+	compact bool
+	depth   int
+}
+
+func (p *printer) message(m *dynamic.Message) {
+	m.Range(func(f *idl.Field, v any) bool {
+		if f.Oneof == nil && isDefault(v) {
+			return true
+		}
+		switch {
+		case f.IsMap():
+			entries := v.(map[any]any)
+			for _, k := range dynamic.SortedKeys(entries) {
+				p.begin(f.Name)
+				p.b = append(p.b, " {"...)
+				p.depth++
+				p.field("key", nil, k)
+				p.field("value", f.Enum, entries[k])
+				p.end()
+			}
+		case f.Repeated:
+			for _, e := range v.([]any) {
+				p.field(f.Name, f.Enum, e)
+			}
+		default:
+			p.field(f.Name, f.Enum, v)
+		}
+		return true
+	})
+}
+
+// field writes one field. enum is the type of an enum value, or nil.
+func (p *printer) field(name string, enum *idl.Enum, v any) {
+	p.begin(name)
+	if sub, ok := v.(*dynamic.Message); ok {
+		p.b = append(p.b, " {"...)
+		p.depth++
+		p.message(sub)
+		p.end()
+		return
+	}
+	p.b = append(p.b, ": "...)
+	p.b = appendScalar(p.b, enum, v)
+}
+
+// begin starts a field on a new line, or after a space when compact.
+func (p *printer) begin(name string) {
+	p.space()
+	p.b = append(p.b, name...)
+}
+
+// end closes the message opened by the last begin.
+func (p *printer) end() {
+	p.depth--
+	p.space()
+	p.b = append(p.b, '}')
+}
+
+func (p *printer) space() {
+	switch {
+	case len(p.b) == 0:
+	case p.compact:
+		p.b = append(p.b, ' ')
+	default:
+		p.b = append(p.b, '\n')
+		for i := 0; i < p.depth; i++ {
+			p.b = append(p.b, "  "...)
+		}
+	}
+}
+
+func appendScalar(b []byte, enum *idl.Enum, v any) []byte {
+	switch x := v.(type) {
+	case int32:
+		if enum != nil {
+			for _, ev := range enum.Values {
+				if ev.Number == int(x) {
+					return append(b, ev.Name...)
+				}
+			}
+		}
+		return strconv.AppendInt(b, int64(x), 10)
+	case int64:
+		return strconv.AppendInt(b, x, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10)
+	case uint64:
+		return strconv.AppendUint(b, x, 10)
+	case float32:
+		return appendFloat(b, float64(x), 32)
+	case float64:
+		return appendFloat(b, x, 64)
+	case bool:
+		return strconv.AppendBool(b, x)
+	case string:
+		return appendQuoted(b, x, true)
+	case []byte:
+		return appendQuoted(b, string(x), false)
+	}
+	panic(fmt.Sprintf("wellstext: unexpected value %T", v))
+}
+
+func appendFloat(b []byte, f float64, bits int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, "nan"...)
+	case math.IsInf(f, 1):
+		return append(b, "inf"...)
+	case math.IsInf(f, -1):
+		return append(b, "-inf"...)
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, bits)
+}
+
+// appendQuoted writes s double-quoted. Printable ASCII is written as is,
+// and so are other UTF-8 characters when text is set; any other byte is
+// escaped.
+func appendQuoted(b []byte, s string, text bool) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case 0x20 <= c && c < 0x7f:
+			b = append(b, c)
+		case c >= utf8.RuneSelf && text:
+			if r, n := utf8.DecodeRuneInString(s[i:]); r != utf8.RuneError || n > 1 {
+				b = append(b, s[i:i+n]...)
+				i += n
+				continue
+			}
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		}
+		i++
+	}
+	return append(b, '"')
+}
+
+// isDefault reports whether v is left out when its field is not in a oneof.
+func isDefault(v any) bool {
+	switch x := v.(type) {
+	case int32:
+		return x == 0
+	case int64:
+		return x == 0
+	case uint32:
+		return x == 0
+	case uint64:
+		return x == 0
+	case float32:
+		return math.Float32bits(x) == 0
+	case float64:
+		return math.Float64bits(x) == 0
+	case bool:
+		return !x
+	case string:
+		return x == ""
+	case []byte:
+		return len(x) == 0
+	case []any:
+		return len(x) == 0
+	case map[any]any:
+		return len(x) == 0
+	}
+	return false
+}
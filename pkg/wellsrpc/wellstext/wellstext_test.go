@@ -0,0 +1,276 @@
+package wellstext
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/dynamic"
+	"github.com/welliardiansyah/wells-rpc/pkg/wellsrpc/internal/wiretest"
+)
+
+func loadSchema(t *testing.T) *dynamic.Schema {
+	t.Helper()
+	s, err := dynamic.Load(nil, wiretest.IDL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+var golden = []struct {
+	name string
+	msg  wellsrpc.WelliMarshaller
+	text string
+}{
+	{"Scalars", &wiretest.Scalars{}, ""},
+	{
+		"Scalars",
+		&wiretest.Scalars{I32: -1, I64: math.MinInt64, U32: math.MaxUint32, U64: math.MaxUint64, F32: 1.5, F64: 1e21,
+			Flag: true, Text: "a\"b\\c\n\x01é", Data: []byte{0, 'z', 0xff}, Level: wiretest.Level_LEVEL_HIGH},
+		`i32: -1
+i64: -9223372036854775808
+u32: 4294967295
+u64: 18446744073709551615
+f32: 1.5
+f64: 1e+21
+flag: true
+text: "a\"b\\c\n\x01é"
+data: "\x00z\xff"
+level: LEVEL_HIGH
+`,
+	},
+	{
+		"Scalars", &wiretest.Scalars{F32: float32(math.Inf(-1)), F64: math.NaN(), Level: 7},
+		"f32: -inf\nf64: nan\nlevel: 7\n",
+	},
+	{
+		"Composite",
+		&wiretest.Composite{
+			Ids:      []int32{1, -1},
+			Tags:     []string{"a", ""},
+			Counts:   map[string]int64{"b": 2, "a": -1},
+			Inner:    &wiretest.Inner{Name: "n"},
+			Children: []*wiretest.Inner{{}, {Name: "c"}},
+			Choice:   &wiretest.Composite_Number{Number: 0},
+		},
+		`ids: 1
+ids: -1
+tags: "a"
+tags: ""
+counts {
+  key: "a"
+  value: -1
+}
+counts {
+  key: "b"
+  value: 2
+}
+inner {
+  name: "n"
+}
+children {
+}
+children {
+  name: "c"
+}
+number: 0
+`,
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range golden {
+		got, err := MarshalMessage(s.Message(tt.name), tt.msg)
+		if err != nil || string(got) != tt.text {
+			t.Errorf("MarshalMessage(%+v) = %s, %v, want\n%s", tt.msg, got, err, tt.text)
+		}
+	}
+}
+
+// fixtureText is what protobuf's prototext writes for each of
+// wiretest.Fixtures.
+var fixtureText = map[string]string{
+	"scalars": `i32: -3
+i64: 1234567890123
+u32: 300
+u64: 9223372036854775808
+f32: 1.5
+f64: -0.25
+flag: true
+text: "hi"
+data: "\x00\xff"
+level: LEVEL_HIGH
+`,
+	"defaults are omitted":     "",
+	"negative zero is written": "f32: -0\nf64: -0\n",
+	"multi-byte keys":          "small: 1\ntwo_byte: 1\nthree: 1\nlargest: -1\n",
+	"composite": `ids: 1
+ids: -1
+ids: 300
+weights: 0.5
+tags: "a"
+tags: ""
+counts {
+  key: "x"
+  value: -2
+}
+inner {
+  name: "n"
+}
+children {
+}
+children {
+  name: "c"
+}
+number: 0
+`,
+	"oneof default is written": "word: \"\"\n",
+	"nested messages": `name: "a"
+next {
+  name: "b"
+}
+children {
+  next {
+  }
+}
+named {
+  key: "k"
+  value {
+    name: "v"
+  }
+}
+`,
+}
+
+func TestFixtures(t *testing.T) {
+	s := loadSchema(t)
+	if len(fixtureText) != len(wiretest.Fixtures) {
+		t.Errorf("fixtureText has %d entries for %d fixtures", len(fixtureText), len(wiretest.Fixtures))
+	}
+	for _, f := range wiretest.Fixtures {
+		want, ok := fixtureText[f.Name]
+		if !ok {
+			t.Errorf("no text for fixture %q", f.Name)
+			continue
+		}
+		desc := s.Message(wiretest.Name(f.Msg))
+		got, err := MarshalMessage(desc, f.Msg)
+		if err != nil || string(got) != want {
+			t.Errorf("%s: MarshalMessage = %s, %v, want\n%s", f.Name, got, err, want)
+		}
+		m := wiretest.New(f.Msg)
+		if err := UnmarshalMessage([]byte(want), desc, m); err != nil {
+			t.Errorf("%s: UnmarshalMessage(%s): %v", f.Name, want, err)
+			continue
+		}
+		if !reflect.DeepEqual(m, f.Msg) {
+			t.Errorf("%s: UnmarshalMessage(%s) = %+v, want %+v", f.Name, want, m, f.Msg)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := loadSchema(t)
+	msg := &wiretest.Composite{Ids: []int32{1}, Inner: &wiretest.Inner{Name: "n"}, Children: []*wiretest.Inner{{}}}
+	got, err := MarshalOptions{Compact: true}.MarshalMessage(s.Message("Composite"), msg)
+	want := `ids: 1 inner { name: "n" } children { }`
+	if err != nil || string(got) != want {
+		t.Errorf("Marshal with Compact = %s, %v, want %s", got, err, want)
+	}
+	var back wiretest.Composite
+	if err := UnmarshalMessage(got, s.Message("Composite"), &back); err != nil || !reflect.DeepEqual(&back, msg) {
+		t.Errorf("UnmarshalMessage(%s) = %+v, %v", got, back, err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range golden {
+		m := dynamic.New(s.Message(tt.name))
+		if err := Unmarshal([]byte(tt.text), m); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.text, err)
+			continue
+		}
+		if got := Marshal(m); string(got) != tt.text {
+			t.Errorf("Unmarshal then Marshal = %s, want %s", got, tt.text)
+		}
+	}
+}
+
+// Unmarshal accepts the other forms that protobuf's text parsers read.
+func TestUnmarshalForms(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range []struct {
+		name string
+		text string
+		want wellsrpc.WelliMarshaller
+	}{
+		{"Scalars", "i32: 0x10, i64: -010; u32: 7 u64: 0X1f\nf32: 2.5f f64: -Infinity",
+			&wiretest.Scalars{I32: 16, I64: -8, U32: 7, U64: 31, F32: 2.5, F64: math.Inf(-1)}},
+		{"Scalars", `text: 'it''s' " é\101" data: "\1\x2\0377" flag: t level: 1`,
+			&wiretest.Scalars{Text: "its éA", Data: []byte{1, 2, 037, '7'}, Flag: true, Level: wiretest.Level_LEVEL_LOW}},
+		{"Scalars", "# a fixture\nflag: True # trailing\n", &wiretest.Scalars{Flag: true}},
+		{"Composite", `ids: [1, -2] ids: 3 tags: [] inner: <name: "x"> children [{}, {name: "y"}]`,
+			&wiretest.Composite{Ids: []int32{1, -2, 3}, Tags: nil, Inner: &wiretest.Inner{Name: "x"},
+				Children: []*wiretest.Inner{{}, {Name: "y"}}}},
+		{"Composite", `counts { key: "k" } counts: [{value: 5 key: "j"}] word: ""`,
+			&wiretest.Composite{Counts: map[string]int64{"k": 0, "j": 5}, Choice: &wiretest.Composite_Word{}}},
+	} {
+		got := reflect.New(reflect.TypeOf(tt.want).Elem()).Interface().(wellsrpc.WelliMarshaller)
+		if err := UnmarshalMessage([]byte(tt.text), s.Message(tt.name), got); err != nil {
+			t.Errorf("UnmarshalMessage(%s): %v", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("UnmarshalMessage(%s) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	s := loadSchema(t)
+	for _, tt := range []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"Scalars", "missing: 1", `1:1: unknown field "missing" in Scalars`},
+		{"Scalars", "i32: 1\ni32: 2", `2:1: field "i32" appears twice`},
+		{"Scalars", "i32 1", `1:5: expected ":", found "1"`},
+		{"Scalars", "i32: 2147483648", "1:6: invalid int32 2147483648"},
+		{"Scalars", "u32: -1", "invalid uint32 -1"},
+		{"Scalars", "i64: 1_000", "invalid int64 1_000"},
+		{"Scalars", "f32: 1e39", "invalid float 1e39"},
+		{"Scalars", "flag: yes", `expected true or false, found "yes"`},
+		{"Scalars", "text: 1", `expected a string, found "1"`},
+		{"Scalars", `text: "\xff"`, "invalid UTF-8 in string"},
+		{"Scalars", `text: "abc`, "1:7: unterminated string"},
+		{"Scalars", `text: "\q"`, `invalid escape \q`},
+		{"Scalars", "level: LEVEL_MAX", `"LEVEL_MAX" is not a value of Level`},
+		{"Scalars", "i32: 1 @", "1:8: unexpected character '@'"},
+		{"Composite", "inner { name: \"x\"", `expected "}", found end of input`},
+		{"Composite", "inner: 1", `expected "{", found "1"`},
+		{"Composite", "ids: [1 2]", `expected ",", found "2"`},
+		{"Composite", "number: 1 word: \"w\"", `fields "number" and "word" of oneof choice are both set`},
+		{"Composite", "counts { key: \"a\" key: \"b\" }", "key appears twice"},
+		{"Composite", "counts { other: 1 }", `expected key or value, found "other"`},
+	} {
+		err := Unmarshal([]byte(tt.text), dynamic.New(s.Message(tt.name)))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Unmarshal(%s) error = %v, want %q", tt.text, err, tt.err)
+		}
+	}
+
+	m := dynamic.New(s.Message("Inner"))
+	text := `other { x: [1, "a"] y <z: 1> } more: "a" "b" name: "n" list: [{}, {}]`
+	if err := (UnmarshalOptions{DiscardUnknown: true}).Unmarshal([]byte(text), m); err != nil {
+		t.Fatalf("Unmarshal with DiscardUnknown: %v", err)
+	}
+	if got := m.Get("name"); got != "n" {
+		t.Errorf("name = %q, want n", got)
+	}
+}
@@ -0,0 +1,118 @@
+package wellsrpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	WireVarint     = 0
+	WireFixed64    = 1
+	WireBytes      = 2
+	WireStartGroup = 3
+	WireEndGroup   = 4
+	WireFixed32    = 5
+)
+
+// MaxFieldNumber is the largest field number a key can carry.
+const MaxFieldNumber = 1<<29 - 1
+
+// MaxDepth is the number of levels of nested messages a decoder accepts, as
+// in protobuf. Decoding recurses once per level, so without a limit a small
+// payload of deeply nested messages would overflow the stack.
+const MaxDepth = 10000
+
+// ErrMaxDepth is returned when a message nests more than MaxDepth levels of
+// messages.
+var ErrMaxDepth = errors.New("messages nested more than MaxDepth levels")
+
+// AppendTag appends the key of field num with the given wire type to b. The
+// key is the varint of num<<3 | wireType, so numbers from 16 on take more
+// than one byte.
+func AppendTag(b []byte, num, wireType int) []byte {
+	return AppendVarint(b, uint64(num)<<3|uint64(wireType&0x7))
+}
+
+// ReadTag decodes the key at the start of b. n is the size of the key, or 0
+// when b does not start with a valid key: the varint is truncated or the
+// field number is not between 1 and MaxFieldNumber.
+func ReadTag(b []byte) (num, wireType, n int) {
+	key, n := DecodeVarint(b)
+	if n == 0 || key>>3 == 0 || key>>3 > MaxFieldNumber {
+		return 0, 0, 0
+	}
+	return int(key >> 3), int(key & 0x7), n
+}
+
+// SkipField returns the size of the field value at the start of b, which
+// follows a key of the given wire type. A group, the deprecated proto2
+// encoding with wire types 3 and 4, is skipped up to and including the
+// end-group key that closes it.
+func SkipField(b []byte, wireType int) (int, error) {
+	switch wireType {
+	case WireVarint:
+		_, n, err := ReadVarint(b)
+		if err != nil {
+			return 0, fmt.Errorf("skip: %w", err)
+		}
+		return n, nil
+	case WireFixed64:
+		if len(b) < 8 {
+			return 0, errors.New("fixed64 truncated in skip")
+		}
+		return 8, nil
+	case WireBytes:
+		l, n, err := ReadVarint(b)
+		if err != nil {
+			return 0, fmt.Errorf("skip length: %w", err)
+		}
+		if uint64(len(b)-n) < l {
+			return 0, errors.New("bytes truncated in skip")
+		}
+		return n + int(l), nil
+	case WireFixed32:
+		if len(b) < 4 {
+			return 0, errors.New("fixed32 truncated in skip")
+		}
+		return 4, nil
+	case WireStartGroup:
+		return skipGroup(b)
+	case WireEndGroup:
+		return 0, errors.New("unexpected end group in skip")
+	default:
+		return 0, errors.New("unknown wire type")
+	}
+}
+
+// skipGroup skips the fields of a group, which may hold nested groups,
+// through its end-group key. The number of the outermost group is not known
+// here, so only nested end-group keys are matched against their start.
+func skipGroup(b []byte) (int, error) {
+	open := []int{0}
+	i := 0
+	for {
+		num, wireType, n := ReadTag(b[i:])
+		if n == 0 {
+			return 0, errors.New("invalid key in group skip")
+		}
+		i += n
+		switch wireType {
+		case WireStartGroup:
+			open = append(open, num)
+		case WireEndGroup:
+			if start := open[len(open)-1]; start != 0 && start != num {
+				return 0, errors.New("mismatched end group in skip")
+			}
+			open = open[:len(open)-1]
+			if len(open) == 0 {
+				return i, nil
+			}
+		default:
+			n, err := SkipField(b[i:], wireType)
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package wellsrpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppendTag(t *testing.T) {
+	tests := []struct {
+		num, wireType int
+		want          []byte
+	}{
+		{1, WireVarint, []byte{0x08}},
+		{2, WireBytes, []byte{0x12}},
+		{15, WireFixed32, []byte{0x7d}},
+		{16, WireVarint, []byte{0x80, 0x01}},
+		{2047, WireFixed64, []byte{0xf9, 0x7f}},
+		{2048, WireVarint, []byte{0x80, 0x80, 0x01}},
+		{262143, WireBytes, []byte{0xfa, 0xff, 0x7f}},
+		{262144, WireVarint, []byte{0x80, 0x80, 0x80, 0x01}},
+		{33554431, WireFixed32, []byte{0xfd, 0xff, 0xff, 0x7f}},
+		{33554432, WireVarint, []byte{0x80, 0x80, 0x80, 0x80, 0x01}},
+		{MaxFieldNumber, WireStartGroup, []byte{0xfb, 0xff, 0xff, 0xff, 0x0f}},
+	}
+	for _, tt := range tests {
+		got := AppendTag([]byte{0xaa}, tt.num, tt.wireType)
+		if !bytes.Equal(got[1:], tt.want) || got[0] != 0xaa {
+			t.Errorf("AppendTag(%d, %d) = %x, want aa%x", tt.num, tt.wireType, got, tt.want)
+		}
+		num, wireType, n := ReadTag(append(got[1:], 0x01))
+		if num != tt.num || wireType != tt.wireType || n != len(tt.want) {
+			t.Errorf("ReadTag(%x) = %d, %d, %d, want %d, %d, %d", got[1:], num, wireType, n, tt.num, tt.wireType, len(tt.want))
+		}
+	}
+}
+
+func TestReadTagAllNumbers(t *testing.T) {
+	// Every field number from 1 to 2^16 and the last few before
+	// MaxFieldNumber, with each wire type.
+	check := func(num int) {
+		for wireType := 0; wireType < 8; wireType++ {
+			b := AppendTag(nil, num, wireType)
+			if n := len(b); n != SizeVarint(uint64(num)<<3) {
+				t.Fatalf("AppendTag(%d, %d) took %d bytes", num, wireType, n)
+			}
+			gotNum, gotType, n := ReadTag(b)
+			if gotNum != num || gotType != wireType || n != len(b) {
+				t.Fatalf("ReadTag(AppendTag(%d, %d)) = %d, %d, %d", num, wireType, gotNum, gotType, n)
+			}
+		}
+	}
+	for num := 1; num <= 1<<16; num++ {
+		check(num)
+	}
+	for num := MaxFieldNumber - 1024; num <= MaxFieldNumber; num++ {
+		check(num)
+	}
+}
+
+func TestReadTagInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":         nil,
+		"truncated":     {0x80, 0x80},
+		"field zero":    {0x02},
+		"field too big": EncodeVarint(uint64(MaxFieldNumber+1) << 3),
+		"key too big":   EncodeVarint(1 << 40),
+	}
+	for name, b := range tests {
+		if num, wireType, n := ReadTag(b); n != 0 {
+			t.Errorf("%s: ReadTag(%x) = %d, %d, %d, want n = 0", name, b, num, wireType, n)
+		}
+	}
+}